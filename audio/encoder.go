@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidEncoder = errors.New("audio: invalid encoder command")
+
+// Encoder turns the samples of a WAV file into a stream of SBC frames.
+// The stream is closed once it is played or playback stops.
+type Encoder interface {
+	Encode(ctx context.Context, wav *WAV) (io.ReadCloser, error)
+}
+
+// CommandEncoder runs an external encoder, which reads 16-bit little-endian PCM
+// from stdin and writes SBC frames to stdout.
+type CommandEncoder struct {
+	// Command returns the command line for the sample format of the WAV file
+	Command func(sampleRate, channels int) []string
+}
+
+// FFmpeg returns an encoder running ffmpeg from PATH, which resamples
+// to the 32 kHz stereo the speaker plays.
+func FFmpeg() *CommandEncoder {
+	return &CommandEncoder{
+		Command: func(sampleRate, channels int) []string {
+			return []string{
+				"ffmpeg", "-loglevel", "error",
+				"-f", "s16le", "-ar", strconv.Itoa(sampleRate), "-ac", strconv.Itoa(channels), "-i", "pipe:0",
+				"-ar", "32000", "-ac", "2", "-c:a", "sbc", "-f", "sbc", "pipe:1",
+			}
+		},
+	}
+}
+
+func (e *CommandEncoder) Encode(ctx context.Context, wav *WAV) (io.ReadCloser, error) {
+	args := e.Command(wav.SampleRate, wav.Channels)
+	if len(args) == 0 {
+		return nil, ErrInvalidEncoder
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream := &commandStream{
+		cmd:    exec.CommandContext(ctx, args[0], args[1:]...),
+		cancel: cancel,
+	}
+	stream.cmd.Stdin = wav
+	stream.cmd.Stderr = &stream.stderr
+
+	stdout, err := stream.cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	err = stream.cmd.Start()
+	if err != nil {
+		cancel()
+
+		return nil, errors.Wrapf(err, "audio: can't start %s", args[0])
+	}
+
+	stream.stdout = stdout
+
+	return stream, nil
+}
+
+// commandStream reads the output of an encoder process. Closing it before
+// the end of the output kills the process, the error it exits with is dropped.
+type commandStream struct {
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	stdout io.Reader
+	stderr bytes.Buffer
+	isRead bool
+}
+
+func (s *commandStream) Read(b []byte) (int, error) {
+	n, err := s.stdout.Read(b)
+	if err == io.EOF {
+		s.isRead = true
+	}
+
+	return n, err
+}
+
+func (s *commandStream) Close() error {
+	if !s.isRead {
+		s.cancel()
+	}
+
+	err := s.cmd.Wait()
+	s.cancel()
+
+	if err != nil && s.isRead {
+		return errors.Wrapf(err, "audio: %s failed: %s", s.cmd.Path, strings.TrimSpace(s.stderr.String()))
+	}
+
+	return nil
+}
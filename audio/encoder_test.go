@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func commandEncoder(t *testing.T, args ...string) *CommandEncoder {
+	t.Helper()
+
+	_, err := exec.LookPath(args[0])
+	if err != nil {
+		t.Skipf("%s is not installed", args[0])
+	}
+
+	return &CommandEncoder{
+		Command: func(int, int) []string {
+			return args
+		},
+	}
+}
+
+func TestCommandEncoderPipesSamples(t *testing.T) {
+	// cat passes the samples through, they are SBC frames here
+	frame := sbcFrame(t, 0x31, 28)
+	sbc := bytes.Repeat(frame, 10)
+
+	w := &writes{}
+	player := NewPlayer(w, TargetSpeaker)
+	player.SetEncoder(commandEncoder(t, "cat"))
+
+	err := player.PlayWAV(context.Background(), bytes.NewReader(wavFile(16000, 1, 16, sbc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.batches) != 2 || !bytes.Equal(append(w.batches[0], w.batches[1]...), sbc) {
+		t.Errorf("got %d batches, want the SBC stream in batches of 8 and 2 frames", len(w.batches))
+	}
+}
+
+func TestCommandEncoderFails(t *testing.T) {
+	encoder := commandEncoder(t, "sh", "-c", "echo no encoder >&2; exit 1")
+
+	wav, err := DecodeWAV(bytes.NewReader(wavFile(16000, 1, 16, []byte{0, 0})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := encoder.Encode(context.Background(), wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Close()
+	if err == nil || !strings.Contains(err.Error(), "no encoder") {
+		t.Errorf("got error %v, want the encoder output", err)
+	}
+
+	_, err = (&CommandEncoder{Command: func(int, int) []string { return nil }}).Encode(context.Background(), wav)
+	if err != ErrInvalidEncoder {
+		t.Errorf("got error %v, want %v", err, ErrInvalidEncoder)
+	}
+}
+
+func TestCommandEncoderStopsOnClose(t *testing.T) {
+	encoder := commandEncoder(t, "sleep", "10")
+
+	wav, err := DecodeWAV(bytes.NewReader(wavFile(16000, 1, 16, []byte{0, 0})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := encoder.Encode(context.Background(), wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- stream.Close()
+	}()
+
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Errorf("closing an unfinished stream failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waits for the encoder to finish")
+	}
+}
+
+func TestFFmpegEncoder(t *testing.T) {
+	commandEncoder(t, "ffmpeg")
+
+	// A tenth of a second of silence
+	wav := wavFile(44100, 1, 16, make([]byte, 2*4410))
+
+	w := &writes{}
+	player := NewPlayer(w, TargetSpeaker)
+	player.SetEncoder(FFmpeg())
+
+	err := player.PlayWAV(context.Background(), bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var duration time.Duration
+
+	for _, batch := range w.batches {
+		frames, err := splitFrames(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, frame := range frames {
+			// 32 kHz, not mono
+			if frame[1]>>6 != 1 || frame[1]>>2&0x03 == 0 {
+				t.Fatalf("got frame header % x, want 32 kHz stereo", frame[:4])
+			}
+
+			duration += FrameDuration(frame)
+		}
+	}
+
+	if duration < 90*time.Millisecond {
+		t.Errorf("got %s of audio, want 100ms", duration)
+	}
+}
+
+func TestPlayWAVWithoutEncoder(t *testing.T) {
+	err := NewPlayer(&writes{}, TargetSpeaker).PlayWAV(context.Background(), bytes.NewReader(wavFile(16000, 1, 16, nil)))
+	if err != ErrNoEncoder {
+		t.Errorf("got error %v, want %v", err, ErrNoEncoder)
+	}
+}
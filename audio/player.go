@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNoEncoder = errors.New("audio: playing WAV files needs an encoder")
+
+type Writer interface {
+	WriteAudio(target Target, sbc []byte) error
+}
+
+// Player streams SBC audio, WAV files are encoded on the fly by the encoder set with SetEncoder.
+type Player struct {
+	writer  Writer
+	target  Target
+	encoder Encoder
+}
+
+func NewPlayer(writer Writer, target Target) *Player {
+	return &Player{writer: writer, target: target}
+}
+
+func (p *Player) SetEncoder(encoder Encoder) {
+	p.encoder = encoder
+}
+
+// PlayWAV encodes a 16-bit PCM WAV file and streams it like PlaySBC.
+func (p *Player) PlayWAV(ctx context.Context, r io.Reader) error {
+	if p.encoder == nil {
+		return ErrNoEncoder
+	}
+
+	wav, err := DecodeWAV(r)
+	if err != nil {
+		return err
+	}
+
+	sbc, err := p.encoder.Encode(ctx, wav)
+	if err != nil {
+		return err
+	}
+
+	err = p.PlaySBC(ctx, sbc)
+
+	closeErr := sbc.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// PlaySBC streams SBC frames in real time until r ends or ctx is canceled. Frames are sent
+// in batches filling the largest audio report, the last batch is sent when r ends.
+func (p *Player) PlaySBC(ctx context.Context, r io.Reader) error {
+	var (
+		reader   = bufio.NewReader(r)
+		start    = time.Now()
+		played   time.Duration
+		batched  time.Duration
+		batch    []byte
+		maxBatch = reportIDs[len(reportIDs)-1].Capacity()
+	)
+
+	for {
+		frame, err := readFrame(reader)
+		if err == io.EOF {
+			if len(batch) == 0 {
+				return nil
+			}
+
+			return p.writer.WriteAudio(p.target, batch)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(batch)+len(frame) > maxBatch {
+			err = p.writer.WriteAudio(p.target, batch)
+			if err != nil {
+				return err
+			}
+
+			batch = batch[:0]
+			played += batched
+			batched = 0
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(start.Add(played))):
+			}
+		}
+
+		batch = append(batch, frame...)
+		batched += FrameDuration(frame)
+	}
+}
+
+// readFrame returns the next SBC frame or io.EOF at the end of the stream.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	header, err := r.Peek(4)
+	switch {
+	case err == io.EOF && len(header) == 0:
+		return nil, io.EOF
+	case err == io.EOF:
+		return nil, truncatedFrameError(len(header), 4)
+	case err != nil:
+		return nil, err
+	}
+
+	length, err := FrameLength(header)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, length)
+
+	n, err := io.ReadFull(r, frame)
+	if err == io.ErrUnexpectedEOF {
+		return nil, truncatedFrameError(n, length)
+	}
+
+	return frame, err
+}
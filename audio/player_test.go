@@ -0,0 +1,91 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type writes struct {
+	targets []Target
+	batches [][]byte
+}
+
+func (w *writes) WriteAudio(target Target, sbc []byte) error {
+	w.targets = append(w.targets, target)
+	w.batches = append(w.batches, append([]byte(nil), sbc...))
+
+	return nil
+}
+
+func TestPlaySBC(t *testing.T) {
+	// 16 kHz mono frames of 64 bytes and 8ms, 8 fit into the largest report
+	frame := sbcFrame(t, 0x31, 28)
+	sbc := bytes.Repeat(frame, 10)
+
+	w := &writes{}
+
+	err := NewPlayer(w, TargetSpeaker).PlaySBC(context.Background(), bytes.NewReader(sbc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.batches) != 2 || len(w.batches[0]) != 8*len(frame) || len(w.batches[1]) != 2*len(frame) {
+		t.Fatalf("got %d batches, want batches of 8 and 2 frames", len(w.batches))
+	}
+
+	if !bytes.Equal(append(w.batches[0], w.batches[1]...), sbc) {
+		t.Error("batches don't carry the SBC stream")
+	}
+
+	for _, target := range w.targets {
+		if target != TargetSpeaker {
+			t.Errorf("target = %#x, want %#x", target, TargetSpeaker)
+		}
+	}
+}
+
+func TestPlaySBCFlushesShortStream(t *testing.T) {
+	frame := sbcFrame(t, 0xBD, 53)
+
+	w := &writes{}
+
+	err := NewPlayer(w, TargetHeadphones).PlaySBC(context.Background(), bytes.NewReader(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.batches) != 1 || !bytes.Equal(w.batches[0], frame) {
+		t.Errorf("got %d batches, want the single frame", len(w.batches))
+	}
+}
+
+func TestPlaySBCTruncated(t *testing.T) {
+	frame := sbcFrame(t, 0xBD, 53)
+
+	for _, sbc := range [][]byte{append(frame, frame[:50]...), append(frame, frame[:2]...)} {
+		err := NewPlayer(&writes{}, TargetSpeaker).PlaySBC(context.Background(), bytes.NewReader(sbc))
+		if errors.Cause(err) != ErrInvalidSBCFrame {
+			t.Errorf("error = %v, want %v", err, ErrInvalidSBCFrame)
+		}
+	}
+}
+
+func TestPlaySBCCanceled(t *testing.T) {
+	frame := sbcFrame(t, 0x31, 28)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := &writes{}
+
+	err := NewPlayer(w, TargetSpeaker).PlaySBC(ctx, bytes.NewReader(bytes.Repeat(frame, 20)))
+	if err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+
+	if len(w.batches) != 1 {
+		t.Errorf("got %d batches, want 1 before the cancellation", len(w.batches))
+	}
+}
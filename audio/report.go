@@ -0,0 +1,132 @@
+package audio
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+type Target byte
+
+const (
+	TargetSpeaker    Target = 0x02
+	TargetHeadphones Target = 0x24
+)
+
+type ReportID byte
+
+const (
+	Report0x14 ReportID = 0x14
+	Report0x15 ReportID = 0x15
+	Report0x16 ReportID = 0x16
+	Report0x17 ReportID = 0x17
+	Report0x18 ReportID = 0x18
+	Report0x19 ReportID = 0x19
+)
+
+// Report layout: id, two flag bytes, little-endian sequence number,
+// target, SBC frames and a trailing CRC-32.
+const (
+	reportHeaderSize = 6
+	reportCRCSize    = 4
+)
+
+var reportIDs = [...]ReportID{Report0x14, Report0x15, Report0x16, Report0x17, Report0x18, Report0x19}
+
+func (id ReportID) Size() int {
+	switch id {
+	case Report0x14:
+		return 270
+	case Report0x15:
+		return 334
+	case Report0x16:
+		return 398
+	case Report0x17:
+		return 462
+	case Report0x18:
+		return 526
+	case Report0x19:
+		return 547
+	default:
+		return 0
+	}
+}
+
+func (id ReportID) Capacity() int {
+	size := id.Size()
+	if size == 0 {
+		return 0
+	}
+
+	return size - reportHeaderSize - reportCRCSize
+}
+
+type Packer struct {
+	sequence uint16
+}
+
+func NewPacker() *Packer {
+	return &Packer{}
+}
+
+// Pack splits SBC data into whole frames and wraps them into the smallest
+// audio output reports able to hold them. The returned reports are ready to
+// be written to the device as is.
+func (p *Packer) Pack(target Target, sbc []byte) ([][]byte, error) {
+	frames, err := splitFrames(sbc)
+	if err != nil {
+		return nil, err
+	}
+
+	maxCapacity := reportIDs[len(reportIDs)-1].Capacity()
+
+	var reports [][]byte
+	for len(frames) > 0 {
+		var (
+			payload []byte
+			n       int
+		)
+
+		for n < len(frames) && len(payload)+len(frames[n]) <= maxCapacity {
+			payload = append(payload, frames[n]...)
+			n++
+		}
+
+		if n == 0 {
+			return nil, errors.Errorf("audio: SBC frame of %d bytes does not fit into a report", len(frames[0]))
+		}
+
+		reports = append(reports, p.report(target, payload))
+		frames = frames[n:]
+	}
+
+	return reports, nil
+}
+
+func (p *Packer) report(target Target, payload []byte) []byte {
+	id := reportIDs[len(reportIDs)-1]
+	for _, reportID := range reportIDs {
+		if reportID.Capacity() >= len(payload) {
+			id = reportID
+			break
+		}
+	}
+
+	size := id.Size()
+	bytes := make([]byte, size+1)
+	bytes[0] = 0xA2
+	bytes[1] = byte(id)
+	bytes[2] = 0x40
+	bytes[3] = 0xA0
+	binary.LittleEndian.PutUint16(bytes[4:], p.sequence)
+	bytes[6] = byte(target)
+	copy(bytes[1+reportHeaderSize:], payload)
+
+	crc := crc32.ChecksumIEEE(bytes[0 : size+1-reportCRCSize])
+	binary.LittleEndian.PutUint32(bytes[size+1-reportCRCSize:], crc)
+
+	p.sequence++
+
+	return bytes[1:]
+}
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func TestReportSizes(t *testing.T) {
+	tests := []struct {
+		id       ReportID
+		size     int
+		capacity int
+	}{
+		{Report0x14, 270, 260},
+		{Report0x15, 334, 324},
+		{Report0x16, 398, 388},
+		{Report0x17, 462, 452},
+		{Report0x18, 526, 516},
+		{Report0x19, 547, 537},
+		{ReportID(0x11), 0, 0},
+	}
+
+	for _, test := range tests {
+		if test.id.Size() != test.size || test.id.Capacity() != test.capacity {
+			t.Errorf("report %#x: size %d, capacity %d, want %d, %d",
+				byte(test.id), test.id.Size(), test.id.Capacity(), test.size, test.capacity)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	frame := sbcFrame(t, 0xBD, 53) // 119 bytes
+
+	tests := []struct {
+		name   string
+		frames int
+		ids    []ReportID
+	}{
+		{"one frame", 1, []ReportID{Report0x14}},
+		{"two frames", 2, []ReportID{Report0x14}},
+		{"three frames", 3, []ReportID{Report0x16}},
+		{"four frames", 4, []ReportID{Report0x18}},
+		{"five frames", 5, []ReportID{Report0x18, Report0x14}},
+		{"nine frames", 9, []ReportID{Report0x18, Report0x18, Report0x14}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sbc := bytes.Repeat(frame, test.frames)
+
+			reports, err := NewPacker().Pack(TargetHeadphones, sbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(reports) != len(test.ids) {
+				t.Fatalf("got %d reports, want %d", len(reports), len(test.ids))
+			}
+
+			var payload []byte
+
+			for i, report := range reports {
+				id := test.ids[i]
+				frames := (len(sbc) - len(payload)) / len(frame)
+				if frames > 4 {
+					frames = 4
+				}
+
+				assertReport(t, report, id, uint16(i), TargetHeadphones)
+				payload = append(payload, report[reportHeaderSize:reportHeaderSize+frames*len(frame)]...)
+			}
+
+			if !bytes.Equal(payload, sbc) {
+				t.Error("reports don't carry the SBC frames in order")
+			}
+		})
+	}
+}
+
+func TestPackSequence(t *testing.T) {
+	packer := NewPacker()
+	frame := sbcFrame(t, 0x31, 28)
+
+	for i := 0; i < 3; i++ {
+		reports, err := packer.Pack(TargetSpeaker, frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertReport(t, reports[0], Report0x14, uint16(i), TargetSpeaker)
+	}
+}
+
+func TestPackInvalid(t *testing.T) {
+	_, err := NewPacker().Pack(TargetSpeaker, []byte{0x00, 0x01, 0x02, 0x03})
+	if err == nil {
+		t.Error("packing data without SBC sync word succeeded")
+	}
+}
+
+// assertReport checks the header and the CRC of an audio output report, which is checksummed
+// with the Bluetooth HID output header 0xA2 prepended.
+func assertReport(t *testing.T, report []byte, id ReportID, sequence uint16, target Target) {
+	t.Helper()
+
+	if len(report) != id.Size() {
+		t.Fatalf("report size = %d, want %d", len(report), id.Size())
+	}
+
+	header := []byte{byte(id), 0x40, 0xA0, byte(sequence), byte(sequence >> 8), byte(target)}
+	if !bytes.Equal(report[:reportHeaderSize], header) {
+		t.Errorf("report header = % x, want % x", report[:reportHeaderSize], header)
+	}
+
+	crc := crc32.ChecksumIEEE(append([]byte{0xA2}, report[:len(report)-reportCRCSize]...))
+	if got := binary.LittleEndian.Uint32(report[len(report)-reportCRCSize:]); got != crc {
+		t.Errorf("report CRC = %#x, want %#x", got, crc)
+	}
+}
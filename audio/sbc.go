@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const sbcSyncWord = 0x9C
+
+var ErrInvalidSBCFrame = errors.New("audio: invalid SBC frame")
+
+// FrameLength returns the length of the SBC frame starting at b,
+// computed from its header as described in the A2DP specification.
+func FrameLength(b []byte) (int, error) {
+	if len(b) < 4 || b[0] != sbcSyncWord {
+		return 0, ErrInvalidSBCFrame
+	}
+
+	blocks := 4 * (int(b[1]>>4&0x03) + 1)
+	channelMode := b[1] >> 2 & 0x03
+	subbands := 4
+	if b[1]&0x01 != 0 {
+		subbands = 8
+	}
+	bitpool := int(b[2])
+
+	channels := 2
+	if channelMode == 0 {
+		channels = 1
+	}
+
+	length := 4 + 4*subbands*channels/8
+
+	switch channelMode {
+	case 0, 1:
+		length += (blocks*channels*bitpool + 7) / 8
+	case 2:
+		length += (blocks*bitpool + 7) / 8
+	case 3:
+		length += (subbands + blocks*bitpool + 7) / 8
+	}
+
+	return length, nil
+}
+
+// FrameDuration returns the playing time of the SBC frame starting at b.
+func FrameDuration(b []byte) time.Duration {
+	if len(b) < 2 || b[0] != sbcSyncWord {
+		return 0
+	}
+
+	frequencies := [...]int{16000, 32000, 44100, 48000}
+
+	blocks := 4 * (int(b[1]>>4&0x03) + 1)
+	subbands := 4
+	if b[1]&0x01 != 0 {
+		subbands = 8
+	}
+
+	return time.Duration(blocks*subbands) * time.Second / time.Duration(frequencies[b[1]>>6])
+}
+
+func splitFrames(sbc []byte) ([][]byte, error) {
+	var frames [][]byte
+
+	for len(sbc) > 0 {
+		length, err := FrameLength(sbc)
+		if err != nil {
+			return nil, err
+		}
+
+		if length > len(sbc) {
+			return nil, truncatedFrameError(len(sbc), length)
+		}
+
+		frames = append(frames, sbc[:length])
+		sbc = sbc[length:]
+	}
+
+	return frames, nil
+}
+
+func truncatedFrameError(n, length int) error {
+	return errors.Wrapf(ErrInvalidSBCFrame, "truncated frame: %d of %d bytes", n, length)
+}
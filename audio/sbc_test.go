@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sbcFrame returns a zeroed SBC frame with the given header bytes, sized from the header.
+func sbcFrame(t *testing.T, mode, bitpool byte) []byte {
+	t.Helper()
+
+	header := []byte{sbcSyncWord, mode, bitpool, 0}
+
+	length, err := FrameLength(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := make([]byte, length)
+	copy(frame, header)
+
+	return frame
+}
+
+func TestFrameLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		length int
+	}{
+		// 44.1 kHz, 16 blocks, joint stereo, loudness, 8 subbands, bitpool 53
+		{"joint stereo", []byte{0x9C, 0xBD, 53, 0}, 119},
+		// 44.1 kHz, 16 blocks, stereo, 8 subbands, bitpool 53
+		{"stereo", []byte{0x9C, 0xB9, 53, 0}, 118},
+		// 16 kHz, 16 blocks, mono, 8 subbands, bitpool 28
+		{"mono", []byte{0x9C, 0x31, 28, 0}, 64},
+		// 48 kHz, 4 blocks, dual channel, 4 subbands, bitpool 10
+		{"dual channel", []byte{0x9C, 0xC4, 10, 0}, 18},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, err := FrameLength(test.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if length != test.length {
+				t.Errorf("length = %d, want %d", length, test.length)
+			}
+		})
+	}
+}
+
+func TestFrameLengthInvalid(t *testing.T) {
+	for _, header := range [][]byte{nil, {0x9C, 0xBD}, {0x00, 0xBD, 53, 0}} {
+		_, err := FrameLength(header)
+		if errors.Cause(err) != ErrInvalidSBCFrame {
+			t.Errorf("FrameLength(% x) error = %v, want %v", header, err, ErrInvalidSBCFrame)
+		}
+	}
+}
+
+func TestFrameDuration(t *testing.T) {
+	tests := []struct {
+		header   []byte
+		duration time.Duration
+	}{
+		{[]byte{0x9C, 0xBD}, 128 * time.Second / 44100},
+		{[]byte{0x9C, 0x31}, 8 * time.Millisecond},
+		{[]byte{0x9C, 0xC4}, 16 * time.Second / 48000},
+	}
+
+	for _, test := range tests {
+		duration := FrameDuration(test.header)
+		if duration != test.duration {
+			t.Errorf("FrameDuration(% x) = %s, want %s", test.header, duration, test.duration)
+		}
+	}
+}
+
+func TestSplitFrames(t *testing.T) {
+	a := sbcFrame(t, 0xBD, 53)
+	b := sbcFrame(t, 0x31, 28)
+
+	frames, err := splitFrames(append(append([]byte(nil), a...), b...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) != 2 || len(frames[0]) != len(a) || len(frames[1]) != len(b) {
+		t.Fatalf("got %d frames, want frames of %d and %d bytes", len(frames), len(a), len(b))
+	}
+
+	_, err = splitFrames(append(a, b[:10]...))
+	if errors.Cause(err) != ErrInvalidSBCFrame {
+		t.Errorf("truncated frame error = %v, want %v", err, ErrInvalidSBCFrame)
+	}
+}
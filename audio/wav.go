@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidWAV     = errors.New("audio: not a WAV file")
+	ErrUnsupportedWAV = errors.New("audio: only 16-bit PCM mono or stereo WAV files are supported")
+)
+
+// WAV is a 16-bit PCM WAV file. Reads return its interleaved little-endian samples.
+type WAV struct {
+	SampleRate int
+	Channels   int
+	data       io.Reader
+}
+
+// DecodeWAV reads the WAV header up to the data chunk, chunks other than the format are skipped.
+func DecodeWAV(r io.Reader) (*WAV, error) {
+	header := make([]byte, 12)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidWAV, "can't read header")
+	}
+
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, ErrInvalidWAV
+	}
+
+	w := &WAV{}
+	isFormatRead := false
+
+	for {
+		chunk := make([]byte, 8)
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidWAV, "no data chunk")
+		}
+
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:])
+
+		switch id {
+		case "fmt ":
+			format := make([]byte, size+size%2)
+			_, err = io.ReadFull(r, format)
+			if err != nil {
+				return nil, errors.Wrap(ErrInvalidWAV, "truncated format chunk")
+			}
+
+			if size < 16 ||
+				binary.LittleEndian.Uint16(format[0:]) != 1 ||
+				binary.LittleEndian.Uint16(format[14:]) != 16 {
+				return nil, ErrUnsupportedWAV
+			}
+
+			w.Channels = int(binary.LittleEndian.Uint16(format[2:]))
+			w.SampleRate = int(binary.LittleEndian.Uint32(format[4:]))
+
+			// SBC carries mono and stereo only
+			if w.Channels < 1 || w.Channels > 2 || w.SampleRate == 0 {
+				return nil, errors.Wrapf(ErrUnsupportedWAV, "%d channels at %d Hz", w.Channels, w.SampleRate)
+			}
+
+			isFormatRead = true
+		case "data":
+			if !isFormatRead {
+				return nil, errors.Wrap(ErrInvalidWAV, "data chunk precedes format chunk")
+			}
+
+			// A trailing odd byte is half a sample
+			w.data = io.LimitReader(r, int64(size&^1))
+
+			return w, nil
+		default:
+			_, err = io.CopyN(io.Discard, r, int64(size+size%2))
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidWAV, "truncated %q chunk", id)
+			}
+		}
+	}
+}
+
+func (w *WAV) Read(b []byte) (int, error) {
+	return w.data.Read(b)
+}
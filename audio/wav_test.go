@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// wavFile returns a WAV file with a LIST chunk before the format chunk.
+func wavFile(sampleRate, channels, bits int, pcm []byte) []byte {
+	chunk := func(id string, data []byte) []byte {
+		header := make([]byte, 8)
+		copy(header, id)
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+
+		b := append(header, data...)
+		if len(data)%2 != 0 {
+			b = append(b, 0)
+		}
+
+		return b
+	}
+
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], 1)
+	binary.LittleEndian.PutUint16(format[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(format[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(format[8:], uint32(sampleRate*channels*bits/8))
+	binary.LittleEndian.PutUint16(format[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(format[14:], uint16(bits))
+
+	body := []byte("WAVE")
+	body = append(body, chunk("LIST", []byte("INFO0"))...)
+	body = append(body, chunk("fmt ", format)...)
+	body = append(body, chunk("data", pcm)...)
+
+	header := []byte("RIFF\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(body)))
+
+	return append(header, body...)
+}
+
+func TestDecodeWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	wav, err := DecodeWAV(bytes.NewReader(wavFile(44100, 2, 16, pcm)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wav.SampleRate != 44100 || wav.Channels != 2 {
+		t.Errorf("got %d Hz and %d channels, want 44100 Hz and 2 channels", wav.SampleRate, wav.Channels)
+	}
+
+	samples, err := io.ReadAll(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The odd byte and the padding after the data chunk are not samples
+	if !bytes.Equal(samples, pcm[:8]) {
+		t.Errorf("got samples % x, want % x", samples, pcm[:8])
+	}
+}
+
+func TestDecodeWAVInvalid(t *testing.T) {
+	valid := wavFile(32000, 1, 16, []byte{0, 0})
+
+	tests := []struct {
+		name string
+		file []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidWAV},
+		{"not RIFF", append([]byte("RIFX"), valid[4:]...), ErrInvalidWAV},
+		{"no data chunk", valid[:len(valid)-10], ErrInvalidWAV},
+		{"8 bits", wavFile(32000, 1, 8, []byte{0}), ErrUnsupportedWAV},
+		{"6 channels", wavFile(32000, 6, 16, []byte{0, 0}), ErrUnsupportedWAV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeWAV(bytes.NewReader(tt.file))
+			if errors.Cause(err) != tt.err {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package gods4
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kpeu3i/gods4/audio"
+)
+
+func TestWriteAudioOverBluetooth(t *testing.T) {
+	device := newMockDevice()
+	controller := NewController(device)
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeBluetooth})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 44.1 kHz joint stereo frames of 119 bytes, 4 fit into a 0x18 report
+	frame := make([]byte, 119)
+	copy(frame, []byte{0x9C, 0xBD, 53})
+	sbc := bytes.Repeat(frame, 5)
+
+	err = audio.NewPlayer(controller, audio.TargetSpeaker).PlaySBC(context.Background(), bytes.NewReader(sbc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := device.written()
+	if len(writes) != 2 {
+		t.Fatalf("got %d output reports, want 2", len(writes))
+	}
+
+	for i, want := range []struct {
+		id     audio.ReportID
+		frames int
+	}{
+		{audio.Report0x18, 4},
+		{audio.Report0x14, 1},
+	} {
+		report := writes[i]
+		if len(report) != want.id.Size() || report[0] != byte(want.id) {
+			t.Fatalf("report #%d: id %#x of %d bytes, want %#x of %d bytes", i, report[0], len(report), byte(want.id), want.id.Size())
+		}
+
+		if report[3] != byte(i) || report[5] != byte(audio.TargetSpeaker) {
+			t.Errorf("report #%d: sequence %d, target %#x", i, report[3], report[5])
+		}
+
+		if !bytes.Equal(report[6:6+want.frames*len(frame)], bytes.Repeat(frame, want.frames)) {
+			t.Errorf("report #%d doesn't carry %d frames", i, want.frames)
+		}
+	}
+}
+
+func TestWriteAudioOverUSB(t *testing.T) {
+	controller := NewController(newMockDevice())
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.WriteAudio(audio.TargetSpeaker, []byte{0x9C, 0xBD, 53, 0})
+	if err != ErrAudioIsNotSupported {
+		t.Errorf("error = %v, want %v", err, ErrAudioIsNotSupported)
+	}
+}
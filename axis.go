@@ -0,0 +1,114 @@
+package gods4
+
+import (
+	"math"
+)
+
+type Axis uint
+
+const (
+	AxisLeftX Axis = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	AxisL2
+	AxisR2
+)
+
+var axes = [...]Axis{
+	AxisLeftX,
+	AxisLeftY,
+	AxisRightX,
+	AxisRightY,
+	AxisL2,
+	AxisR2,
+}
+
+func (a Axis) String() string {
+	switch a {
+	case AxisLeftX:
+		return "left_x"
+	case AxisLeftY:
+		return "left_y"
+	case AxisRightX:
+		return "right_x"
+	case AxisRightY:
+		return "right_y"
+	case AxisL2:
+		return "l2"
+	case AxisR2:
+		return "r2"
+	default:
+		return ""
+	}
+}
+
+// ParseAxis returns the axis with the given name, as returned by String.
+func ParseAxis(name string) (Axis, bool) {
+	for _, axis := range axes {
+		if axis.String() == name {
+			return axis, true
+		}
+	}
+
+	return 0, false
+}
+
+func (a Axis) isTrigger() bool {
+	return a == AxisL2 || a == AxisR2
+}
+
+// axis returns stick axes in [-1, 1] and trigger travel in [0, 1].
+func (s *state) axis(a Axis) float64 {
+	switch a {
+	case AxisLeftX:
+		return s.leftStick.AxisX
+	case AxisLeftY:
+		return s.leftStick.AxisY
+	case AxisRightX:
+		return s.rightStick.AxisX
+	case AxisRightY:
+		return s.rightStick.AxisY
+	case AxisL2:
+		return float64(s.l2.Value) / 255
+	case AxisR2:
+		return float64(s.r2.Value) / 255
+	default:
+		return 0
+	}
+}
+
+func (s *state) setAxis(a Axis, value float64) {
+	switch a {
+	case AxisLeftX:
+		s.leftStick.AxisX, s.leftStick.X = value, denormalizeAxis(value)
+	case AxisLeftY:
+		s.leftStick.AxisY, s.leftStick.Y = value, denormalizeAxis(value)
+	case AxisRightX:
+		s.rightStick.AxisX, s.rightStick.X = value, denormalizeAxis(value)
+	case AxisRightY:
+		s.rightStick.AxisY, s.rightStick.Y = value, denormalizeAxis(value)
+	case AxisL2:
+		s.l2.Value = byte(math.Round(math.Max(0, math.Min(1, value)) * 255))
+	case AxisR2:
+		s.r2.Value = byte(math.Round(math.Max(0, math.Min(1, value)) * 255))
+	}
+}
+
+// invertAxis mirrors a stick axis around its center, keeping the raw value consistent.
+func (s *state) invertAxis(a Axis) {
+	switch a {
+	case AxisLeftX:
+		s.leftStick.AxisX, s.leftStick.X = -s.leftStick.AxisX, denormalizeAxis(-normalizeAxis(s.leftStick.X))
+	case AxisLeftY:
+		s.leftStick.AxisY, s.leftStick.Y = -s.leftStick.AxisY, denormalizeAxis(-normalizeAxis(s.leftStick.Y))
+	case AxisRightX:
+		s.rightStick.AxisX, s.rightStick.X = -s.rightStick.AxisX, denormalizeAxis(-normalizeAxis(s.rightStick.X))
+	case AxisRightY:
+		s.rightStick.AxisY, s.rightStick.Y = -s.rightStick.AxisY, denormalizeAxis(-normalizeAxis(s.rightStick.Y))
+	case AxisL2:
+		s.l2.Value = 255 - s.l2.Value
+	case AxisR2:
+		s.r2.Value = 255 - s.r2.Value
+	}
+}
@@ -0,0 +1,245 @@
+package gods4
+
+type Button uint
+
+const (
+	ButtonCross Button = iota
+	ButtonCircle
+	ButtonSquare
+	ButtonTriangle
+	ButtonL1
+	ButtonL2
+	ButtonL3
+	ButtonR1
+	ButtonR2
+	ButtonR3
+	ButtonDPadUp
+	ButtonDPadDown
+	ButtonDPadLeft
+	ButtonDPadRight
+	ButtonShare
+	ButtonOptions
+	ButtonTouchpad
+	ButtonPS
+)
+
+var buttons = [...]Button{
+	ButtonCross,
+	ButtonCircle,
+	ButtonSquare,
+	ButtonTriangle,
+	ButtonL1,
+	ButtonL2,
+	ButtonL3,
+	ButtonR1,
+	ButtonR2,
+	ButtonR3,
+	ButtonDPadUp,
+	ButtonDPadDown,
+	ButtonDPadLeft,
+	ButtonDPadRight,
+	ButtonShare,
+	ButtonOptions,
+	ButtonTouchpad,
+	ButtonPS,
+}
+
+// String returns the button name used as a prefix of its events.
+func (b Button) String() string {
+	switch b {
+	case ButtonCross:
+		return "cross"
+	case ButtonCircle:
+		return "circle"
+	case ButtonSquare:
+		return "square"
+	case ButtonTriangle:
+		return "triangle"
+	case ButtonL1:
+		return "l1"
+	case ButtonL2:
+		return "l2"
+	case ButtonL3:
+		return "l3"
+	case ButtonR1:
+		return "r1"
+	case ButtonR2:
+		return "r2"
+	case ButtonR3:
+		return "r3"
+	case ButtonDPadUp:
+		return "dpad_up"
+	case ButtonDPadDown:
+		return "dpad_down"
+	case ButtonDPadLeft:
+		return "dpad_left"
+	case ButtonDPadRight:
+		return "dpad_right"
+	case ButtonShare:
+		return "share"
+	case ButtonOptions:
+		return "options"
+	case ButtonTouchpad:
+		return "touchpad"
+	case ButtonPS:
+		return "ps"
+	default:
+		return ""
+	}
+}
+
+func (b Button) PressEvent() Event {
+	return b.event("press")
+}
+
+func (b Button) ReleaseEvent() Event {
+	return b.event("release")
+}
+
+// ParseButton returns the button with the given name, as returned by String.
+func ParseButton(name string) (Button, bool) {
+	for _, button := range buttons {
+		if button.String() == name {
+			return button, true
+		}
+	}
+
+	return 0, false
+}
+
+// Buttons returns all buttons.
+func Buttons() []Button {
+	return append([]Button(nil), buttons[:]...)
+}
+
+func (b Button) event(action string) Event {
+	return Event(b.String() + "." + action)
+}
+
+func (s *state) button(b Button) bool {
+	switch b {
+	case ButtonCross:
+		return s.cross
+	case ButtonCircle:
+		return s.circle
+	case ButtonSquare:
+		return s.square
+	case ButtonTriangle:
+		return s.triangle
+	case ButtonL1:
+		return s.l1
+	case ButtonL2:
+		return s.l2.IsPressed
+	case ButtonL3:
+		return s.l3
+	case ButtonR1:
+		return s.r1
+	case ButtonR2:
+		return s.r2.IsPressed
+	case ButtonR3:
+		return s.r3
+	case ButtonDPadUp:
+		return s.dPad.IsUp()
+	case ButtonDPadDown:
+		return s.dPad.IsDown()
+	case ButtonDPadLeft:
+		return s.dPad.IsLeft()
+	case ButtonDPadRight:
+		return s.dPad.IsRight()
+	case ButtonShare:
+		return s.share
+	case ButtonOptions:
+		return s.options
+	case ButtonTouchpad:
+		return s.touchpad.Press
+	case ButtonPS:
+		return s.ps
+	default:
+		return false
+	}
+}
+
+// IsPressed reports whether the button is pressed, L2 and R2 by their press threshold.
+func (s *State) IsPressed(b Button) bool {
+	switch b {
+	case ButtonCross:
+		return s.Cross
+	case ButtonCircle:
+		return s.Circle
+	case ButtonSquare:
+		return s.Square
+	case ButtonTriangle:
+		return s.Triangle
+	case ButtonL1:
+		return s.L1
+	case ButtonL2:
+		return s.L2.IsPressed
+	case ButtonL3:
+		return s.L3
+	case ButtonR1:
+		return s.R1
+	case ButtonR2:
+		return s.R2.IsPressed
+	case ButtonR3:
+		return s.R3
+	case ButtonDPadUp:
+		return s.DPad.IsUp()
+	case ButtonDPadDown:
+		return s.DPad.IsDown()
+	case ButtonDPadLeft:
+		return s.DPad.IsLeft()
+	case ButtonDPadRight:
+		return s.DPad.IsRight()
+	case ButtonShare:
+		return s.Share
+	case ButtonOptions:
+		return s.Options
+	case ButtonTouchpad:
+		return s.Touchpad.Press
+	case ButtonPS:
+		return s.PS
+	default:
+		return false
+	}
+}
+
+func (s *state) setButton(b Button, isPressed bool) {
+	switch b {
+	case ButtonCross:
+		s.cross = isPressed
+	case ButtonCircle:
+		s.circle = isPressed
+	case ButtonSquare:
+		s.square = isPressed
+	case ButtonTriangle:
+		s.triangle = isPressed
+	case ButtonL1:
+		s.l1 = isPressed
+	case ButtonL2:
+		s.l2.IsPressed = isPressed
+	case ButtonL3:
+		s.l3 = isPressed
+	case ButtonR1:
+		s.r1 = isPressed
+	case ButtonR2:
+		s.r2.IsPressed = isPressed
+	case ButtonR3:
+		s.r3 = isPressed
+	case ButtonDPadUp:
+		s.dPad = directionOf(isPressed, s.dPad.IsDown(), s.dPad.IsLeft(), s.dPad.IsRight())
+	case ButtonDPadDown:
+		s.dPad = directionOf(s.dPad.IsUp(), isPressed, s.dPad.IsLeft(), s.dPad.IsRight())
+	case ButtonDPadLeft:
+		s.dPad = directionOf(s.dPad.IsUp(), s.dPad.IsDown(), isPressed, s.dPad.IsRight())
+	case ButtonDPadRight:
+		s.dPad = directionOf(s.dPad.IsUp(), s.dPad.IsDown(), s.dPad.IsLeft(), isPressed)
+	case ButtonShare:
+		s.share = isPressed
+	case ButtonOptions:
+		s.options = isPressed
+	case ButtonTouchpad:
+		s.touchpad.Press = isPressed
+	case ButtonPS:
+		s.ps = isPressed
+	}
+}
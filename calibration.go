@@ -0,0 +1,132 @@
+package gods4
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Feature report with the DualShock 4 MAC address, available over USB only
+const getFeatureReportCode0x12 = 0x12
+
+var ErrInvalidCalibration = errors.New("ds4: invalid calibration")
+
+// AxisCalibration is the raw center and range of a stick axis. The zero value leaves the axis uncalibrated.
+type AxisCalibration struct {
+	Min    byte
+	Center byte
+	Max    byte
+}
+
+type StickCalibration struct {
+	X AxisCalibration
+	Y AxisCalibration
+}
+
+// Calibration maps worn sticks back to the full [-1, 1] range around their actual center.
+// Raw stick values in State are left as reported.
+type Calibration struct {
+	LeftStick  StickCalibration
+	RightStick StickCalibration
+}
+
+// CalibrationStore looks up calibrations by controller serial, see Controller.Serial.
+type CalibrationStore interface {
+	Calibration(serial string) (Calibration, bool)
+}
+
+func (c AxisCalibration) IsZero() bool {
+	return c == AxisCalibration{}
+}
+
+func (c AxisCalibration) Validate() error {
+	if c.IsZero() {
+		return nil
+	}
+
+	if c.Min >= c.Center || c.Center >= c.Max {
+		return errors.Wrapf(ErrInvalidCalibration, "min (%d), center (%d) and max (%d) must be increasing", c.Min, c.Center, c.Max)
+	}
+
+	return nil
+}
+
+func (c Calibration) Validate() error {
+	for _, axis := range []struct {
+		name        string
+		calibration AxisCalibration
+	}{
+		{"left stick x", c.LeftStick.X},
+		{"left stick y", c.LeftStick.Y},
+		{"right stick x", c.RightStick.X},
+		{"right stick y", c.RightStick.Y},
+	} {
+		err := axis.calibration.Validate()
+		if err != nil {
+			return errors.Wrap(err, axis.name)
+		}
+	}
+
+	return nil
+}
+
+func (c AxisCalibration) normalize(value byte) float64 {
+	if c.IsZero() {
+		return normalizeAxis(value)
+	}
+
+	if value >= c.Center {
+		return math.Min(1, float64(value-c.Center)/float64(c.Max-c.Center))
+	}
+
+	return math.Max(-1, -float64(c.Center-value)/float64(c.Center-c.Min))
+}
+
+// deviceSerial identifies a controller by its MAC address, read from the device serial
+// or, over USB where the serial is usually empty, from feature report 0x12 (0x09 on DualSense).
+func deviceSerial(device Device, model Model, connectionType ConnectionType) string {
+	serial := normalizeSerial(device.Serial())
+	if serial != "" || connectionType != ConnectionTypeUSB {
+		return serial
+	}
+
+	code := byte(getFeatureReportCode0x12)
+	if model == ModelDualSense {
+		code = getFeatureReportCode0x09
+	}
+
+	bytes, err := device.GetFeatureReport(code)
+	if err != nil || len(bytes) < 7 {
+		return ""
+	}
+
+	// The address is stored in reverse byte order
+	mac := make([]byte, 6)
+	for i := range mac {
+		mac[i] = bytes[6-i]
+	}
+
+	return formatMAC(mac)
+}
+
+func normalizeSerial(serial string) string {
+	serial = strings.ToLower(strings.TrimSpace(serial))
+
+	mac, err := parseMAC(serial)
+	if err != nil {
+		return serial
+	}
+
+	return formatMAC(mac)
+}
+
+func formatMAC(mac []byte) string {
+	parts := make([]string, len(mac))
+	for i, b := range mac {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+
+	return strings.Join(parts, ":")
+}
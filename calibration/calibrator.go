@@ -0,0 +1,170 @@
+package calibration
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// An axis must travel at least this far from its center in both directions to be calibrated
+const minTravel = 64
+
+var (
+	ErrNoCenterSamples = errors.New("calibration: no samples with centered sticks")
+	ErrIncompleteRange = errors.New("calibration: sticks were not rotated fully")
+)
+
+type Phase int
+
+const (
+	PhaseNone Phase = iota
+	// Sticks are left centered
+	PhaseCenter
+	// Sticks are rotated along their edges
+	PhaseRange
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseCenter:
+		return "center"
+	case PhaseRange:
+		return "range"
+	default:
+		return "none"
+	}
+}
+
+// Calibrator collects raw stick values, the center in PhaseCenter and the range in PhaseRange.
+type Calibrator struct {
+	mutex   sync.Mutex
+	detach  func()
+	phase   Phase
+	centers [4]axisCenter
+	ranges  [4]axisRange
+}
+
+type axisCenter struct {
+	sum   float64
+	count int
+}
+
+type axisRange struct {
+	min, max byte
+	count    int
+}
+
+func NewCalibrator() *Calibrator {
+	return &Calibrator{}
+}
+
+// Attach samples every report of a live session as decoded, before profiles swap
+// or invert sticks. Event callbacks are left alone.
+func (c *Calibrator) Attach(controller *gods4.Controller) {
+	detach := controller.OnRawState(c.Add)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.detach != nil {
+		c.detach()
+	}
+
+	c.detach = detach
+}
+
+// Detach stops sampling the attached controller.
+func (c *Calibrator) Detach() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.detach != nil {
+		c.detach()
+		c.detach = nil
+	}
+}
+
+func (c *Calibrator) SetPhase(phase Phase) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.phase = phase
+}
+
+func (c *Calibrator) Phase() Phase {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.phase
+}
+
+func (c *Calibrator) Add(state gods4.State) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	values := [4]byte{state.LeftStick.X, state.LeftStick.Y, state.RightStick.X, state.RightStick.Y}
+
+	for i, value := range values {
+		switch c.phase {
+		case PhaseCenter:
+			c.centers[i].sum += float64(value)
+			c.centers[i].count++
+		case PhaseRange:
+			r := &c.ranges[i]
+			if r.count == 0 || value < r.min {
+				r.min = value
+			}
+
+			if r.count == 0 || value > r.max {
+				r.max = value
+			}
+
+			r.count++
+		}
+	}
+}
+
+// Reset drops all samples and returns to PhaseNone.
+func (c *Calibrator) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.phase = PhaseNone
+	c.centers = [4]axisCenter{}
+	c.ranges = [4]axisRange{}
+}
+
+// Calibration returns the mean centered value and the extremes reached of every stick axis.
+func (c *Calibrator) Calibration() (gods4.Calibration, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	names := [4]string{"left stick x", "left stick y", "right stick x", "right stick y"}
+
+	var axes [4]gods4.AxisCalibration
+
+	for i := range axes {
+		if c.centers[i].count == 0 {
+			return gods4.Calibration{}, ErrNoCenterSamples
+		}
+
+		center := byte(math.Round(c.centers[i].sum / float64(c.centers[i].count)))
+		r := c.ranges[i]
+
+		if r.count == 0 || int(center)-int(r.min) < minTravel || int(r.max)-int(center) < minTravel {
+			return gods4.Calibration{}, errors.Wrapf(ErrIncompleteRange, "%s reached [%d, %d] around %d", names[i], r.min, r.max, center)
+		}
+
+		axes[i] = gods4.AxisCalibration{Min: r.min, Center: center, Max: r.max}
+	}
+
+	calibration := gods4.Calibration{
+		LeftStick:  gods4.StickCalibration{X: axes[0], Y: axes[1]},
+		RightStick: gods4.StickCalibration{X: axes[2], Y: axes[3]},
+	}
+
+	return calibration, calibration.Validate()
+}
@@ -0,0 +1,223 @@
+// Package calibration measures stick center and range of worn controllers
+// and stores the result per controller in a local JSON file.
+package calibration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+)
+
+const version = 1
+
+var (
+	ErrUnsupportedVersion = errors.New("calibration: unsupported version")
+	ErrSerialIsRequired   = errors.New("calibration: serial is required")
+)
+
+// Store holds calibrations by controller serial, see gods4.Controller.Serial.
+// It is safe for concurrent use and implements gods4.CalibrationStore.
+type Store struct {
+	mutex        sync.RWMutex
+	path         string
+	calibrations map[string]gods4.Calibration
+}
+
+type file struct {
+	Version     int                    `json:"version"`
+	Controllers map[string]calibration `json:"controllers"`
+}
+
+type calibration struct {
+	LeftStick  stick `json:"left_stick"`
+	RightStick stick `json:"right_stick"`
+}
+
+type stick struct {
+	X axis `json:"x"`
+	Y axis `json:"y"`
+}
+
+type axis struct {
+	Min    byte `json:"min"`
+	Center byte `json:"center"`
+	Max    byte `json:"max"`
+}
+
+// DefaultPath returns calibration.json in the gods4 directory of the user config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "gods4", "calibration.json"), nil
+}
+
+// Open loads the store from path, a missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:         path,
+		calibrations: make(map[string]gods4.Calibration),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "calibration: %s", path)
+	}
+
+	if f.Version != version {
+		return nil, errors.Wrapf(ErrUnsupportedVersion, "%s: version %d", path, f.Version)
+	}
+
+	for serial, c := range f.Controllers {
+		calibration := c.decode()
+
+		err = calibration.Validate()
+		if err != nil {
+			return nil, errors.Wrapf(err, "calibration: %s: controller %q", path, serial)
+		}
+
+		s.calibrations[serial] = calibration
+	}
+
+	return s, nil
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) Calibration(serial string) (gods4.Calibration, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	calibration, ok := s.calibrations[serial]
+
+	return calibration, ok
+}
+
+// Serials returns the serials of all stored controllers in sorted order.
+func (s *Store) Serials() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	serials := make([]string, 0, len(s.calibrations))
+	for serial := range s.calibrations {
+		serials = append(serials, serial)
+	}
+
+	sort.Strings(serials)
+
+	return serials
+}
+
+// Set stores the calibration in memory, Save writes it to the file.
+func (s *Store) Set(serial string, calibration gods4.Calibration) error {
+	if serial == "" {
+		return ErrSerialIsRequired
+	}
+
+	err := calibration.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calibrations[serial] = calibration
+
+	return nil
+}
+
+func (s *Store) Delete(serial string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.calibrations, serial)
+}
+
+// Save writes the store to a temporary file and renames it, so the file is never left half written.
+func (s *Store) Save() error {
+	s.mutex.RLock()
+
+	f := file{
+		Version:     version,
+		Controllers: make(map[string]calibration, len(s.calibrations)),
+	}
+
+	for serial, c := range s.calibrations {
+		f.Controllers[serial] = encode(c)
+	}
+
+	s.mutex.RUnlock()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+func encode(c gods4.Calibration) calibration {
+	encodeStick := func(s gods4.StickCalibration) stick {
+		return stick{X: axis(s.X), Y: axis(s.Y)}
+	}
+
+	return calibration{LeftStick: encodeStick(c.LeftStick), RightStick: encodeStick(c.RightStick)}
+}
+
+func (c calibration) decode() gods4.Calibration {
+	decodeStick := func(s stick) gods4.StickCalibration {
+		return gods4.StickCalibration{X: gods4.AxisCalibration(s.X), Y: gods4.AxisCalibration(s.Y)}
+	}
+
+	return gods4.Calibration{LeftStick: decodeStick(c.LeftStick), RightStick: decodeStick(c.RightStick)}
+}
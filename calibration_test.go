@@ -0,0 +1,128 @@
+package gods4
+
+import (
+	"io"
+	"testing"
+)
+
+type mapStore map[string]Calibration
+
+func (s mapStore) Calibration(serial string) (Calibration, bool) {
+	calibration, ok := s[serial]
+
+	return calibration, ok
+}
+
+// usbInput returns a USB input report with the given raw stick values.
+func usbInput(leftX, leftY, rightX, rightY byte) []byte {
+	report := make([]byte, 64)
+	report[0] = usbInputReportID
+	report[1], report[2], report[3], report[4] = leftX, leftY, rightX, rightY
+	report[5] = 0x08
+
+	return report
+}
+
+func TestCalibrationStoreMiss(t *testing.T) {
+	worn := AxisCalibration{Min: 10, Center: 120, Max: 240}
+	store := mapStore{"a4:53:85:00:00:01": {LeftStick: StickCalibration{X: worn}}}
+
+	device := newMockDevice()
+	device.serial = "a4:53:85:00:00:01"
+	controller := NewController(device)
+	controller.SetCalibrationStore(store)
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if controller.Calibration().LeftStick.X != worn {
+		t.Fatalf("stored calibration was not applied: %+v", controller.Calibration())
+	}
+
+	err = controller.Disconnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if controller.Calibration() != (Calibration{}) {
+		t.Errorf("calibration kept after Disconnect: %+v", controller.Calibration())
+	}
+
+	err = controller.SetCalibration(Calibration{LeftStick: StickCalibration{X: worn}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	device.serial = "a4:53:85:00:00:02"
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if controller.Calibration() != (Calibration{}) {
+		t.Errorf("controller missing from the store got calibration %+v", controller.Calibration())
+	}
+}
+
+func TestRawStateIsNotRemapped(t *testing.T) {
+	device := newMockDevice(usbInput(10, 20, 200, 210))
+	controller := NewController(device)
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.AddProfile(Profile{Name: "swapped", Rules: []Rule{SwapSticks()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.SetProfile("swapped")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw, remapped []State
+
+	controller.On(EventStateUpdate, func(data interface{}) error {
+		remapped = append(remapped, data.(State))
+
+		return nil
+	})
+
+	remove := controller.OnRawState(func(state State) {
+		raw = append(raw, state)
+	})
+	defer remove()
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want io.EOF", err)
+	}
+
+	if len(raw) != 1 || len(remapped) != 1 {
+		t.Fatalf("got %d raw and %d remapped states, want 1 of each", len(raw), len(remapped))
+	}
+
+	if raw[0].LeftStick.X != 10 || raw[0].RightStick.X != 200 {
+		t.Errorf("raw sticks are remapped: left x %d, right x %d", raw[0].LeftStick.X, raw[0].RightStick.X)
+	}
+
+	if remapped[0].LeftStick.X != 200 {
+		t.Errorf("remapped left x = %d, want 200", remapped[0].LeftStick.X)
+	}
+}
@@ -0,0 +1,231 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// fakeDevice is a DualShock 4 answering reads from a list of input reports.
+type fakeDevice struct {
+	inputs [][]byte
+	err    error
+}
+
+func (d *fakeDevice) VendorID() uint16     { return 0x054C }
+func (d *fakeDevice) ProductID() uint16    { return 0x09CC }
+func (d *fakeDevice) Path() string         { return "fake" }
+func (d *fakeDevice) Release() uint16      { return 0x0100 }
+func (d *fakeDevice) Serial() string       { return "a4:53:85:00:00:01" }
+func (d *fakeDevice) Manufacturer() string { return "Sony" }
+func (d *fakeDevice) Product() string      { return "Wireless Controller" }
+func (d *fakeDevice) Open() error          { return nil }
+func (d *fakeDevice) Close() error         { return nil }
+
+func (d *fakeDevice) Read(b []byte) (int, error) {
+	if len(d.inputs) == 0 {
+		return 0, d.err
+	}
+
+	n := copy(b, d.inputs[0])
+	d.inputs = d.inputs[1:]
+
+	return n, nil
+}
+
+func (d *fakeDevice) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (d *fakeDevice) GetFeatureReport(code byte) ([]byte, error) {
+	return []byte{code, 0xAA}, nil
+}
+
+func (d *fakeDevice) SendFeatureReport(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := []byte{0x01, 0x80, 0x80, 0x80, 0x80, 0x08, 0x00, 0x00, 0x00, 0x00}
+	device := &fakeDevice{inputs: [][]byte{input}, err: errors.New("device is gone")}
+
+	var file bytes.Buffer
+
+	recorder, err := NewRecorder(device, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A controller in the reduced report mode sends 0x01 reports over Bluetooth
+	recorder.SetConnectionType(gods4.ConnectionTypeBluetooth)
+
+	buf := make([]byte, 64)
+
+	n, err := recorder.Read(buf)
+	if err != nil || n != len(input) {
+		t.Fatalf("read %d bytes, error %v", n, err)
+	}
+
+	_, err = recorder.Write([]byte{0x05, 0xFF})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = recorder.GetFeatureReport(0x02)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = recorder.SendFeatureReport([]byte{0x13, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = recorder.Read(buf)
+	if err == nil {
+		t.Fatal("read error was not returned")
+	}
+
+	err = recorder.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := reader.Metadata()
+	if metadata.ProductID != 0x09CC || metadata.Serial != device.Serial() || metadata.Path != "fake" {
+		t.Errorf("unexpected metadata: %+v", metadata)
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Kind: KindTransport, Data: []byte{byte(TransportBluetooth)}},
+		{Kind: KindInput, Data: input},
+		{Kind: KindOutput, Data: []byte{0x05, 0xFF}},
+		{Kind: KindFeatureReport, Code: 0x02, Data: []byte{0x02, 0xAA}},
+		{Kind: KindSentFeatureReport, Code: 0x13, Data: []byte{0x01}},
+		{Kind: KindReadError, Data: []byte("device is gone")},
+	}
+
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+
+	for i, record := range records {
+		if i > 0 && record.Time < records[i-1].Time {
+			t.Errorf("record #%d goes back in time", i)
+		}
+
+		record.Time = 0
+		if !reflect.DeepEqual(record, want[i]) {
+			t.Errorf("record #%d = %+v, want %+v", i, record, want[i])
+		}
+	}
+
+	if reader.Transport() != TransportBluetooth {
+		t.Errorf("transport = %v, want BT", reader.Transport())
+	}
+
+	player, err := NewPlayer(bytes.NewReader(file.Bytes()), TimingFast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = player.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = player.Read(buf)
+	if err != nil || !bytes.Equal(buf[:n], input) {
+		t.Errorf("played % x, error %v, want % x", buf[:n], err, input)
+	}
+
+	_, err = player.Read(buf)
+	if _, ok := err.(ReadError); !ok {
+		t.Errorf("got error %v, want the recorded read error", err)
+	}
+
+	_, err = player.Read(buf)
+	if err != io.EOF {
+		t.Errorf("got error %v, want io.EOF", err)
+	}
+
+	feature, err := player.GetFeatureReport(0x02)
+	if err != nil || !bytes.Equal(feature, []byte{0x02, 0xAA}) {
+		t.Errorf("feature report % x, error %v", feature, err)
+	}
+}
+
+func TestReaderRejectsInvalidCaptures(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":     nil,
+		"bad magic": []byte("XXXXXXXX\x01"),
+		"truncated": []byte(magic),
+		"version":   append([]byte(magic), Version+1),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(data))
+			if err == nil {
+				t.Error("invalid capture was accepted")
+			}
+		})
+	}
+}
+
+func TestRecorderTakesTransportFromController(t *testing.T) {
+	var file bytes.Buffer
+
+	recorder, err := NewRecorder(&fakeDevice{err: io.EOF}, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := gods4.NewController(recorder)
+
+	err = controller.ConfigureConnection(gods4.ConnectionConfig{
+		Type:       gods4.ConnectionTypeBluetooth,
+		ReportMode: gods4.ReportModeReduced,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Disconnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.Transport() != TransportBluetooth {
+		t.Errorf("transport = %v, want BT", reader.Transport())
+	}
+}
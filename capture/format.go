@@ -0,0 +1,195 @@
+// Package capture records raw HID traffic of a controller to a compact binary file.
+//
+// A capture starts with the magic "DS4CAP", a version byte and a metadata record.
+// Every record is a kind byte, the time since the previous record in nanoseconds
+// and the payload length (both unsigned varints), followed by the payload.
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const Version = 1
+
+const magic = "DS4CAP"
+
+// Payloads larger than this are treated as a corrupt capture
+const maxPayloadSize = 1 << 16
+
+var (
+	ErrInvalidCapture     = errors.New("capture: invalid capture")
+	ErrUnsupportedVersion = errors.New("capture: unsupported version")
+)
+
+type Kind byte
+
+const (
+	KindMetadata Kind = iota + 1
+	KindTransport
+	KindInput
+	KindOutput
+	KindFeatureReport
+	KindReadError
+	KindSentFeatureReport
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindMetadata:
+		return "metadata"
+	case KindTransport:
+		return "transport"
+	case KindInput:
+		return "input"
+	case KindOutput:
+		return "output"
+	case KindFeatureReport:
+		return "feature_report"
+	case KindReadError:
+		return "read_error"
+	case KindSentFeatureReport:
+		return "sent_feature_report"
+	default:
+		return ""
+	}
+}
+
+type Transport byte
+
+const (
+	TransportUnknown Transport = iota
+	TransportUSB
+	TransportBluetooth
+)
+
+func (t Transport) String() string {
+	switch t {
+	case TransportUSB:
+		return "USB"
+	case TransportBluetooth:
+		return "BT"
+	default:
+		return "unknown"
+	}
+}
+
+// Metadata describes the recorded device and when the capture was started.
+type Metadata struct {
+	StartedAt    time.Time
+	VendorID     uint16
+	ProductID    uint16
+	Release      uint16
+	Path         string
+	Serial       string
+	Manufacturer string
+	Product      string
+}
+
+// Record is a single recorded event. Time is measured from the start of the capture.
+// Code is the report code of a feature report, Data is the report or the error message.
+type Record struct {
+	Kind Kind
+	Time time.Duration
+	Code byte
+	Data []byte
+}
+
+func (m Metadata) encode() []byte {
+	payload := make([]byte, 14)
+	binary.LittleEndian.PutUint64(payload[0:], uint64(m.StartedAt.UnixNano()))
+	binary.LittleEndian.PutUint16(payload[8:], m.VendorID)
+	binary.LittleEndian.PutUint16(payload[10:], m.ProductID)
+	binary.LittleEndian.PutUint16(payload[12:], m.Release)
+
+	for _, s := range []string{m.Path, m.Serial, m.Manufacturer, m.Product} {
+		payload = binary.AppendUvarint(payload, uint64(len(s)))
+		payload = append(payload, s...)
+	}
+
+	return payload
+}
+
+func decodeMetadata(payload []byte) (Metadata, error) {
+	if len(payload) < 14 {
+		return Metadata{}, errors.Wrap(ErrInvalidCapture, "metadata is too short")
+	}
+
+	m := Metadata{
+		StartedAt: time.Unix(0, int64(binary.LittleEndian.Uint64(payload[0:]))),
+		VendorID:  binary.LittleEndian.Uint16(payload[8:]),
+		ProductID: binary.LittleEndian.Uint16(payload[10:]),
+		Release:   binary.LittleEndian.Uint16(payload[12:]),
+	}
+
+	payload = payload[14:]
+	for _, s := range []*string{&m.Path, &m.Serial, &m.Manufacturer, &m.Product} {
+		length, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < length {
+			return Metadata{}, errors.Wrap(ErrInvalidCapture, "metadata string is truncated")
+		}
+
+		*s = string(payload[n : n+int(length)])
+		payload = payload[n+int(length):]
+	}
+
+	return m, nil
+}
+
+func writeRecord(w *bufio.Writer, kind Kind, delta time.Duration, payload []byte) error {
+	header := make([]byte, 1, 1+2*binary.MaxVarintLen64)
+	header[0] = byte(kind)
+	header = binary.AppendUvarint(header, uint64(delta))
+	header = binary.AppendUvarint(header, uint64(len(payload)))
+
+	_, err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+
+	return err
+}
+
+func readRecord(r *bufio.Reader) (Kind, time.Duration, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	delta, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, nil, truncated(err)
+	}
+
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, nil, truncated(err)
+	}
+
+	if length > maxPayloadSize {
+		return 0, 0, nil, errors.Wrapf(ErrInvalidCapture, "record of %d bytes", length)
+	}
+
+	payload := make([]byte, length)
+
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return 0, 0, nil, truncated(err)
+	}
+
+	return Kind(kind), time.Duration(delta), payload, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.Wrap(ErrInvalidCapture, "record is truncated")
+	}
+
+	return err
+}
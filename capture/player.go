@@ -0,0 +1,273 @@
+package capture
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrPlayerIsClosed  = errors.New("capture: player is closed")
+	ErrInvalidTiming   = errors.New("capture: invalid timing")
+	ErrNoFeatureReport = errors.New("capture: feature report is not in the capture")
+)
+
+// ReadError is a read error of the recorded device, returned when it is played back.
+type ReadError string
+
+func (e ReadError) Error() string {
+	return "capture: recorded read error: " + string(e)
+}
+
+// Timing is the pace of playback: Speed 1 keeps the original timing,
+// 2 plays twice as fast and 0 plays as fast as possible.
+type Timing struct {
+	Speed float64
+}
+
+var (
+	TimingOriginal = Timing{Speed: 1}
+	TimingFast     = Timing{Speed: 0}
+)
+
+func TimingScaled(speed float64) Timing {
+	return Timing{Speed: speed}
+}
+
+// Player is a Device that plays back a capture. Input reports are returned by Read,
+// feature reports are answered from the capture, writes and sent feature reports are
+// kept for assertions. Read returns io.EOF at the end of the capture, Open rewinds it.
+type Player struct {
+	mutex     sync.Mutex
+	metadata  Metadata
+	transport Transport
+	inputs    []Record
+	features  map[byte][]byte
+	timing    Timing
+	next      int
+	start     time.Time
+	writes    [][]byte
+	sent      [][]byte
+	isOpen    bool
+	closed    chan struct{}
+}
+
+func NewPlayer(r io.Reader, timing Timing) (*Player, error) {
+	if timing.Speed < 0 || math.IsNaN(timing.Speed) || math.IsInf(timing.Speed, 0) {
+		return nil, errors.Wrapf(ErrInvalidTiming, "speed %v", timing.Speed)
+	}
+
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Player{
+		metadata:  reader.Metadata(),
+		transport: reader.Transport(),
+		features:  make(map[byte][]byte),
+		timing:    timing,
+		closed:    make(chan struct{}),
+	}
+
+	for _, record := range records {
+		switch record.Kind {
+		case KindInput, KindReadError:
+			p.inputs = append(p.inputs, record)
+		case KindFeatureReport:
+			if _, ok := p.features[record.Code]; !ok {
+				p.features[record.Code] = record.Data
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Player) VendorID() uint16 {
+	return p.metadata.VendorID
+}
+
+func (p *Player) ProductID() uint16 {
+	return p.metadata.ProductID
+}
+
+func (p *Player) Path() string {
+	return p.metadata.Path
+}
+
+func (p *Player) Release() uint16 {
+	return p.metadata.Release
+}
+
+func (p *Player) Serial() string {
+	return p.metadata.Serial
+}
+
+func (p *Player) Manufacturer() string {
+	return p.metadata.Manufacturer
+}
+
+func (p *Player) Product() string {
+	return p.metadata.Product
+}
+
+func (p *Player) Metadata() Metadata {
+	return p.metadata
+}
+
+// IsBluetooth returns the recorded transport, so Connect doesn't read reports to detect it.
+// Ok is false for captures without a transport record.
+func (p *Player) IsBluetooth() (bool, bool) {
+	return p.transport == TransportBluetooth, p.transport != TransportUnknown
+}
+
+func (p *Player) Open() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.next = 0
+	p.start = time.Time{}
+	p.isOpen = true
+	p.closed = make(chan struct{})
+
+	return nil
+}
+
+func (p *Player) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.isOpen {
+		return ErrPlayerIsClosed
+	}
+
+	p.isOpen = false
+	close(p.closed)
+
+	return nil
+}
+
+// Read waits until the next input report is due and copies it into b.
+func (p *Player) Read(b []byte) (int, error) {
+	p.mutex.Lock()
+
+	if !p.isOpen {
+		p.mutex.Unlock()
+
+		return 0, ErrPlayerIsClosed
+	}
+
+	if p.next >= len(p.inputs) {
+		p.mutex.Unlock()
+
+		return 0, io.EOF
+	}
+
+	record := p.inputs[p.next]
+	p.next++
+
+	// Timing is relative to the first input report, not to the start of the capture
+	if p.start.IsZero() {
+		p.start = time.Now().Add(-p.scale(record.Time))
+	}
+
+	wait := time.Until(p.start.Add(p.scale(record.Time)))
+	closed := p.closed
+
+	p.mutex.Unlock()
+
+	if p.timing.Speed > 0 && wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-closed:
+			return 0, ErrPlayerIsClosed
+		}
+	}
+
+	if record.Kind == KindReadError {
+		return 0, ReadError(record.Data)
+	}
+
+	return copy(b, record.Data), nil
+}
+
+func (p *Player) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.isOpen {
+		return 0, ErrPlayerIsClosed
+	}
+
+	p.writes = append(p.writes, append([]byte(nil), b...))
+
+	return len(b), nil
+}
+
+func (p *Player) SendFeatureReport(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.isOpen {
+		return 0, ErrPlayerIsClosed
+	}
+
+	p.sent = append(p.sent, append([]byte(nil), b...))
+
+	return len(b), nil
+}
+
+func (p *Player) GetFeatureReport(code byte) ([]byte, error) {
+	bytes, ok := p.features[code]
+	if !ok {
+		return nil, errors.Wrapf(ErrNoFeatureReport, "code %#x", code)
+	}
+
+	return append([]byte(nil), bytes...), nil
+}
+
+// Writes returns the output reports written since the player was created.
+func (p *Player) Writes() [][]byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	writes := make([][]byte, len(p.writes))
+	for i, b := range p.writes {
+		writes[i] = append([]byte(nil), b...)
+	}
+
+	return writes
+}
+
+// SentFeatureReports returns the feature reports sent since the player was created.
+func (p *Player) SentFeatureReports() [][]byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	sent := make([][]byte, len(p.sent))
+	for i, b := range p.sent {
+		sent[i] = append([]byte(nil), b...)
+	}
+
+	return sent
+}
+
+func (p *Player) scale(d time.Duration) time.Duration {
+	if p.timing.Speed == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(d) / p.timing.Speed)
+}
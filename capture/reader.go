@@ -0,0 +1,110 @@
+package capture
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reader reads records of a capture.
+type Reader struct {
+	reader    *bufio.Reader
+	metadata  Metadata
+	transport Transport
+	now       time.Duration
+}
+
+// NewReader reads the capture header and metadata.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(magic)+1)
+
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidCapture, "header is truncated")
+	}
+
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.Wrap(ErrInvalidCapture, "bad magic")
+	}
+
+	if header[len(magic)] != Version {
+		return nil, errors.Wrapf(ErrUnsupportedVersion, "%d", header[len(magic)])
+	}
+
+	kind, _, payload, err := readRecord(reader)
+	if err != nil {
+		return nil, truncated(err)
+	}
+
+	if kind != KindMetadata {
+		return nil, errors.Wrapf(ErrInvalidCapture, "first record is %v, expected metadata", kind)
+	}
+
+	metadata, err := decodeMetadata(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{reader: reader, metadata: metadata}, nil
+}
+
+func (r *Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+// Transport returns the transport read so far, it is recorded once Connect has detected it.
+func (r *Reader) Transport() Transport {
+	return r.transport
+}
+
+// Next returns the next record or io.EOF at the end of the capture.
+func (r *Reader) Next() (Record, error) {
+	kind, delta, payload, err := readRecord(r.reader)
+	if err != nil {
+		return Record{}, err
+	}
+
+	r.now += delta
+	record := Record{Kind: kind, Time: r.now, Data: payload}
+
+	switch kind {
+	case KindTransport:
+		if len(payload) != 1 {
+			return Record{}, errors.Wrap(ErrInvalidCapture, "transport record must be 1 byte")
+		}
+
+		r.transport = Transport(payload[0])
+	case KindFeatureReport, KindSentFeatureReport:
+		if len(payload) == 0 {
+			return Record{}, errors.Wrap(ErrInvalidCapture, "feature report record has no code")
+		}
+
+		record.Code, record.Data = payload[0], payload[1:]
+	case KindMetadata:
+		return Record{}, errors.Wrap(ErrInvalidCapture, "duplicate metadata record")
+	}
+
+	return record, nil
+}
+
+// ReadAll reads the remaining records.
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,180 @@
+package capture
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// Recorder is a Device that records all traffic of the wrapped device.
+// The transport is recorded once the controller has detected it on Connect.
+type Recorder struct {
+	gods4.Device
+	mutex  sync.Mutex
+	writer *bufio.Writer
+	start  time.Time
+	last   time.Duration
+	err    error
+}
+
+// NewRecorder writes the capture header and the device metadata to w.
+// Write errors are returned by Close and Err, the device keeps working.
+func NewRecorder(device gods4.Device, w io.Writer) (*Recorder, error) {
+	r := &Recorder{
+		Device: device,
+		writer: bufio.NewWriter(w),
+		start:  time.Now(),
+	}
+
+	_, err := r.writer.WriteString(magic)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.writer.WriteByte(Version)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := Metadata{
+		StartedAt:    r.start,
+		VendorID:     device.VendorID(),
+		ProductID:    device.ProductID(),
+		Release:      device.Release(),
+		Path:         device.Path(),
+		Serial:       device.Serial(),
+		Manufacturer: device.Manufacturer(),
+		Product:      device.Product(),
+	}
+
+	err = writeRecord(r.writer, KindMetadata, 0, metadata.encode())
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Recorder) Read(b []byte) (int, error) {
+	n, err := r.Device.Read(b)
+	if err != nil {
+		r.record(KindReadError, []byte(err.Error()))
+
+		return n, err
+	}
+
+	r.record(KindInput, b[:n])
+
+	return n, nil
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.Device.Write(b)
+	if err == nil {
+		r.record(KindOutput, b[:n])
+	}
+
+	return n, err
+}
+
+func (r *Recorder) GetFeatureReport(code byte) ([]byte, error) {
+	bytes, err := r.Device.GetFeatureReport(code)
+	if err == nil {
+		r.record(KindFeatureReport, append([]byte{code}, bytes...))
+	}
+
+	return bytes, err
+}
+
+// SendFeatureReport sends a feature report if the wrapped device can.
+func (r *Recorder) SendFeatureReport(b []byte) (int, error) {
+	device, ok := r.Device.(interface{ SendFeatureReport(b []byte) (int, error) })
+	if !ok {
+		return 0, errors.Wrap(gods4.ErrIsNotSupported, "sending feature reports")
+	}
+
+	n, err := device.SendFeatureReport(b)
+	if err == nil && len(b) > 0 {
+		r.record(KindSentFeatureReport, b)
+	}
+
+	return n, err
+}
+
+// Close closes the device and flushes the capture.
+func (r *Recorder) Close() error {
+	err := r.Device.Close()
+
+	flushErr := r.Flush()
+	if err == nil {
+		err = flushErr
+	}
+
+	return err
+}
+
+func (r *Recorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	r.err = r.writer.Flush()
+
+	return r.err
+}
+
+// Err returns the first error that occurred while writing the capture.
+func (r *Recorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.err
+}
+
+// IsBluetooth returns the transport of the wrapped device if it knows it.
+func (r *Recorder) IsBluetooth() (bool, bool) {
+	device, ok := r.Device.(interface{ IsBluetooth() (bool, bool) })
+	if !ok {
+		return false, false
+	}
+
+	return device.IsBluetooth()
+}
+
+// SetConnectionType records the transport, Connect calls it with the detected connection type.
+func (r *Recorder) SetConnectionType(connectionType gods4.ConnectionType) {
+	transport := TransportUnknown
+
+	switch connectionType {
+	case gods4.ConnectionTypeUSB:
+		transport = TransportUSB
+	case gods4.ConnectionTypeBluetooth:
+		transport = TransportBluetooth
+	}
+
+	r.record(KindTransport, []byte{byte(transport)})
+}
+
+func (r *Recorder) record(kind Kind, payload []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	// time.Since uses the monotonic clock
+	now := time.Since(r.start)
+	delta := now - r.last
+	r.last = now
+
+	r.err = writeRecord(r.writer, kind, delta, payload)
+}
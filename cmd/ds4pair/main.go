@@ -0,0 +1,111 @@
+// Command ds4pair pairs DualShock 4 controllers connected over USB to a Bluetooth host.
+//
+// Without -host it prints the host every controller is paired to. With -host it sets the host
+// and prints the link key, generated per controller unless -key is given. The host must store
+// the link key for the controller address before the controller connects over Bluetooth.
+//
+//	ds4pair -host aa:bb:cc:dd:ee:ff [-key 00112233445566778899aabbccddeeff] [-serial 11:22:33:44:55:66]
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kpeu3i/gods4"
+)
+
+func main() {
+	host := flag.String("host", "", "Bluetooth address of the host to pair with")
+	key := flag.String("key", "", "link key as 32 hex digits, random per controller if empty")
+	serial := flag.String("serial", "", "only pair the controller with this address")
+	flag.Parse()
+
+	var linkKey []byte
+	if *key != "" {
+		var err error
+
+		linkKey, err = hex.DecodeString(*key)
+		if err != nil {
+			log.Fatalf("invalid link key: %s", err)
+		}
+	}
+
+	controllers := gods4.Find()
+	if len(controllers) == 0 {
+		log.Fatal("no controllers found")
+	}
+
+	var isFailed, isFound bool
+
+	for _, controller := range controllers {
+		err := controller.Connect()
+		if err != nil {
+			log.Printf("%s: %s", controller, err)
+			isFailed = true
+
+			continue
+		}
+
+		if controller.ConnectionType() != gods4.ConnectionTypeUSB || (*serial != "" && !strings.EqualFold(controller.Serial(), *serial)) {
+			_ = controller.Disconnect()
+
+			continue
+		}
+
+		isFound = true
+
+		err = pair(controller, *host, linkKey)
+		if err != nil {
+			log.Printf("%s: %s", controller.Serial(), err)
+			isFailed = true
+		}
+
+		_ = controller.Disconnect()
+	}
+
+	if !isFound {
+		log.Fatal("no matching controllers connected over USB")
+	}
+
+	if isFailed {
+		os.Exit(1)
+	}
+}
+
+func pair(controller *gods4.Controller, host string, linkKey []byte) error {
+	if host != "" {
+		if linkKey == nil {
+			linkKey = make([]byte, 16)
+
+			_, err := rand.Read(linkKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := controller.SetPairedHost(host, linkKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	pairedHost, err := controller.PairedHost()
+	if err != nil {
+		return err
+	}
+
+	if host == "" {
+		fmt.Printf("%s host: %s\n", controller.Serial(), pairedHost)
+
+		return nil
+	}
+
+	fmt.Printf("%s host: %s link key: %s\n", controller.Serial(), pairedHost, hex.EncodeToString(linkKey))
+
+	return nil
+}
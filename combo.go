@@ -0,0 +1,194 @@
+package gods4
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidCombo = errors.New("ds4: invalid combo")
+
+type chord struct {
+	event    Event
+	buttons  []Button
+	isActive bool
+}
+
+type sequence struct {
+	event    Event
+	window   time.Duration
+	steps    []Event
+	partials []partialMatch
+}
+
+type partialMatch struct {
+	startedAt time.Duration
+	progress  int
+}
+
+// combos detects chords and sequences from the events passing through the emitter.
+type combos struct {
+	mutex     sync.Mutex
+	held      map[Button]bool
+	chords    []*chord
+	sequences []*sequence
+}
+
+func newCombos() *combos {
+	return &combos{held: make(map[Button]bool)}
+}
+
+func (c *combos) addChord(event Event, buttons []Button) error {
+	if len(buttons) < 2 {
+		return errors.Wrapf(ErrInvalidCombo, "chord %q requires at least 2 buttons", event)
+	}
+
+	for _, button := range buttons {
+		if button.String() == "" {
+			return errors.Wrapf(ErrInvalidCombo, "chord %q has unknown button: %d", event, button)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.remove(event)
+	c.chords = append(c.chords, &chord{event: event, buttons: append([]Button(nil), buttons...)})
+
+	return nil
+}
+
+func (c *combos) addSequence(event Event, window time.Duration, steps []Event) error {
+	if len(steps) < 2 {
+		return errors.Wrapf(ErrInvalidCombo, "sequence %q requires at least 2 steps", event)
+	}
+
+	if window <= 0 {
+		return errors.Wrapf(ErrInvalidCombo, "sequence %q requires a positive window", event)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.remove(event)
+	c.sequences = append(c.sequences, &sequence{event: event, window: window, steps: append([]Event(nil), steps...)})
+
+	return nil
+}
+
+func (c *combos) removeCombo(event Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.remove(event)
+}
+
+func (c *combos) remove(event Event) {
+	chords := c.chords[:0]
+	for _, chord := range c.chords {
+		if chord.event != event {
+			chords = append(chords, chord)
+		}
+	}
+	c.chords = chords
+
+	sequences := c.sequences[:0]
+	for _, sequence := range c.sequences {
+		if sequence.event != event {
+			sequences = append(sequences, sequence)
+		}
+	}
+	c.sequences = sequences
+}
+
+// observe consumes an emitted event and returns the combo events it completes.
+func (c *combos) observe(event Event, now time.Duration) []Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var matched []Event
+
+	isPress := strings.HasSuffix(string(event), ".press")
+
+	for _, button := range buttons {
+		switch event {
+		case button.event("press"):
+			c.held[button] = true
+		case button.event("release"):
+			c.held[button] = false
+		}
+	}
+
+	for _, chord := range c.chords {
+		isHeld := true
+		for _, button := range chord.buttons {
+			if !c.held[button] {
+				isHeld = false
+				break
+			}
+		}
+
+		if isHeld && !chord.isActive {
+			matched = append(matched, chord.event)
+		}
+
+		chord.isActive = isHeld
+	}
+
+	for _, sequence := range c.sequences {
+		if sequence.observe(event, isPress, now) {
+			matched = append(matched, sequence.event)
+		}
+	}
+
+	return matched
+}
+
+// observe advances all partial matches expecting event and drops the ones
+// interrupted by another press or expired. Every occurrence of the first step
+// starts a new partial match, so overlapping attempts are tracked independently.
+func (s *sequence) observe(event Event, isPress bool, now time.Duration) bool {
+	if !isPress && !s.contains(event) {
+		return false
+	}
+
+	partials := s.partials[:0]
+	for _, partial := range s.partials {
+		if now-partial.startedAt > s.window {
+			continue
+		}
+
+		if s.steps[partial.progress] == event {
+			partial.progress++
+			if partial.progress == len(s.steps) {
+				s.partials = s.partials[:0]
+
+				return true
+			}
+
+			partials = append(partials, partial)
+		} else if !isPress {
+			partials = append(partials, partial)
+		}
+	}
+
+	if s.steps[0] == event {
+		partials = append(partials, partialMatch{startedAt: now, progress: 1})
+	}
+
+	s.partials = partials
+
+	return false
+}
+
+func (s *sequence) contains(event Event) bool {
+	for _, step := range s.steps {
+		if step == event {
+			return true
+		}
+	}
+
+	return false
+}
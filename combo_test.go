@@ -0,0 +1,251 @@
+package gods4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	eventSuper    Event = "combo.super"
+	eventReset    Event = "combo.reset"
+	eventHadouken Event = "combo.hadouken"
+	eventDouble   Event = "combo.double"
+)
+
+// input is an event observed by combos at a time in milliseconds.
+type input struct {
+	event Event
+	at    time.Duration
+}
+
+func observeAll(c *combos, inputs []input) []Event {
+	var matched []Event
+	for _, in := range inputs {
+		matched = append(matched, c.observe(in.event, in.at*time.Millisecond)...)
+	}
+
+	return matched
+}
+
+func TestChords(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []input
+		want   []Event
+	}{
+		{
+			"in order",
+			[]input{{EventL1Press, 0}, {EventR1Press, 10}},
+			[]Event{eventSuper},
+		},
+		{
+			"in any order",
+			[]input{{EventR1Press, 0}, {EventL1Press, 500}},
+			[]Event{eventSuper},
+		},
+		{
+			"once while held",
+			[]input{{EventL1Press, 0}, {EventR1Press, 10}, {EventCrossPress, 20}, {EventCrossRelease, 30}},
+			[]Event{eventSuper},
+		},
+		{
+			"again after a member is released",
+			[]input{{EventL1Press, 0}, {EventR1Press, 10}, {EventR1Release, 20}, {EventR1Press, 30}},
+			[]Event{eventSuper, eventSuper},
+		},
+		{
+			"not after a member is released",
+			[]input{{EventL1Press, 0}, {EventL1Release, 10}, {EventR1Press, 20}},
+			nil,
+		},
+		{
+			// Both chords share L1 and R1, the larger one completes later
+			"overlapping",
+			[]input{{EventL1Press, 0}, {EventR1Press, 10}, {EventOptionsPress, 20}},
+			[]Event{eventSuper, eventReset},
+		},
+		{
+			"overlapping at once",
+			[]input{{EventOptionsPress, 0}, {EventL1Press, 10}, {EventR1Press, 20}},
+			[]Event{eventSuper, eventReset},
+		},
+		{
+			"overlapping, shared member released",
+			[]input{{EventL1Press, 0}, {EventR1Press, 10}, {EventOptionsPress, 20}, {EventL1Release, 30}, {EventL1Press, 40}},
+			[]Event{eventSuper, eventReset, eventSuper, eventReset},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCombos()
+
+			err := c.addChord(eventSuper, []Button{ButtonL1, ButtonR1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.addChord(eventReset, []Button{ButtonL1, ButtonR1, ButtonOptions})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if matched := observeAll(c, tt.inputs); !equalEvents(matched, tt.want) {
+				t.Errorf("got %v, want %v", matched, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequences(t *testing.T) {
+	// A quarter circle rolls from down to right, the diagonal in between is both pressed
+	down, right, square := EventDPadDownPress, EventDPadRightPress, EventSquarePress
+
+	tests := []struct {
+		name   string
+		inputs []input
+		want   []Event
+	}{
+		{
+			"match",
+			[]input{{down, 0}, {right, 50}, {square, 100}},
+			[]Event{eventHadouken},
+		},
+		{
+			"releases in between",
+			[]input{{down, 0}, {right, 50}, {EventDPadDownRelease, 60}, {EventDPadRightRelease, 90}, {square, 100}},
+			[]Event{eventHadouken},
+		},
+		{
+			"at the end of the window",
+			[]input{{down, 0}, {right, 150}, {square, 300}},
+			[]Event{eventHadouken},
+		},
+		{
+			"timeout",
+			[]input{{down, 0}, {right, 150}, {square, 301}},
+			nil,
+		},
+		{
+			"new attempt after a timeout",
+			[]input{{down, 0}, {right, 100}, {down, 320}, {right, 350}, {square, 400}},
+			[]Event{eventHadouken},
+		},
+		{
+			"canceled by another press",
+			[]input{{down, 0}, {right, 50}, {EventCrossPress, 60}, {square, 100}},
+			nil,
+		},
+		{
+			"not canceled by other events",
+			[]input{{down, 0}, {right, 50}, {EventCrossRelease, 60}, {EventL2Move, 70}, {EventCrossHold, 80}, {square, 100}},
+			[]Event{eventHadouken},
+		},
+		{
+			// The first step again restarts the match instead of canceling it
+			"partial match restart",
+			[]input{{down, 0}, {down, 10}, {right, 50}, {square, 100}},
+			[]Event{eventHadouken},
+		},
+		{
+			"restart after a partial match",
+			[]input{{down, 0}, {right, 10}, {down, 20}, {right, 50}, {square, 100}},
+			[]Event{eventHadouken},
+		},
+		{
+			"out of order",
+			[]input{{right, 0}, {down, 50}, {square, 100}},
+			nil,
+		},
+		{
+			"incomplete",
+			[]input{{down, 0}, {right, 50}},
+			nil,
+		},
+		{
+			// A repeated step is matched by consecutive presses only, the match consumes them
+			"repeated steps",
+			[]input{{EventCrossPress, 0}, {EventCrossPress, 10}, {EventCrossPress, 20}, {EventCrossPress, 30}},
+			[]Event{eventDouble, eventDouble},
+		},
+		{
+			"repeated steps, odd",
+			[]input{{EventCrossPress, 0}, {EventCrossPress, 10}, {EventCrossPress, 20}},
+			[]Event{eventDouble},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCombos()
+
+			err := c.addSequence(eventHadouken, 300*time.Millisecond, []Event{down, right, square})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.addSequence(eventDouble, 100*time.Millisecond, []Event{EventCrossPress, EventCrossPress})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if matched := observeAll(c, tt.inputs); !equalEvents(matched, tt.want) {
+				t.Errorf("got %v, want %v", matched, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveCombo(t *testing.T) {
+	c := newCombos()
+
+	err := c.addSequence(eventDouble, time.Second, []Event{EventCrossPress, EventCrossPress})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.observe(EventCrossPress, 0)
+	c.removeCombo(eventDouble)
+
+	if matched := c.observe(EventCrossPress, time.Millisecond); len(matched) != 0 {
+		t.Errorf("removed sequence matched: %v", matched)
+	}
+}
+
+func TestInvalidCombos(t *testing.T) {
+	c := newCombos()
+
+	for name, err := range map[string]error{
+		"one button": c.addChord(eventSuper, []Button{ButtonL1}),
+		"one step":   c.addSequence(eventDouble, time.Second, []Event{EventCrossPress}),
+		"no window":  c.addSequence(eventDouble, 0, []Event{EventCrossPress, EventCrossPress}),
+	} {
+		if errors.Cause(err) != ErrInvalidCombo {
+			t.Errorf("%s: got error %v, want %v", name, err, ErrInvalidCombo)
+		}
+	}
+}
+
+func TestCombosThroughEmitter(t *testing.T) {
+	e := newEmitter()
+
+	err := e.combos.addChord(eventSuper, []Button{ButtonL1, ButtonR1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := &eventLog{}
+	log.listen(e, EventL1Press, EventR1Press, eventSuper)
+
+	// Both are pressed in one report
+	err = e.emit(&state{timestamp: time.Millisecond, l1: true, r1: true}, &state{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Event{EventL1Press, EventR1Press, eventSuper}; !equalEvents(log.events, want) {
+		t.Errorf("got %v, want %v", log.events, want)
+	}
+}
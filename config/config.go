@@ -0,0 +1,188 @@
+// Package config loads controller profiles from YAML, JSON or TOML files.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+var ErrUnknownFormat = errors.New("config: unknown format")
+
+// Error is a problem found at a line of a config file.
+type Error struct {
+	File    string
+	Line    int
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+	}
+
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+
+	b.WriteString(e.Message)
+
+	return b.String()
+}
+
+// Errors holds every problem found in a config file.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return "config: invalid config:\n\t" + strings.Join(messages, "\n\t")
+}
+
+// Config is a set of profiles. Bindings activate a profile when PS is held
+// and the bound button is pressed.
+type Config struct {
+	DefaultProfile string
+	Profiles       []gods4.Profile
+	Bindings       map[gods4.Button]string
+}
+
+// Load reads a config file, the format is detected by the file extension.
+func Load(path string) (*Config, error) {
+	var format Format
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".json":
+		format = FormatJSON
+	case ".toml":
+		format = FormatTOML
+	default:
+		return nil, errors.Wrapf(ErrUnknownFormat, "%s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := Parse(data, format)
+	if err != nil {
+		setFile(err, path)
+
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func Parse(data []byte, format Format) (*Config, error) {
+	var (
+		root *node
+		err  error
+	)
+
+	switch format {
+	case FormatYAML:
+		root, err = parseYAML(data)
+	case FormatJSON:
+		root, err = parseJSON(data)
+	case FormatTOML:
+		root, err = parseTOML(data)
+	default:
+		return nil, errors.Wrapf(ErrUnknownFormat, "%q", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{}
+	config := d.config(root)
+	if len(d.errors) > 0 {
+		return nil, d.errors
+	}
+
+	return config, nil
+}
+
+// Apply adds the profiles and bindings to the controller. The active profile
+// is activated again so its changes take effect, if there is no active profile
+// the default one is activated.
+func (c *Config) Apply(controller *gods4.Controller) error {
+	for _, profile := range c.Profiles {
+		err := controller.AddProfile(profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	for button, name := range c.Bindings {
+		err := controller.BindProfile(button, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	active := controller.Profile()
+	if active != "" && c.profile(active) {
+		return controller.SetProfile(active)
+	}
+
+	if active == "" && c.DefaultProfile != "" {
+		return controller.SetProfile(c.DefaultProfile)
+	}
+
+	return nil
+}
+
+func (c *Config) profile(name string) bool {
+	for _, profile := range c.Profiles {
+		if profile.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setFile(err error, file string) {
+	switch err := err.(type) {
+	case *Error:
+		err.File = file
+	case Errors:
+		for _, e := range err {
+			e.File = file
+		}
+	}
+}
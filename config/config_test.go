@@ -0,0 +1,416 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// fakeDevice is a DualShock 4 over USB answering reads from a list of input reports.
+type fakeDevice struct {
+	inputs [][]byte
+}
+
+func (d *fakeDevice) VendorID() uint16     { return 0x054C }
+func (d *fakeDevice) ProductID() uint16    { return 0x09CC }
+func (d *fakeDevice) Path() string         { return "fake" }
+func (d *fakeDevice) Release() uint16      { return 0x0100 }
+func (d *fakeDevice) Serial() string       { return "" }
+func (d *fakeDevice) Manufacturer() string { return "Sony" }
+func (d *fakeDevice) Product() string      { return "Wireless Controller" }
+func (d *fakeDevice) Open() error          { return nil }
+func (d *fakeDevice) Close() error         { return nil }
+
+func (d *fakeDevice) Read(b []byte) (int, error) {
+	if len(d.inputs) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, d.inputs[0])
+	d.inputs = d.inputs[1:]
+
+	return n, nil
+}
+
+func (d *fakeDevice) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (d *fakeDevice) GetFeatureReport(code byte) ([]byte, error) {
+	return []byte{code}, nil
+}
+
+// usbInput returns a USB report with centered sticks and the PS button and face buttons set.
+func usbInput(ps bool, faceButtons byte) []byte {
+	report := make([]byte, 64)
+	report[0] = 0x01
+	report[1], report[2], report[3], report[4] = 128, 128, 128, 128
+	report[5] = 0x08 | faceButtons
+
+	if ps {
+		report[7] = 0x01
+	}
+
+	return report
+}
+
+const yamlConfig = `version: 1
+default_profile: fps
+profiles:
+  fps:
+    bind: triangle
+    rumble_scale: 0.5
+    r2: {press_threshold: 40, release_threshold: 25}
+    remap:
+      - {type: swap_buttons, a: l1, b: r1}
+      - swap_sticks
+    macros:
+      l3:
+        - press: cross
+        - wait: 50ms
+        - release: cross
+`
+
+const jsonConfig = `{
+  "version": 1,
+  "default_profile": "fps",
+  "profiles": {
+    "fps": {
+      "bind": "triangle",
+      "rumble_scale": 0.5,
+      "r2": {"press_threshold": 40, "release_threshold": 25},
+      "remap": [{"type": "swap_buttons", "a": "l1", "b": "r1"}, "swap_sticks"],
+      "macros": {"l3": [{"press": "cross"}, {"wait": "50ms"}, {"release": "cross"}]}
+    }
+  }
+}
+`
+
+const tomlConfig = `version = 1
+default_profile = "fps"
+
+[profiles.fps]
+bind = "triangle"
+rumble_scale = 0.5
+r2 = {press_threshold = 40, release_threshold = 25}
+remap = [{type = "swap_buttons", a = "l1", b = "r1"}, "swap_sticks"]
+macros = {l3 = [{press = "cross"}, {wait = "50ms"}, {release = "cross"}]}
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{FormatYAML, yamlConfig},
+		{FormatJSON, jsonConfig},
+		{FormatTOML, tomlConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			config, err := Parse([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if config.DefaultProfile != "fps" {
+				t.Errorf("default profile = %q, want fps", config.DefaultProfile)
+			}
+
+			if config.Bindings[gods4.ButtonTriangle] != "fps" {
+				t.Errorf("bindings = %v, want triangle bound to fps", config.Bindings)
+			}
+
+			if len(config.Profiles) != 1 {
+				t.Fatalf("got %d profiles, want 1", len(config.Profiles))
+			}
+
+			profile := config.Profiles[0]
+			if profile.Name != "fps" || len(profile.Rules) != 2 {
+				t.Errorf("profile %q has %d rules, want fps with 2", profile.Name, len(profile.Rules))
+			}
+
+			if profile.RumbleScale == nil || *profile.RumbleScale != 0.5 {
+				t.Errorf("rumble scale = %v, want 0.5", profile.RumbleScale)
+			}
+
+			if profile.R2 == nil || profile.R2.PressThreshold != 40 || profile.R2.ReleaseThreshold != 25 {
+				t.Errorf("r2 = %+v, want thresholds 40 and 25", profile.R2)
+			}
+
+			macro, ok := profile.Macros[gods4.ButtonL3]
+			if !ok || len(macro.Steps) != 2 || macro.Steps[1].Delay != 50*time.Millisecond {
+				t.Errorf("l3 macro = %+v, want release 50ms after press", macro)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+		want   []Error
+	}{
+		{
+			format: FormatYAML,
+			data: `version: 1
+profiles:
+  fps:
+    r2: {press_threshold: 300}
+    remap:
+      - {type: flip}
+`,
+			want: []Error{
+				{Line: 4, Path: "profiles.fps.r2.press_threshold"},
+				{Line: 6, Path: "profiles.fps.remap[0]"},
+			},
+		},
+		{
+			format: FormatYAML,
+			data: `version: 1
+profiles:
+  fps:
+    left_stick:
+      filter: {type: one_euro, min_cutoff: 0, beta: -1, derivative_cutoff: 1}
+    right_stick:
+      filter: {type: threshold, delta: -0.1}
+  racing:
+    left_stick:
+      filter: {type: median, window: .inf}
+`,
+			want: []Error{
+				{Line: 5, Path: "profiles.fps.left_stick.filter.min_cutoff"},
+				{Line: 5, Path: "profiles.fps.left_stick.filter.beta"},
+				{Line: 7, Path: "profiles.fps.right_stick.filter.delta"},
+				{Line: 10, Path: "profiles.racing.left_stick.filter.window"},
+			},
+		},
+		{
+			format: FormatJSON,
+			data: `{
+  "version": 2,
+  "default_profile": "missing"
+}
+`,
+			want: []Error{
+				{Line: 2, Path: "version"},
+				{Line: 3, Path: "default_profile"},
+			},
+		},
+		{
+			format: FormatTOML,
+			data: `version = 1
+
+[profiles.fps]
+bind = "ps"
+`,
+			want: []Error{
+				{Line: 4, Path: "profiles.fps.bind"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format)
+
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("got error %v, want Errors", err)
+			}
+
+			if len(errs) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs), len(tt.want), errs)
+			}
+
+			for i, want := range tt.want {
+				if errs[i].Line != want.Line || errs[i].Path != want.Path {
+					t.Errorf("error #%d = %d:%s, want %d:%s", i, errs[i].Line, errs[i].Path, want.Line, want.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSetsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controls.yaml")
+
+	err := os.WriteFile(path, []byte("version: 1\nunknown: true\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(path)
+
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("got error %v, want one error", err)
+	}
+
+	if errs[0].File != path || errs[0].Line != 2 {
+		t.Errorf("error at %s:%d, want %s:2", errs[0].File, errs[0].Line, path)
+	}
+}
+
+func TestWatcherRemovesDeletedProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controls.yaml")
+	controller := gods4.NewController(nil)
+
+	err := os.WriteFile(path, []byte("version: 1\nprofiles:\n  fps: {bind: triangle}\n  racing: {}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{path: path, controller: controller}
+
+	_, err = w.reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = controller.SetProfile("fps")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A different size is enough for the file to be reloaded
+	err = os.WriteFile(path, []byte("version: 1\nprofiles:\n  racing: {}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if controller.Profile() != "" {
+		t.Errorf("active profile = %q, want none", controller.Profile())
+	}
+
+	err = controller.SetProfile("fps")
+	if err == nil {
+		t.Error("deleted profile can still be activated")
+	}
+
+	err = controller.SetProfile("racing")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWatcherMovesBindings(t *testing.T) {
+	const (
+		square   = 0x10
+		triangle = 0x80
+	)
+
+	path := filepath.Join(t.TempDir(), "controls.yaml")
+
+	err := os.WriteFile(path, []byte("version: 1\nprofiles:\n  fps: {bind: triangle}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// PS + triangle, then PS + square
+	device := &fakeDevice{inputs: [][]byte{
+		usbInput(true, 0), usbInput(true, triangle), usbInput(false, 0),
+		usbInput(true, 0), usbInput(true, square), usbInput(false, 0),
+	}}
+	controller := gods4.NewController(device)
+
+	err = controller.ConfigureConnection(gods4.ConnectionConfig{Type: gods4.ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{path: path, controller: controller}
+
+	_, err = w.reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = os.WriteFile(path, []byte("version: 1\nprofiles:\n  fps: {bind: square}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var switches int
+
+	controller.On(gods4.EventProfileChange, func(interface{}) error {
+		switches++
+
+		return nil
+	})
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want io.EOF", err)
+	}
+
+	if switches != 1 || controller.Profile() != "fps" {
+		t.Errorf("got %d switches to %q, want one by the moved binding", switches, controller.Profile())
+	}
+}
+
+func TestWatcherReportsMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controls.yaml")
+	controller := gods4.NewController(nil)
+
+	config := []byte("version: 1\nprofiles:\n  fps: {}\n")
+
+	err := os.WriteFile(path, config, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{path: path, controller: controller}
+
+	_, err = w.reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isChanged, err := w.reload()
+	if !isChanged || !os.IsNotExist(err) {
+		t.Fatalf("got %v, %v, want the deleted file reported", isChanged, err)
+	}
+
+	isChanged, _ = w.reload()
+	if isChanged {
+		t.Error("the deleted file is reported again")
+	}
+
+	// The same file is loaded again once it is back
+	err = os.WriteFile(path, config, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isChanged, err = w.reload()
+	if !isChanged || err != nil {
+		t.Errorf("got %v, %v, want the file reloaded", isChanged, err)
+	}
+}
@@ -0,0 +1,348 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type nodeKind uint
+
+const (
+	nodeScalar nodeKind = iota
+	nodeMapping
+	nodeSequence
+)
+
+// node is a format independent document tree, so all formats share
+// the same schema validation and report the same errors.
+type node struct {
+	kind   nodeKind
+	line   int
+	value  interface{} // nil, bool, float64 or string
+	keys   []string
+	fields map[string]*node
+	items  []*node
+}
+
+func newMapping(line int) *node {
+	return &node{kind: nodeMapping, line: line, fields: make(map[string]*node)}
+}
+
+func (n *node) set(key string, value *node) {
+	if _, ok := n.fields[key]; !ok {
+		n.keys = append(n.keys, key)
+	}
+
+	n.fields[key] = value
+}
+
+func parseYAML(data []byte) (*node, error) {
+	var document yaml.Node
+
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, yamlError(err)
+	}
+
+	if len(document.Content) == 0 {
+		return newMapping(1), nil
+	}
+
+	return fromYAML(document.Content[0])
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): `)
+
+func yamlError(err error) *Error {
+	message := err.Error()
+
+	match := yamlLinePattern.FindStringSubmatch(message)
+	if match == nil {
+		return &Error{Message: strings.TrimPrefix(message, "yaml: ")}
+	}
+
+	line, _ := strconv.Atoi(match[1])
+
+	return &Error{Line: line, Message: message[len(match[0]):]}
+}
+
+func fromYAML(y *yaml.Node) (*node, error) {
+	switch y.Kind {
+	case yaml.AliasNode:
+		return fromYAML(y.Alias)
+	case yaml.MappingNode:
+		n := newMapping(y.Line)
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			if _, ok := n.fields[y.Content[i].Value]; ok {
+				return nil, &Error{Line: y.Content[i].Line, Message: "duplicate key " + strconv.Quote(y.Content[i].Value)}
+			}
+
+			value, err := fromYAML(y.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			n.set(y.Content[i].Value, value)
+		}
+
+		return n, nil
+	case yaml.SequenceNode:
+		n := &node{kind: nodeSequence, line: y.Line}
+		for _, item := range y.Content {
+			value, err := fromYAML(item)
+			if err != nil {
+				return nil, err
+			}
+
+			n.items = append(n.items, value)
+		}
+
+		return n, nil
+	default:
+		n := &node{kind: nodeScalar, line: y.Line}
+
+		switch y.ShortTag() {
+		case "!!null":
+		case "!!bool", "!!int", "!!float":
+			var value interface{}
+
+			err := y.Decode(&value)
+			if err != nil {
+				return nil, &Error{Line: y.Line, Message: err.Error()}
+			}
+
+			n.value = normalize(value)
+		default:
+			n.value = y.Value
+		}
+
+		return n, nil
+	}
+}
+
+func parseJSON(data []byte) (*node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	syntaxError := func(err error) error {
+		line := lineAt(decoder.InputOffset())
+		if e, ok := err.(*json.SyntaxError); ok {
+			line = lineAt(e.Offset)
+		}
+
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return &Error{Line: line, Message: err.Error()}
+	}
+
+	var value func() (*node, error)
+	value = func() (*node, error) {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+
+		line := lineAt(decoder.InputOffset())
+
+		switch token := token.(type) {
+		case json.Delim:
+			if token == '[' {
+				n := &node{kind: nodeSequence, line: line}
+				for decoder.More() {
+					item, err := value()
+					if err != nil {
+						return nil, err
+					}
+
+					n.items = append(n.items, item)
+				}
+
+				_, err = decoder.Token()
+				if err != nil {
+					return nil, syntaxError(err)
+				}
+
+				return n, nil
+			}
+
+			n := newMapping(line)
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, syntaxError(err)
+				}
+
+				if _, ok := n.fields[key.(string)]; ok {
+					return nil, &Error{Line: lineAt(decoder.InputOffset()), Message: "duplicate key " + strconv.Quote(key.(string))}
+				}
+
+				field, err := value()
+				if err != nil {
+					return nil, err
+				}
+
+				n.set(key.(string), field)
+			}
+
+			_, err = decoder.Token()
+			if err != nil {
+				return nil, syntaxError(err)
+			}
+
+			return n, nil
+		case json.Number:
+			number, err := token.Float64()
+			if err != nil {
+				return nil, &Error{Line: line, Message: err.Error()}
+			}
+
+			return &node{kind: nodeScalar, line: line, value: number}, nil
+		default:
+			return &node{kind: nodeScalar, line: line, value: token}, nil
+		}
+	}
+
+	root, err := value()
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, &Error{Line: lineAt(decoder.InputOffset()), Message: "unexpected data after the top-level value"}
+	}
+
+	return root, nil
+}
+
+// BurntSushi/toml does not expose key positions, so lines are
+// looked up in the source by the dotted path of the key.
+func parseTOML(data []byte) (*node, error) {
+	var document map[string]interface{}
+
+	_, err := toml.Decode(string(data), &document)
+	if err != nil {
+		if e, ok := err.(toml.ParseError); ok {
+			return nil, &Error{Line: e.Position.Line, Message: e.Message}
+		}
+
+		return nil, errors.WithStack(err)
+	}
+
+	lines := tomlKeyLines(data)
+
+	return fromTOML(document, nil, lines), nil
+}
+
+func fromTOML(value interface{}, path []string, lines map[string]int) *node {
+	line := lines[strings.Join(path, ".")]
+	if line == 0 {
+		line = 1
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		n := newMapping(line)
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			n.set(key, fromTOML(value[key], append(path[:len(path):len(path)], key), lines))
+		}
+
+		return n
+	case []map[string]interface{}:
+		n := &node{kind: nodeSequence, line: line}
+		for _, item := range value {
+			n.items = append(n.items, fromTOML(item, path, lines))
+		}
+
+		return n
+	case []interface{}:
+		n := &node{kind: nodeSequence, line: line}
+		for _, item := range value {
+			n.items = append(n.items, fromTOML(item, path, lines))
+		}
+
+		return n
+	default:
+		return &node{kind: nodeScalar, line: line, value: normalize(value)}
+	}
+}
+
+var (
+	tomlTablePattern = regexp.MustCompile(`^\s*\[\[?\s*([^\]]+?)\s*\]\]?`)
+	tomlKeyPattern   = regexp.MustCompile(`^\s*([A-Za-z0-9_\-."' ]+?)\s*=`)
+)
+
+// tomlKeyLines maps dotted key paths to the line they are first defined on.
+func tomlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	define := func(path string, line int) {
+		if _, ok := lines[path]; !ok {
+			lines[path] = line
+		}
+	}
+
+	var table string
+
+	for i, text := range strings.Split(string(data), "\n") {
+		if match := tomlTablePattern.FindStringSubmatch(text); match != nil {
+			table = tomlPath(match[1])
+			define(table, i+1)
+
+			continue
+		}
+
+		if match := tomlKeyPattern.FindStringSubmatch(text); match != nil {
+			path := tomlPath(match[1])
+			if table != "" {
+				path = table + "." + path
+			}
+
+			define(path, i+1)
+		}
+	}
+
+	return lines
+}
+
+func tomlPath(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	default:
+		return value
+	}
+}
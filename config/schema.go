@@ -0,0 +1,797 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kpeu3i/gods4"
+	"github.com/kpeu3i/gods4/curve"
+	"github.com/kpeu3i/gods4/filter"
+	"github.com/kpeu3i/gods4/led"
+)
+
+const version = 1
+
+// decoder validates a document against the schema, collecting every error
+// instead of stopping at the first one.
+type decoder struct {
+	errors Errors
+}
+
+func (d *decoder) errorf(n *node, path string, format string, args ...interface{}) {
+	d.errors = append(d.errors, &Error{Line: n.line, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) config(root *node) *Config {
+	config := &Config{Bindings: make(map[gods4.Button]string)}
+
+	if !d.mapping(root, "", "version", "default_profile", "profiles") {
+		return config
+	}
+
+	if n, ok := root.fields["version"]; ok {
+		value, ok := d.number(n, "version")
+		if ok && value != version {
+			d.errorf(n, "version", "unsupported version %v, expected %d", value, version)
+		}
+	}
+
+	profiles, ok := root.fields["profiles"]
+	if ok && d.mapping(profiles, "profiles") {
+		bound := make(map[gods4.Button]string)
+
+		for _, name := range profiles.keys {
+			n := profiles.fields[name]
+			path := "profiles." + name
+
+			profile, bind, ok := d.profile(n, path, name)
+			if !ok {
+				continue
+			}
+
+			config.Profiles = append(config.Profiles, profile)
+
+			if bind == nil {
+				continue
+			}
+
+			button, ok := d.button(bind, path+".bind")
+			if !ok {
+				continue
+			}
+
+			if button == gods4.ButtonPS {
+				d.errorf(bind, path+".bind", "ps can't be bound, profiles are switched with ps and the bound button")
+
+				continue
+			}
+
+			if other, ok := bound[button]; ok {
+				d.errorf(bind, path+".bind", "button %s is already bound to profile %q", button, other)
+
+				continue
+			}
+
+			bound[button] = name
+			config.Bindings[button] = name
+		}
+	}
+
+	if n, ok := root.fields["default_profile"]; ok {
+		name, ok := d.str(n, "default_profile")
+		if ok && (profiles == nil || profiles.fields[name] == nil) {
+			d.errorf(n, "default_profile", "profile %q is not defined", name)
+		}
+
+		if ok {
+			config.DefaultProfile = name
+		}
+	}
+
+	return config
+}
+
+func (d *decoder) profile(n *node, path, name string) (gods4.Profile, *node, bool) {
+	profile := gods4.Profile{Name: name}
+	errors := len(d.errors)
+
+	if !d.mapping(n, path, "bind", "led", "rumble_scale", "left_stick", "right_stick", "l2", "r2", "remap", "macros") {
+		return profile, nil, false
+	}
+
+	if name == "" {
+		d.errorf(n, path, "profile name is required")
+	}
+
+	if field, ok := n.fields["led"]; ok {
+		profile.Led = d.led(field, path+".led")
+	}
+
+	if field, ok := n.fields["rumble_scale"]; ok {
+		value, ok := d.number(field, path+".rumble_scale")
+		if ok && value < 0 {
+			d.errorf(field, path+".rumble_scale", "must not be negative")
+		}
+
+		profile.RumbleScale = &value
+	}
+
+	if field, ok := n.fields["left_stick"]; ok {
+		profile.LeftStick = d.stick(field, path+".left_stick")
+	}
+
+	if field, ok := n.fields["right_stick"]; ok {
+		profile.RightStick = d.stick(field, path+".right_stick")
+	}
+
+	if field, ok := n.fields["l2"]; ok {
+		profile.L2 = d.trigger(field, path+".l2")
+	}
+
+	if field, ok := n.fields["r2"]; ok {
+		profile.R2 = d.trigger(field, path+".r2")
+	}
+
+	if field, ok := n.fields["remap"]; ok && d.sequence(field, path+".remap") {
+		for i, item := range field.items {
+			rule, ok := d.rule(item, fmt.Sprintf("%s.remap[%d]", path, i))
+			if ok {
+				profile.Rules = append(profile.Rules, rule)
+			}
+		}
+	}
+
+	if field, ok := n.fields["macros"]; ok && d.mapping(field, path+".macros") {
+		profile.Macros = make(map[gods4.Button]gods4.Macro)
+
+		for _, key := range field.keys {
+			button, ok := gods4.ParseButton(key)
+			if !ok {
+				d.errorf(field.fields[key], path+".macros", "unknown button %q", key)
+
+				continue
+			}
+
+			macro, ok := d.macro(field.fields[key], path+".macros."+key)
+			if ok {
+				profile.Macros[button] = macro
+			}
+		}
+	}
+
+	if len(d.errors) > errors {
+		return profile, nil, false
+	}
+
+	// Conflicting rules are only known once the whole profile is read
+	err := profile.Validate()
+	if err != nil {
+		d.errorf(n, path, "%s", err)
+
+		return profile, nil, false
+	}
+
+	return profile, n.fields["bind"], true
+}
+
+func (d *decoder) led(n *node, path string) *led.Led {
+	if n.kind == nodeScalar {
+		value, ok := d.str(n, path)
+		if !ok {
+			return nil
+		}
+
+		rgb, err := strconv.ParseUint(strings.TrimPrefix(value, "#"), 16, 32)
+		if !strings.HasPrefix(value, "#") || len(value) != 7 || err != nil {
+			d.errorf(n, path, "expected a color like \"#FF8000\" or a mapping, got %q", value)
+
+			return nil
+		}
+
+		return led.RGB(byte(rgb>>16), byte(rgb>>8), byte(rgb))
+	}
+
+	if !d.mapping(n, path, "red", "green", "blue", "flash_on", "flash_off") {
+		return nil
+	}
+
+	var values [5]byte
+	for i, key := range []string{"red", "green", "blue", "flash_on", "flash_off"} {
+		if field, ok := n.fields[key]; ok {
+			values[i], _ = d.byte(field, path+"."+key)
+		}
+	}
+
+	return led.RGB(values[0], values[1], values[2]).Flash(values[3], values[4])
+}
+
+func (d *decoder) stick(n *node, path string) *gods4.StickConfig {
+	config := gods4.DefaultStickConfig()
+	errors := len(d.errors)
+
+	if !d.mapping(n, path, "dead_zone_type", "inner_dead_zone", "outer_dead_zone", "anti_dead_zone", "curve", "filter",
+		"direction_threshold", "direction_hysteresis", "direction_angle_hysteresis") {
+		return nil
+	}
+
+	if field, ok := n.fields["dead_zone_type"]; ok {
+		name, ok := d.str(field, path+".dead_zone_type")
+		if ok {
+			config.DeadZoneType, ok = gods4.ParseDeadZoneType(name)
+			if !ok {
+				d.errorf(field, path+".dead_zone_type", "unknown dead zone type %q, expected axial, radial or scaled_radial", name)
+			}
+		}
+	}
+
+	floats := []struct {
+		key   string
+		value *float64
+	}{
+		{"inner_dead_zone", &config.InnerDeadZone},
+		{"outer_dead_zone", &config.OuterDeadZone},
+		{"anti_dead_zone", &config.AntiDeadZone},
+		{"direction_threshold", &config.DirectionThreshold},
+		{"direction_hysteresis", &config.DirectionHysteresis},
+		{"direction_angle_hysteresis", &config.DirectionAngleHysteresis},
+	}
+
+	for _, f := range floats {
+		if field, ok := n.fields[f.key]; ok {
+			*f.value, _ = d.number(field, path+"."+f.key)
+		}
+	}
+
+	if field, ok := n.fields["curve"]; ok {
+		config.Curve = d.curve(field, path+".curve")
+	}
+
+	if field, ok := n.fields["filter"]; ok {
+		config.Filter = d.filter(field, path+".filter")
+	}
+
+	if len(d.errors) > errors {
+		return nil
+	}
+
+	err := config.Validate()
+	if err != nil {
+		d.errorf(n, path, "%s", err)
+
+		return nil
+	}
+
+	return &config
+}
+
+func (d *decoder) trigger(n *node, path string) *gods4.TriggerConfig {
+	config := gods4.DefaultTriggerConfig()
+	errors := len(d.errors)
+
+	if !d.mapping(n, path, "press_threshold", "release_threshold", "full_pull_threshold", "curve") {
+		return nil
+	}
+
+	bytes := []struct {
+		key   string
+		value *byte
+	}{
+		{"press_threshold", &config.PressThreshold},
+		{"release_threshold", &config.ReleaseThreshold},
+		{"full_pull_threshold", &config.FullPullThreshold},
+	}
+
+	for _, f := range bytes {
+		if field, ok := n.fields[f.key]; ok {
+			*f.value, _ = d.byte(field, path+"."+f.key)
+		}
+	}
+
+	if field, ok := n.fields["curve"]; ok {
+		config.Curve = d.curve(field, path+".curve")
+	}
+
+	if len(d.errors) > errors {
+		return nil
+	}
+
+	err := config.Validate()
+	if err != nil {
+		d.errorf(n, path, "%s", err)
+
+		return nil
+	}
+
+	return &config
+}
+
+func (d *decoder) curve(n *node, path string) curve.Curve {
+	kind, ok := d.kind(n, path)
+	if !ok {
+		return nil
+	}
+
+	switch kind {
+	case "linear":
+		if d.mapping(n, path, "type") {
+			return curve.Linear()
+		}
+	case "exponential":
+		if !d.mapping(n, path, "type", "exponent") {
+			return nil
+		}
+
+		exponent, ok := d.required(n, path, "exponent")
+		if ok && exponent <= 0 {
+			d.errorf(n.fields["exponent"], path+".exponent", "must be positive")
+		}
+
+		return curve.Exponential(exponent)
+	case "lut":
+		if !d.mapping(n, path, "type", "points") {
+			return nil
+		}
+
+		field, ok := n.fields["points"]
+		if !ok {
+			d.errorf(n, path, "points are required")
+
+			return nil
+		}
+
+		if !d.sequence(field, path+".points") {
+			return nil
+		}
+
+		points := make([]float64, len(field.items))
+		for i, item := range field.items {
+			points[i], _ = d.number(item, fmt.Sprintf("%s.points[%d]", path, i))
+		}
+
+		lut, err := curve.LUT(points...)
+		if err != nil {
+			d.errorf(field, path+".points", "%s", strings.TrimPrefix(err.Error(), "curve: "))
+		}
+
+		return lut
+	default:
+		d.errorf(n, path, "unknown curve %q, expected linear, exponential or lut", kind)
+	}
+
+	return nil
+}
+
+func (d *decoder) filter(n *node, path string) filter.Filter {
+	kind, ok := d.kind(n, path)
+	if !ok {
+		return nil
+	}
+
+	switch kind {
+	case "none":
+		if d.mapping(n, path, "type") {
+			return filter.None()
+		}
+	case "threshold":
+		if d.mapping(n, path, "type", "delta") {
+			// Stick axes span 2
+			delta, ok := d.required(n, path, "delta")
+			if ok && !(delta >= 0 && delta <= 2) {
+				d.errorf(n.fields["delta"], path+".delta", "%v is out of range [0, 2]", delta)
+			}
+
+			return filter.Threshold(delta)
+		}
+	case "ema":
+		if d.mapping(n, path, "type", "alpha") {
+			alpha, ok := d.required(n, path, "alpha")
+			if ok && !(alpha > 0 && alpha <= 1) {
+				d.errorf(n.fields["alpha"], path+".alpha", "%v is out of range (0, 1]", alpha)
+			}
+
+			return filter.EMA(alpha)
+		}
+	case "median":
+		if d.mapping(n, path, "type", "window") {
+			window, ok := d.required(n, path, "window")
+			if ok && (!(window >= 1 && window <= math.MaxInt32) || window != math.Trunc(window)) {
+				d.errorf(n.fields["window"], path+".window", "must be a positive integer")
+			}
+
+			return filter.Median(int(window))
+		}
+	case "one_euro":
+		if d.mapping(n, path, "type", "min_cutoff", "beta", "derivative_cutoff") {
+			minCutoff, ok := d.required(n, path, "min_cutoff")
+			if ok && !(minCutoff > 0 && !math.IsInf(minCutoff, 1)) {
+				d.errorf(n.fields["min_cutoff"], path+".min_cutoff", "must be a positive frequency")
+			}
+
+			beta, ok := d.required(n, path, "beta")
+			if ok && !(beta >= 0 && !math.IsInf(beta, 1)) {
+				d.errorf(n.fields["beta"], path+".beta", "must not be negative")
+			}
+
+			derivativeCutoff, ok := d.required(n, path, "derivative_cutoff")
+			if ok && !(derivativeCutoff > 0 && !math.IsInf(derivativeCutoff, 1)) {
+				d.errorf(n.fields["derivative_cutoff"], path+".derivative_cutoff", "must be a positive frequency")
+			}
+
+			return filter.OneEuro(minCutoff, beta, derivativeCutoff)
+		}
+	default:
+		d.errorf(n, path, "unknown filter %q, expected none, threshold, ema, median or one_euro", kind)
+	}
+
+	return nil
+}
+
+func (d *decoder) rule(n *node, path string) (gods4.Rule, bool) {
+	kind, ok := d.kind(n, path)
+	if !ok {
+		return gods4.Rule{}, false
+	}
+
+	errors := len(d.errors)
+	button := func(key string) gods4.Button {
+		field, ok := n.fields[key]
+		if !ok {
+			d.errorf(n, path, "%s is required", key)
+
+			return 0
+		}
+
+		button, _ := d.button(field, path+"."+key)
+
+		return button
+	}
+	axis := func() gods4.Axis {
+		field, ok := n.fields["axis"]
+		if !ok {
+			d.errorf(n, path, "axis is required")
+
+			return 0
+		}
+
+		axis, _ := d.axis(field, path+".axis")
+
+		return axis
+	}
+
+	var rule gods4.Rule
+
+	switch kind {
+	case "swap_buttons":
+		if d.mapping(n, path, "type", "a", "b") {
+			rule = gods4.SwapButtons(button("a"), button("b"))
+		}
+	case "remap_button":
+		if d.mapping(n, path, "type", "from", "to") {
+			rule = gods4.RemapButton(button("from"), button("to"))
+		}
+	case "disable_button":
+		if d.mapping(n, path, "type", "button") {
+			rule = gods4.DisableButton(button("button"))
+		}
+	case "button_to_axis":
+		if d.mapping(n, path, "type", "button", "axis", "value") {
+			value, _ := d.required(n, path, "value")
+			rule = gods4.ButtonToAxis(button("button"), axis(), value)
+		}
+	case "axis_to_button":
+		if d.mapping(n, path, "type", "axis", "threshold", "button") {
+			threshold, _ := d.required(n, path, "threshold")
+			rule = gods4.AxisToButton(axis(), threshold, button("button"))
+		}
+	case "invert_axis":
+		if d.mapping(n, path, "type", "axis") {
+			rule = gods4.InvertAxis(axis())
+		}
+	case "disable_axis":
+		if d.mapping(n, path, "type", "axis") {
+			rule = gods4.DisableAxis(axis())
+		}
+	case "swap_sticks":
+		if d.mapping(n, path, "type") {
+			rule = gods4.SwapSticks()
+		}
+	default:
+		d.errorf(n, path, "unknown rule %q, expected swap_buttons, remap_button, disable_button, "+
+			"button_to_axis, axis_to_button, invert_axis, disable_axis or swap_sticks", kind)
+	}
+
+	return rule, len(d.errors) == errors
+}
+
+// macro reads a list of steps, or a mapping with steps, speed and loop.
+// Each step is one of press, release, event or wait, waits delay the next step.
+func (d *decoder) macro(n *node, path string) (gods4.Macro, bool) {
+	var macro gods4.Macro
+
+	errors := len(d.errors)
+
+	if n.kind == nodeMapping {
+		d.mapping(n, path, "steps", "speed", "loop")
+
+		if field, ok := n.fields["speed"]; ok {
+			speed, ok := d.number(field, path+".speed")
+			if ok && speed <= 0 {
+				d.errorf(field, path+".speed", "must be positive")
+			}
+
+			macro.Speed = speed
+		}
+
+		if field, ok := n.fields["loop"]; ok {
+			macro.Loop, _ = d.boolean(field, path+".loop")
+		}
+
+		steps, ok := n.fields["steps"]
+		if !ok {
+			d.errorf(n, path, "steps are required")
+
+			return macro, false
+		}
+
+		n, path = steps, path+".steps"
+	}
+
+	if !d.sequence(n, path) {
+		return macro, false
+	}
+
+	var delay time.Duration
+
+	for i, item := range n.items {
+		path := fmt.Sprintf("%s[%d]", path, i)
+		if !d.mapping(item, path, "press", "release", "event", "wait") {
+			continue
+		}
+
+		if len(item.keys) != 1 {
+			d.errorf(item, path, "expected exactly one of press, release, event or wait")
+
+			continue
+		}
+
+		key := item.keys[0]
+		field := item.fields[key]
+
+		switch key {
+		case "wait":
+			wait, ok := d.duration(field, path+".wait")
+			if ok {
+				delay += wait
+			}
+
+			continue
+		case "event":
+			event, ok := d.str(field, path+".event")
+			if ok && event == "" {
+				d.errorf(field, path+".event", "must not be empty")
+			}
+
+			macro.Steps = append(macro.Steps, gods4.MacroStep{Delay: delay, Event: gods4.Event(event)})
+		default:
+			button, _ := d.button(field, path+"."+key)
+
+			event := button.PressEvent()
+			if key == "release" {
+				event = button.ReleaseEvent()
+			}
+
+			macro.Steps = append(macro.Steps, gods4.MacroStep{Delay: delay, Event: event})
+		}
+
+		delay = 0
+	}
+
+	if len(d.errors) > errors {
+		return macro, false
+	}
+
+	if len(macro.Steps) == 0 {
+		d.errorf(n, path, "at least one press, release or event step is required")
+
+		return macro, false
+	}
+
+	if delay > 0 {
+		d.errorf(n.items[len(n.items)-1], path, "macro must not end with a wait")
+
+		return macro, false
+	}
+
+	return macro, true
+}
+
+// kind returns the type of a value given either as a name or as a mapping with a type key.
+func (d *decoder) kind(n *node, path string) (string, bool) {
+	if n.kind == nodeMapping {
+		field, ok := n.fields["type"]
+		if !ok {
+			d.errorf(n, path, "type is required")
+
+			return "", false
+		}
+
+		return d.str(field, path+".type")
+	}
+
+	return d.str(n, path)
+}
+
+func (d *decoder) mapping(n *node, path string, keys ...string) bool {
+	if n.kind == nodeScalar && len(keys) == 1 && keys[0] == "type" {
+		// A bare name like "linear" instead of {type: linear}
+		return true
+	}
+
+	if n.kind != nodeMapping {
+		d.errorf(n, path, "expected a mapping")
+
+		return false
+	}
+
+	if len(keys) == 0 {
+		return true
+	}
+
+	// Unknown keys are reported, but known ones are still validated
+	for _, key := range n.keys {
+		if !contains(keys, key) {
+			d.errorf(n.fields[key], join(path, key), "unknown key, expected one of: %s", strings.Join(keys, ", "))
+		}
+	}
+
+	return true
+}
+
+func (d *decoder) sequence(n *node, path string) bool {
+	if n.kind != nodeSequence {
+		d.errorf(n, path, "expected a list")
+
+		return false
+	}
+
+	return true
+}
+
+func (d *decoder) required(n *node, path, key string) (float64, bool) {
+	field, ok := n.fields[key]
+	if !ok {
+		d.errorf(n, path, "%s is required", key)
+
+		return 0, false
+	}
+
+	return d.number(field, path+"."+key)
+}
+
+func (d *decoder) number(n *node, path string) (float64, bool) {
+	value, ok := n.value.(float64)
+	if n.kind != nodeScalar || !ok {
+		d.errorf(n, path, "expected a number")
+
+		return 0, false
+	}
+
+	return value, true
+}
+
+func (d *decoder) byte(n *node, path string) (byte, bool) {
+	value, ok := d.number(n, path)
+	if !ok {
+		return 0, false
+	}
+
+	if value < 0 || value > 255 || value != math.Trunc(value) {
+		d.errorf(n, path, "%v is not an integer in range [0, 255]", value)
+
+		return 0, false
+	}
+
+	return byte(value), true
+}
+
+func (d *decoder) boolean(n *node, path string) (bool, bool) {
+	value, ok := n.value.(bool)
+	if n.kind != nodeScalar || !ok {
+		d.errorf(n, path, "expected true or false")
+
+		return false, false
+	}
+
+	return value, true
+}
+
+func (d *decoder) str(n *node, path string) (string, bool) {
+	value, ok := n.value.(string)
+	if n.kind != nodeScalar || !ok {
+		d.errorf(n, path, "expected a string")
+
+		return "", false
+	}
+
+	return value, true
+}
+
+// duration accepts Go durations like "150ms" or a number of milliseconds.
+func (d *decoder) duration(n *node, path string) (time.Duration, bool) {
+	var duration time.Duration
+
+	switch value := n.value.(type) {
+	case float64:
+		duration = time.Duration(value * float64(time.Millisecond))
+	case string:
+		var err error
+
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			d.errorf(n, path, "invalid duration %q", value)
+
+			return 0, false
+		}
+	default:
+		d.errorf(n, path, "expected a duration like \"150ms\" or a number of milliseconds")
+
+		return 0, false
+	}
+
+	if duration < 0 {
+		d.errorf(n, path, "must not be negative")
+
+		return 0, false
+	}
+
+	return duration, true
+}
+
+func (d *decoder) button(n *node, path string) (gods4.Button, bool) {
+	name, ok := d.str(n, path)
+	if !ok {
+		return 0, false
+	}
+
+	button, ok := gods4.ParseButton(name)
+	if !ok {
+		d.errorf(n, path, "unknown button %q", name)
+	}
+
+	return button, ok
+}
+
+func (d *decoder) axis(n *node, path string) (gods4.Axis, bool) {
+	name, ok := d.str(n, path)
+	if !ok {
+		return 0, false
+	}
+
+	axis, ok := gods4.ParseAxis(name)
+	if !ok {
+		d.errorf(n, path, "unknown axis %q, expected left_x, left_y, right_x, right_y, l2 or r2", name)
+	}
+
+	return axis, ok
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kpeu3i/gods4"
+)
+
+const DefaultWatchInterval = time.Second
+
+// Watcher reloads a config file onto a controller whenever the file changes.
+type Watcher struct {
+	path       string
+	controller *gods4.Controller
+	onError    func(err error)
+	modTime    time.Time
+	size       int64
+	statErr    string
+	profiles   []string
+	bindings   map[gods4.Button]string
+	quit       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Watch loads the config onto the controller and then polls the file every interval.
+// A config that fails to load is reported to onError (may be nil) and the controller
+// keeps the previous one, so does a file that can't be found, once until it is back.
+// Profiles and bindings deleted from the file are removed from the controller.
+func Watch(path string, controller *gods4.Controller, interval time.Duration, onError func(err error)) (*Watcher, error) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	w := &Watcher{
+		path:       path,
+		controller: controller,
+		onError:    onError,
+		quit:       make(chan struct{}),
+	}
+
+	_, err := w.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.watch(interval)
+
+	return w, nil
+}
+
+func (w *Watcher) Close() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+func (w *Watcher) watch(interval time.Duration) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-ticker.C:
+			isChanged, err := w.reload()
+			if err != nil && isChanged && w.onError != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// reload applies the config if the file has changed since the last reload and reports
+// whether it has. A file that can't be found changes once and is reloaded when it is back.
+func (w *Watcher) reload() (bool, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		isNew := err.Error() != w.statErr
+		w.statErr = err.Error()
+		w.modTime, w.size = time.Time{}, 0
+
+		return isNew, err
+	}
+
+	w.statErr = ""
+
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	config, err := Load(w.path)
+	if err != nil {
+		return true, err
+	}
+
+	for _, name := range w.profiles {
+		if !config.profile(name) {
+			w.controller.RemoveProfile(name)
+		}
+	}
+
+	w.profiles = w.profiles[:0]
+	for _, profile := range config.Profiles {
+		w.profiles = append(w.profiles, profile.Name)
+	}
+
+	// A binding moved to another button must not stay on the old one
+	for button := range w.bindings {
+		if _, ok := config.Bindings[button]; !ok {
+			err = w.controller.BindProfile(button, "")
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+
+	w.bindings = config.Bindings
+
+	return true, config.Apply(w.controller)
+}
@@ -1,5 +1,21 @@
 package gods4
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidConnectionConfig = errors.New("ds4: invalid connection config")
+
+// Input reports read at most while detecting the connection type
+const maxDetectionReports = 10
+
+// The DualShock 4 sends Bluetooth reports at most this many milliseconds apart
+const maxPollInterval = 62 * time.Millisecond
+
 type ConnectionType uint
 
 const (
@@ -21,20 +37,203 @@ func (t ConnectionType) String() string {
 	}
 }
 
-func detectConnectionType(device Device) (ConnectionType, error) {
-	_, _ = device.GetFeatureReport(getFeatureReportCode0x04)
+// connectionTypeSetter is implemented by devices that keep the connection type
+// found by Connect, such as capture.Recorder.
+type connectionTypeSetter interface {
+	SetConnectionType(connectionType ConnectionType)
+}
+
+// ReportMode is the kind of input reports a controller sends over Bluetooth.
+type ReportMode uint
+
+const (
+	// Full reports with IMU, touch and battery data, requested on connect
+	ReportModeExtended ReportMode = iota
+	// Reports with sticks, buttons and triggers only, which a controller sends
+	// after pairing until the extended reports are requested
+	ReportModeReduced
+)
+
+func (m ReportMode) String() string {
+	switch m {
+	case ReportModeExtended:
+		return "extended"
+	case ReportModeReduced:
+		return "reduced"
+	default:
+		return ""
+	}
+}
+
+// ConnectionConfig sets how Connect finds out the transport. A Type other than ConnectionTypeNone
+// skips detection, otherwise it is asked from the device or detected from the first input reports,
+// which are read until DetectionTimeout has passed. Connect then closes the device.
+//
+// ReportMode and PollInterval apply over Bluetooth only. PollInterval is the time between
+// input reports of a DualShock 4 in whole milliseconds up to 62, 0 sends them as fast as
+// the link allows. The DualSense ignores it.
+type ConnectionConfig struct {
+	Type             ConnectionType
+	DetectionTimeout time.Duration
+	ReportMode       ReportMode
+	PollInterval     time.Duration
+}
+
+func DefaultConnectionConfig() ConnectionConfig {
+	return ConnectionConfig{
+		Type:             ConnectionTypeNone,
+		DetectionTimeout: 2 * time.Second,
+		ReportMode:       ReportModeExtended,
+	}
+}
+
+func (c ConnectionConfig) Validate() error {
+	if c.Type > ConnectionTypeBluetooth {
+		return errors.Wrapf(ErrInvalidConnectionConfig, "unknown connection type: %d", c.Type)
+	}
+
+	if c.Type == ConnectionTypeNone && c.DetectionTimeout <= 0 {
+		return errors.Wrap(ErrInvalidConnectionConfig, "detection timeout must be positive")
+	}
+
+	if c.ReportMode > ReportModeReduced {
+		return errors.Wrapf(ErrInvalidConnectionConfig, "unknown report mode: %d", c.ReportMode)
+	}
+
+	if c.PollInterval < 0 || c.PollInterval > maxPollInterval || c.PollInterval%time.Millisecond != 0 {
+		return errors.Wrapf(ErrInvalidConnectionConfig, "poll interval (%s) must be whole milliseconds in [0, %s]", c.PollInterval, maxPollInterval)
+	}
+
+	return nil
+}
+
+// transportDevice is implemented by devices that know their transport without
+// reading input, such as hid.Device. Ok is false if the transport is unknown.
+type transportDevice interface {
+	IsBluetooth() (isBluetooth bool, ok bool)
+}
+
+// detectConnectionType asks the device for its transport and falls back to reading input
+// reports until one tells it. If none does within the timeout, the device is closed to end
+// the pending read, which must then return.
+func detectConnectionType(device Device, model Model, mode ReportMode, timeout time.Duration) (ConnectionType, error) {
+	if transport, ok := device.(transportDevice); ok {
+		isBluetooth, ok := transport.IsBluetooth()
+		if ok && isBluetooth {
+			return ConnectionTypeBluetooth, nil
+		}
 
-	bytes := make([]byte, 2)
-	for i := 1; i <= 100; i++ {
-		_, err := device.Read(bytes)
+		if ok {
+			return ConnectionTypeUSB, nil
+		}
+	}
+
+	if mode == ReportModeExtended {
+		_, _ = device.GetFeatureReport(model.bluetoothFeatureReport())
+	}
+
+	type detection struct {
+		connectionType ConnectionType
+		err            error
+	}
+
+	var (
+		done    = make(chan detection, 1)
+		timer   = time.NewTimer(timeout)
+		expired = make(chan struct{})
+	)
+	defer timer.Stop()
+
+	go func() {
+		connectionType, err := detectFromReports(device, expired)
+		done <- detection{connectionType, err}
+	}()
+
+	select {
+	case d := <-done:
+		return d.connectionType, d.err
+	case <-timer.C:
+	}
+
+	// The pending read is joined, so that it can't take a report meant for a later Connect
+	close(expired)
+	_ = device.Close()
+
+	d := <-done
+	if d.err == nil {
+		d.err = ErrInvalidConnectionType
+	}
+
+	return 0, errors.Wrapf(d.err, "timed out after %s", timeout)
+}
+
+// detectFromReports tells the transport by the report ID and size: full USB reports are 64 bytes
+// with ID 0x01, Bluetooth reports have ID 0x11 (0x31 on DualSense) or are reduced 0x01 reports.
+// It gives up after maxDetectionReports or once expired is closed.
+func detectFromReports(device Device, expired <-chan struct{}) (ConnectionType, error) {
+	var (
+		bytes    = make([]byte, bluetoothInputReportSize)
+		observed []string
+		counts   = make(map[string]int)
+	)
+
+	for i := 0; i < maxDetectionReports; i++ {
+		n, err := device.Read(bytes)
 		if err != nil {
-			return 0, ErrInvalidConnectionType
+			if isClosed(expired) {
+				break
+			}
+
+			return 0, errors.Wrapf(ErrInvalidConnectionType, "read: %v", err)
+		}
+
+		report := "empty"
+
+		switch {
+		case n == 0:
+			// Counted as observed below
+		case bytes[0] == bluetoothInputReportID, bytes[0] == dualSenseBluetoothInputReportID:
+			return ConnectionTypeBluetooth, nil
+		case bytes[0] == usbInputReportID && n >= usbInputReportSize:
+			return ConnectionTypeUSB, nil
+		case bytes[0] == usbInputReportID:
+			return ConnectionTypeBluetooth, nil
+		default:
+			report = fmt.Sprintf("0x%02X (%d bytes)", bytes[0], n)
+		}
+
+		if counts[report] == 0 {
+			observed = append(observed, report)
+		}
+
+		counts[report]++
+
+		if isClosed(expired) {
+			break
 		}
 	}
 
-	if bytes[0] == 0x11 && bytes[1] == 0xC0 {
-		return ConnectionTypeBluetooth, nil
+	if len(observed) == 0 {
+		return 0, errors.Wrap(ErrInvalidConnectionType, "no input reports")
 	}
 
-	return ConnectionTypeUSB, nil
+	for i, report := range observed {
+		observed[i] = fmt.Sprintf("%s x%d", report, counts[report])
+	}
+
+	return 0, errors.Wrapf(ErrInvalidConnectionType, "unexpected input reports: %s", strings.Join(observed, ", "))
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// pollIntervalFlags returns the output report byte setting the report interval of a DualShock 4 over Bluetooth.
+func pollIntervalFlags(interval time.Duration) byte {
+	return bluetoothOutputFlagHID | byte(interval/time.Millisecond)
 }
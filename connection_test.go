@@ -0,0 +1,194 @@
+package gods4
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// transportMockDevice knows its transport like hid.Device does.
+type transportMockDevice struct {
+	*mockDevice
+	isBluetooth bool
+}
+
+func (d *transportMockDevice) IsBluetooth() (bool, bool) {
+	return d.isBluetooth, true
+}
+
+func TestDetectConnectionTypeFromDevice(t *testing.T) {
+	device := &transportMockDevice{mockDevice: newMockDevice(usbInput(128, 128, 128, 128)), isBluetooth: true}
+
+	connectionType, err := detectConnectionType(device, ModelDualShock4, ReportModeExtended, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if connectionType != ConnectionTypeBluetooth {
+		t.Errorf("got %v, want BT", connectionType)
+	}
+
+	if len(device.inputs) != 1 {
+		t.Error("reports were read although the device knows its transport")
+	}
+}
+
+func TestDetectConnectionTypeFromReports(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs [][]byte
+		want   ConnectionType
+	}{
+		{"USB", [][]byte{usbInput(128, 128, 128, 128)}, ConnectionTypeUSB},
+		{"extended BT", [][]byte{bluetoothInput()}, ConnectionTypeBluetooth},
+		{"reduced BT", [][]byte{usbInput(128, 128, 128, 128)[:reducedInputReportSize]}, ConnectionTypeBluetooth},
+		{"after unknown reports", [][]byte{{0x05}, {0x05}, bluetoothInput()}, ConnectionTypeBluetooth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The report after the detected one is left for Listen
+			device := newMockDevice(append(tt.inputs, usbInput(1, 2, 3, 4))...)
+
+			connectionType, err := detectConnectionType(device, ModelDualShock4, ReportModeReduced, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if connectionType != tt.want {
+				t.Errorf("got %v, want %v", connectionType, tt.want)
+			}
+
+			if len(device.inputs) != 1 {
+				t.Errorf("%d reports left, want 1", len(device.inputs))
+			}
+		})
+	}
+}
+
+func TestDetectConnectionTypeFails(t *testing.T) {
+	inputs := make([][]byte, maxDetectionReports)
+	for i := range inputs {
+		inputs[i] = []byte{0x05, 0x00}
+	}
+
+	_, err := detectConnectionType(newMockDevice(inputs...), ModelDualShock4, ReportModeReduced, time.Second)
+	if errors.Cause(err) != ErrInvalidConnectionType {
+		t.Errorf("got error %v, want %v", err, ErrInvalidConnectionType)
+	}
+
+	_, err = detectConnectionType(newMockDevice(), ModelDualShock4, ReportModeReduced, time.Second)
+	if errors.Cause(err) != ErrInvalidConnectionType {
+		t.Errorf("got error %v, want %v", err, ErrInvalidConnectionType)
+	}
+}
+
+// silentDevice sends no report, a read waits until the device is closed.
+type silentDevice struct {
+	*mockDevice
+	closed   chan struct{}
+	isJoined bool
+}
+
+func (d *silentDevice) Read(b []byte) (int, error) {
+	<-d.closed
+	d.isJoined = true
+
+	return 0, io.EOF
+}
+
+func (d *silentDevice) Close() error {
+	select {
+	case <-d.closed:
+	default:
+		close(d.closed)
+	}
+
+	return d.mockDevice.Close()
+}
+
+func TestDetectConnectionTypeTimesOut(t *testing.T) {
+	device := &silentDevice{mockDevice: newMockDevice(), closed: make(chan struct{})}
+
+	_, err := detectConnectionType(device, ModelDualShock4, ReportModeReduced, 10*time.Millisecond)
+	if errors.Cause(err) != ErrInvalidConnectionType {
+		t.Errorf("got error %v, want %v", err, ErrInvalidConnectionType)
+	}
+
+	if !device.isJoined {
+		t.Error("the pending read was not joined")
+	}
+}
+
+// failingFeatureDevice fails to get feature reports.
+type failingFeatureDevice struct {
+	*mockDevice
+}
+
+func (d *failingFeatureDevice) GetFeatureReport(code byte) ([]byte, error) {
+	return nil, errors.New("broken pipe")
+}
+
+func TestConnectClosesDeviceOnError(t *testing.T) {
+	const serial = "a4:53:85:00:00:01"
+
+	invalid := AxisCalibration{Min: 200, Center: 100, Max: 50}
+
+	tests := []struct {
+		name   string
+		config ConnectionConfig
+		device func(mock *mockDevice) Device
+		store  CalibrationStore
+	}{
+		{
+			"detection",
+			DefaultConnectionConfig(),
+			func(mock *mockDevice) Device { return mock },
+			nil,
+		},
+		{
+			"extended mode",
+			ConnectionConfig{Type: ConnectionTypeBluetooth},
+			func(mock *mockDevice) Device { return &failingFeatureDevice{mock} },
+			nil,
+		},
+		{
+			"calibration",
+			ConnectionConfig{Type: ConnectionTypeUSB},
+			func(mock *mockDevice) Device { return mock },
+			mapStore{serial: {LeftStick: StickCalibration{X: invalid}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockDevice()
+			mock.serial = serial
+
+			c := NewController(tt.device(mock))
+			if tt.store != nil {
+				c.SetCalibrationStore(tt.store)
+			}
+
+			err := c.ConfigureConnection(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.Connect()
+			if err == nil {
+				t.Fatal("connected")
+			}
+
+			if mock.isOpen {
+				t.Error("device left open")
+			}
+
+			if c.ConnectionType() != ConnectionTypeNone {
+				t.Errorf("got connection type %v, want none", c.ConnectionType())
+			}
+		})
+	}
+}
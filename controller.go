@@ -4,13 +4,19 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kpeu3i/gods4/audio"
+	"github.com/kpeu3i/gods4/filter"
 	"github.com/kpeu3i/gods4/hid"
 	"github.com/kpeu3i/gods4/led"
 	"github.com/kpeu3i/gods4/rumble"
+	"github.com/kpeu3i/gods4/trigger"
+	"github.com/kpeu3i/gods4/volume"
 )
 
 var (
@@ -18,10 +24,17 @@ var (
 	ErrControllerIsConnected    = errors.New("ds4: controller is already connected")
 	ErrControllerIsNotConnected = errors.New("ds4: controller is not connected")
 	ErrControllerIsListening    = errors.New("ds4: controller is already listening for events")
+	ErrAudioIsNotSupported      = errors.New("ds4: audio streaming is supported over bluetooth only")
+	ErrInvalidRumbleScale       = errors.New("ds4: invalid rumble scale")
+	ErrIsNotSupported           = errors.New("ds4: not supported by the controller model")
+	ErrInvalidTrigger           = errors.New("ds4: trigger effects apply to L2 and R2 only")
 )
 
 const getFeatureReportCode0x04 = 0x04
 
+// Enables headphone (left and right), microphone and speaker volume bytes of the output report
+const outputFlagVolume = 0xF0
+
 type Device interface {
 	VendorID() uint16
 	ProductID() uint16
@@ -41,13 +54,37 @@ type Controller struct {
 	mutex          sync.RWMutex
 	device         Device
 	connectionType ConnectionType
+	connection     ConnectionConfig
+	reportMode     ReportMode
+	model          Model
+	serial         string
+	calibrations   CalibrationStore
 	emitter        *emitter
-	inputOffset    uint
+	remapper       *remapper
+	turbo          *turbo
+	settingsMutex  sync.RWMutex
+	settings       *settings
+	inputLayout    *inputLayout
+	reducedLayout  *inputLayout
+	reportCounters reportCounters
 	inputCurrState *state
 	inputPrevState *state
+	inputPrevRaw   *state
 	outputOffset   uint
 	outputState    []byte
+	outputSeq      byte
+	led            *led.Led
+	rumble         *rumble.Rumble
+	rumbleScale    float64
+	volume         *volume.Volume
+	playerLeds     *PlayerLeds
+	micLed         *MicLed
+	l2Effect       *trigger.Effect
+	r2Effect       *trigger.Effect
+	audioPacker    *audio.Packer
 	isListening    bool
+	outputWorker   *outputWorker
+	done           chan struct{}
 	errors         chan error
 	quit           chan struct{}
 }
@@ -58,7 +95,13 @@ func NewController(device Device) *Controller {
 	return &Controller{
 		device:         device,
 		connectionType: ConnectionTypeNone,
+		connection:     DefaultConnectionConfig(),
 		emitter:        newEmitter(),
+		remapper:       newRemapper(),
+		turbo:          newTurbo(),
+		settings:       defaultSettings(),
+		rumbleScale:    1,
+		audioPacker:    audio.NewPacker(),
 		errors:         make(chan error),
 		quit:           make(chan struct{}),
 	}
@@ -78,63 +121,180 @@ func (c *Controller) Connect() error {
 		return err
 	}
 
-	connectionType, err := detectConnectionType(c.device)
-	if err != nil {
-		return err
+	model := detectModel(c.device)
+
+	connectionType := c.connection.Type
+	if connectionType == ConnectionTypeNone {
+		connectionType, err = detectConnectionType(c.device, model, c.connection.ReportMode, c.connection.DetectionTimeout)
+		if err != nil {
+			_ = c.device.Close()
+
+			return err
+		}
 	}
 
-	c.connectionType = connectionType
+	reportMode := ReportModeExtended
+	if connectionType == ConnectionTypeBluetooth {
+		reportMode = c.connection.ReportMode
+	}
 
-	switch c.connectionType {
-	case ConnectionTypeBluetooth:
-		_, err = c.device.GetFeatureReport(getFeatureReportCode0x04)
+	if connectionType == ConnectionTypeBluetooth && reportMode == ReportModeExtended {
+		_, err = c.device.GetFeatureReport(model.bluetoothFeatureReport())
 		if err != nil {
+			_ = c.device.Close()
+
 			return err
 		}
+	}
 
-		c.inputOffset = 2
-		c.outputOffset = 3
+	serial := deviceSerial(c.device, model, connectionType)
 
-		c.outputState = make([]byte, 79)
-		c.outputState[0] = 0xA2
-		c.outputState[1] = 0x11
-		c.outputState[2] = 0x80
-		c.outputState[4] = 0x0F
-	case ConnectionTypeUSB:
-		c.inputOffset = 0
-		c.outputOffset = 0
+	// A controller missing from the store must not keep the calibration of another one
+	if c.calibrations != nil {
+		var calibration Calibration
+		if serial != "" {
+			calibration, _ = c.calibrations.Calibration(serial)
+		}
+
+		err = c.SetCalibration(calibration)
+		if err != nil {
+			_ = c.device.Close()
 
-		c.outputState = make([]byte, 79)
-		c.outputState[0] = 0x05
-		c.outputState[1] = 0x07
+			return err
+		}
+	}
+
+	c.model = model
+	c.connectionType = connectionType
+	c.reportMode = reportMode
+	c.outputWorker = newOutputWorker()
+
+	if setter, ok := c.device.(connectionTypeSetter); ok {
+		setter.SetConnectionType(connectionType)
+	}
+
+	c.inputLayout = newInputLayout(model, model.inputOffset(connectionType))
+	c.reducedLayout = nil
+
+	// A controller which sent extended reports before keeps sending them, so both
+	// are decoded. Their timestamps can't be mixed, all reports are stamped on arrival.
+	if reportMode == ReportModeReduced {
+		epoch := time.Now()
+		c.inputLayout.stampOnArrival(epoch)
+		c.reducedLayout = reducedInputLayout(epoch)
 	}
 
+	c.outputState, c.outputOffset = newOutputState(model, connectionType)
+	if c.hasPollInterval() {
+		c.outputState[2] = pollIntervalFlags(c.connection.PollInterval)
+	}
+
+	c.outputSeq = 0
+	c.reportCounters.reset()
+
+	c.serial = serial
+
 	return nil
 }
 
 func (c *Controller) Disconnect() error {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	worker, err := c.disconnect()
+	c.mutex.Unlock()
+
+	// A queued output change may be waiting for c.mutex,
+	// so the worker is joined once it is released
+	if worker != nil {
+		worker.wait()
+	}
+
+	return err
+}
 
+func (c *Controller) disconnect() (*outputWorker, error) {
 	err := c.errorIfNotConnected()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// The handler may have already stopped on a read error
+	isStopped := false
+	if c.done != nil {
+		select {
+		case c.quit <- struct{}{}:
+			isStopped = true
+		case <-c.done:
+		}
+
+		c.done = nil
 	}
 
-	c.quit <- struct{}{}
+	worker := c.outputWorker
+	c.outputWorker = nil
+	worker.stop()
 
 	err = c.device.Close()
 	if err != nil {
-		return err
+		return worker, err
 	}
 
 	c.connectionType = ConnectionTypeNone
+	c.reportMode = ReportModeExtended
+	c.serial = ""
+
+	if c.calibrations != nil {
+		c.updateSettings(func(s *settings) {
+			s.calibration = Calibration{}
+		})
+	}
 
-	c.errors <- nil
+	if isStopped {
+		c.errors <- nil
+	}
+
+	return worker, nil
+}
+
+// ConfigureConnection sets how the next Connect finds out the transport and which reports it asks for.
+// The poll interval of a connected DualShock 4 over Bluetooth changes at once.
+func (c *Controller) ConfigureConnection(config ConnectionConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.connection = config
+
+	if c.hasPollInterval() {
+		return c.set(map[uint]byte{2: pollIntervalFlags(config.PollInterval)})
+	}
 
 	return nil
 }
 
+func (c *Controller) ConnectionConfig() ConnectionConfig {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.connection
+}
+
+// ReportMode returns the kind of input reports decoded, always extended over USB.
+func (c *Controller) ReportMode() ReportMode {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.reportMode
+}
+
+// ReportRate returns the rate of input reports in Hz measured over the last second, 0 until a second has passed.
+func (c *Controller) ReportRate() float64 {
+	return c.reportCounters.rate.current()
+}
+
 func (c *Controller) ConnectionType() ConnectionType {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -147,20 +307,27 @@ func (c *Controller) Listen() error {
 
 	err := c.errorIfNotConnected()
 	if err != nil {
+		c.mutex.Unlock()
+
 		return err
 	}
 
 	err = c.errorIfListening()
 	if err != nil {
+		c.mutex.Unlock()
+
 		return err
 	}
 
 	c.isListening = true
 	defer func() {
+		c.mutex.Lock()
 		c.isListening = false
+		c.mutex.Unlock()
 	}()
 
-	go c.handle()
+	c.done = make(chan struct{})
+	go c.handle(c.done, c.outputWorker)
 
 	c.mutex.Unlock()
 
@@ -175,23 +342,342 @@ func (c *Controller) Off(event Event) {
 	c.emitter.unsetCallback(event)
 }
 
-func (c *Controller) Rumble(rumble *rumble.Rumble) error {
+// OnRawState calls fn with every decoded report before remapping and turbo.
+// Listeners are kept apart from event callbacks, the returned function removes fn.
+func (c *Controller) OnRawState(fn func(State)) func() {
+	return c.emitter.addRawListener(fn)
+}
+
+func (c *Controller) ConfigureL2(config TriggerConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.l2 = config
+	})
+
+	return nil
+}
+
+func (c *Controller) ConfigureR2(config TriggerConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.r2 = config
+	})
+
+	return nil
+}
+
+func (c *Controller) ConfigureLeftStick(config StickConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.leftStick = config
+	})
+
+	return nil
+}
+
+func (c *Controller) ConfigureRightStick(config StickConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.rightStick = config
+	})
+
+	return nil
+}
+
+func (c *Controller) SetCalibration(calibration Calibration) error {
+	err := calibration.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.calibration = calibration
+	})
+
+	return nil
+}
+
+func (c *Controller) Calibration() Calibration {
+	return c.currentSettings().calibration
+}
+
+// SetCalibrationStore makes Connect apply the stored calibration of the connected controller,
+// or no calibration if it is not stored. The calibration is dropped on Disconnect.
+func (c *Controller) SetCalibrationStore(store CalibrationStore) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	err := c.errorIfNotConnected()
+	c.calibrations = store
+}
+
+// Serial returns the MAC address of the connected controller, or its device serial
+// if that is not a MAC address. It is empty if neither is available.
+func (c *Controller) Serial() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.serial
+}
+
+func (c *Controller) ConfigureAccelerometer(filter filter.Filter) error {
+	if filter == nil {
+		return ErrInvalidFilter
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.accelerometerFilter = filter
+	})
+
+	return nil
+}
+
+func (c *Controller) ConfigureGyroscope(filter filter.Filter) error {
+	if filter == nil {
+		return ErrInvalidFilter
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.gyroscopeFilter = filter
+	})
+
+	return nil
+}
+
+func (c *Controller) ConfigureTiming(config TimingConfig) error {
+	err := config.Validate()
 	if err != nil {
 		return err
 	}
 
-	patch := make(map[uint]byte, 2)
-	patch[4+c.outputOffset] = rumble.Left()
-	patch[5+c.outputOffset] = rumble.Right()
+	c.emitter.setTiming(config)
+
+	return nil
+}
+
+// ConfigureReports sets how corrupt input reports are handled.
+func (c *Controller) ConfigureReports(config ReportConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.updateSettings(func(s *settings) {
+		s.reports = config
+	})
+
+	return nil
+}
+
+func (c *Controller) ReportConfig() ReportConfig {
+	return c.currentSettings().reports
+}
+
+func (c *Controller) ReportStats() ReportStats {
+	return c.reportCounters.stats()
+}
+
+func (c *Controller) TimingConfig() TimingConfig {
+	return c.emitter.currentTiming()
+}
+
+func (c *Controller) L2Config() TriggerConfig {
+	return c.currentSettings().l2
+}
+
+func (c *Controller) R2Config() TriggerConfig {
+	return c.currentSettings().r2
+}
+
+func (c *Controller) LeftStickConfig() StickConfig {
+	return c.currentSettings().leftStick
+}
+
+func (c *Controller) RightStickConfig() StickConfig {
+	return c.currentSettings().rightStick
+}
+
+// AddProfile adds or replaces a remapping profile.
+func (c *Controller) AddProfile(profile Profile) error {
+	return c.remapper.addProfile(profile)
+}
 
-	return c.set(patch)
+// RemoveProfile removes a profile and the buttons bound to it.
+// Removing the active profile disables remapping.
+func (c *Controller) RemoveProfile(name string) {
+	c.remapper.removeProfile(name)
+}
+
+// SetProfile activates a profile added before, an empty name disables remapping.
+// The profile LED is set only if the controller is connected.
+func (c *Controller) SetProfile(name string) error {
+	err := c.remapper.setProfile(name)
+	if err != nil {
+		return err
+	}
+
+	profile, err := c.applyProfile(name)
+	if err != nil || profile == nil {
+		return err
+	}
+
+	return c.applyProfileOutput(*profile)
+}
+
+func (c *Controller) Profile() string {
+	return c.remapper.profile()
+}
+
+// BindProfile activates the profile when PS is held and the button is pressed.
+// The button pressed with PS is not reported, up to its release.
+func (c *Controller) BindProfile(button Button, name string) error {
+	return c.remapper.bind(button, name)
+}
+
+// RegisterChord fires event once all buttons are held together.
+func (c *Controller) RegisterChord(event Event, buttons ...Button) error {
+	return c.emitter.combos.addChord(event, buttons)
+}
+
+// RegisterSequence fires event when steps are emitted in order within window.
+// Any other press event cancels a partial match.
+func (c *Controller) RegisterSequence(event Event, window time.Duration, steps ...Event) error {
+	return c.emitter.combos.addSequence(event, window, steps)
+}
+
+func (c *Controller) UnregisterCombo(event Event) {
+	c.emitter.combos.removeCombo(event)
+}
+
+// BindMacro plays the macro back each time the trigger button is pressed.
+// Played back events go through the same callbacks as real input.
+func (c *Controller) BindMacro(trigger Button, macro Macro) error {
+	return c.emitter.macros.bind(trigger, macro)
+}
+
+func (c *Controller) UnbindMacro(trigger Button) {
+	c.emitter.macros.unbind(trigger)
+}
+
+func (c *Controller) Macro(trigger Button) (Macro, bool) {
+	return c.emitter.macros.macro(trigger)
+}
+
+// CancelMacro stops playback of the macro bound to trigger,
+// buttons pressed by the macro are released.
+func (c *Controller) CancelMacro(trigger Button) {
+	c.emitter.macros.cancelOne(trigger)
+}
+
+func (c *Controller) CancelMacros() {
+	c.emitter.macros.cancelAll()
+}
+
+// EnableMacroRecording lets the user record macros: holding modifier and pressing a button
+// starts recording, repeating the gesture binds the recorded events to that button.
+func (c *Controller) EnableMacroRecording(modifier Button) error {
+	return c.emitter.macros.enableRecording(modifier)
+}
+
+func (c *Controller) DisableMacroRecording() {
+	c.emitter.macros.disableRecording()
+}
+
+// StartMacroRecording records input events until StopMacroRecording binds them to trigger.
+func (c *Controller) StartMacroRecording(trigger Button) error {
+	return c.emitter.macros.startRecording(trigger)
+}
+
+func (c *Controller) StopMacroRecording() (Macro, error) {
+	return c.emitter.macros.stopRecording()
+}
+
+// EnableTurbo makes the button emit press/release pairs while it is held,
+// or from a tap to the next one in toggle mode.
+func (c *Controller) EnableTurbo(button Button, config TurboConfig) error {
+	return c.turbo.enable(button, config)
+}
+
+func (c *Controller) DisableTurbo(button Button) {
+	c.turbo.disable(button)
+}
+
+// IsTurboActive reports whether the button is firing right now.
+func (c *Controller) IsTurboActive(button Button) bool {
+	return c.turbo.isActive(button)
+}
+
+// SetRumbleScale scales the strength of rumble sent to the controller,
+// CurrentRumble keeps returning the requested values.
+func (c *Controller) SetRumbleScale(scale float64) error {
+	if scale < 0 || math.IsNaN(scale) {
+		return errors.Wrapf(ErrInvalidRumbleScale, "%v", scale)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.rumbleScale = scale
+
+	if c.rumble == nil || c.connectionType == ConnectionTypeNone {
+		return nil
+	}
+
+	return c.set(c.rumblePatch(c.rumble))
+}
+
+func (c *Controller) RumbleScale() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.rumbleScale
+}
+
+func (c *Controller) Rumble(rumble *rumble.Rumble) error {
+	output, err := c.setRumble(rumble)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
 }
 
 func (c *Controller) Led(led *led.Led) error {
+	output, err := c.setLed(led)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+func (c *Controller) Volume(volume *volume.Volume) error {
+	output, err := c.setVolume(volume)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+// WriteAudio streams SBC frames to the speaker or the headset jack.
+// Over USB the controller exposes a regular USB audio device instead.
+func (c *Controller) WriteAudio(target audio.Target, sbc []byte) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -200,40 +686,196 @@ func (c *Controller) Led(led *led.Led) error {
 		return err
 	}
 
-	patch := make(map[uint]byte, 3)
-	patch[6+c.outputOffset] = led.Red()
-	patch[7+c.outputOffset] = led.Green()
-	patch[8+c.outputOffset] = led.Blue()
-	patch[9+c.outputOffset] = led.FlashOn()
-	patch[10+c.outputOffset] = led.FlashOff()
+	if c.model == ModelDualSense {
+		return errors.Wrap(ErrIsNotSupported, "audio")
+	}
+
+	if c.connectionType != ConnectionTypeBluetooth {
+		return ErrAudioIsNotSupported
+	}
 
-	return c.set(patch)
+	reports, err := c.audioPacker.Pack(target, sbc)
+	if err != nil {
+		return err
+	}
+
+	for _, report := range reports {
+		_, err = c.device.Write(report)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PlayerLeds lights the player LEDs of a DualSense.
+func (c *Controller) PlayerLeds(leds PlayerLeds) error {
+	output, err := c.setPlayerLeds(leds)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+// MicLed sets the LED of the DualSense mute button.
+func (c *Controller) MicLed(mode MicLed) error {
+	output, err := c.setMicLed(mode)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+// TriggerEffect sets the adaptive trigger effect of L2 or R2 on a DualSense.
+func (c *Controller) TriggerEffect(button Button, effect *trigger.Effect) error {
+	output, err := c.setTriggerEffect(button, effect)
+	if err != nil {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+func (c *Controller) CurrentLed() *led.Led {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return copyLed(c.led)
+}
+
+func (c *Controller) CurrentRumble() *rumble.Rumble {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return copyRumble(c.rumble)
+}
+
+func (c *Controller) CurrentVolume() *volume.Volume {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return copyVolume(c.volume)
+}
+
+func (c *Controller) CurrentOutput() Output {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.output()
 }
 
-func (c *Controller) handle() {
-	bytes := make([]byte, 64)
-	bytes[0+c.inputOffset] = 1
-	bytes[1+c.inputOffset] = 128
-	bytes[2+c.inputOffset] = 128
-	bytes[3+c.inputOffset] = 128
-	bytes[4+c.inputOffset] = 128
-	bytes[5+c.inputOffset] = 8
+func (c *Controller) handle(done chan struct{}, worker *outputWorker) {
+	defer close(done)
 
-	c.inputPrevState = newState(bytes, c.inputOffset, nil)
+	bytes := make([]byte, bluetoothInputReportSize)
+	c.inputLayout.neutral(bytes)
+
+	// Reduced reports keep the neutral values of the fields they don't carry
+	var reduced []byte
+	if c.reducedLayout != nil {
+		reduced = make([]byte, bluetoothInputReportSize)
+		c.reducedLayout.neutral(reduced)
+	}
+
+	settings := c.currentSettings()
+
+	// The neutral state is not a sample, filters start from the first report
+	var filters *filters
+
+	c.inputPrevRaw = newState(bytes, c.inputLayout, nil, settings, newFilters(settings))
+	c.inputPrevState = c.inputPrevRaw
 
 	for {
 		select {
 		case <-c.quit:
+			return
+		case err := <-worker.errors:
+			c.errors <- err
+
 			return
 		default:
-			_, err := c.device.Read(bytes)
+			n, err := c.device.Read(bytes)
 			if err != nil {
 				c.errors <- err
 
 				return
 			}
 
-			c.inputCurrState = newState(bytes, c.inputOffset, c.inputPrevState)
+			settings = c.currentSettings()
+
+			ok, err := c.checkReport(bytes[:n], settings.reports)
+			if err != nil {
+				c.errors <- err
+
+				return
+			}
+
+			if !ok {
+				continue
+			}
+
+			if filters == nil {
+				filters = newFilters(settings)
+			} else {
+				filters.update(settings)
+			}
+
+			layout, input := c.inputLayout, bytes
+			if reduced != nil && bytes[0] == usbInputReportID {
+				copy(reduced, bytes[:n])
+				layout, input = c.reducedLayout, reduced
+			}
+
+			raw := newState(input, layout, c.inputPrevRaw, settings, filters)
+			c.emitter.emitRaw(raw)
+
+			var (
+				profile    string
+				isSwitched bool
+			)
+
+			c.inputCurrState, profile, isSwitched = c.remapper.apply(raw, c.inputPrevRaw, c.inputPrevState, settings)
+
+			if isSwitched {
+				active, err := c.applyProfile(profile)
+				if err != nil {
+					c.errors <- err
+
+					return
+				}
+
+				if active != nil && !c.queueOutput(worker, func(<-chan struct{}) error {
+					return c.applyProfileOutput(*active)
+				}) {
+					return
+				}
+
+				err = c.emitter.fire(raw.timestamp, EventProfileChange, profile)
+				if err != nil {
+					c.errors <- err
+
+					return
+				}
+			}
+
+			var toggles []turboToggle
+
+			c.inputCurrState, toggles = c.turbo.apply(c.inputCurrState)
+			for _, toggle := range toggles {
+				err = c.toggleTurbo(c.inputCurrState.timestamp, toggle, worker)
+				if err == errInputStopped {
+					return
+				}
+
+				if err != nil {
+					c.errors <- err
+
+					return
+				}
+			}
 
 			err = c.emitter.emit(c.inputCurrState, c.inputPrevState)
 			if err != nil {
@@ -242,11 +884,20 @@ func (c *Controller) handle() {
 				return
 			}
 
+			c.inputPrevRaw = raw
 			c.inputPrevState = c.inputCurrState
 		}
 	}
 }
 
+// Model returns the model of the connected controller.
+func (c *Controller) Model() Model {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.model
+}
+
 func (c *Controller) VendorID() uint16 {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -275,29 +926,351 @@ func (c *Controller) String() string {
 	return fmt.Sprintf("%s (vendor: %v, product: %v)", c.device.Product(), c.device.VendorID(), c.device.ProductID())
 }
 
+func (c *Controller) setRumble(rumble *rumble.Rumble) (Output, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	err = c.set(c.rumblePatch(rumble))
+	if err != nil {
+		return Output{}, err
+	}
+
+	c.rumble = copyRumble(rumble)
+
+	return c.output(), nil
+}
+
+func (c *Controller) rumblePatch(rumble *rumble.Rumble) map[uint]byte {
+	scale := func(value byte) byte {
+		return byte(math.Min(math.Round(float64(value)*c.rumbleScale), 255))
+	}
+
+	patch := make(map[uint]byte, 2)
+
+	if c.model == ModelDualSense {
+		patch[3+c.outputOffset] = scale(rumble.Left())
+		patch[4+c.outputOffset] = scale(rumble.Right())
+
+		return patch
+	}
+
+	patch[4+c.outputOffset] = scale(rumble.Left())
+	patch[5+c.outputOffset] = scale(rumble.Right())
+
+	return patch
+}
+
+func (c *Controller) setLed(led *led.Led) (Output, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	err = c.set(c.ledPatch(led))
+	if err != nil {
+		return Output{}, err
+	}
+
+	c.led = copyLed(led)
+
+	return c.output(), nil
+}
+
+func (c *Controller) ledPatch(led *led.Led) map[uint]byte {
+	patch := make(map[uint]byte, 5)
+
+	// The DualSense lightbar does not flash
+	if c.model == ModelDualSense {
+		patch[45+c.outputOffset] = led.Red()
+		patch[46+c.outputOffset] = led.Green()
+		patch[47+c.outputOffset] = led.Blue()
+	} else {
+		patch[6+c.outputOffset] = led.Red()
+		patch[7+c.outputOffset] = led.Green()
+		patch[8+c.outputOffset] = led.Blue()
+		patch[9+c.outputOffset] = led.FlashOn()
+		patch[10+c.outputOffset] = led.FlashOff()
+	}
+
+	return patch
+}
+
+func (c *Controller) setVolume(volume *volume.Volume) (Output, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	if c.model == ModelDualSense {
+		return Output{}, errors.Wrap(ErrIsNotSupported, "volume")
+	}
+
+	patch := make(map[uint]byte, 5)
+	patch[1+c.outputOffset] = c.outputState[1+c.outputOffset] | outputFlagVolume
+	patch[19+c.outputOffset] = volume.Left()
+	patch[20+c.outputOffset] = volume.Right()
+	patch[21+c.outputOffset] = volume.Mic()
+	patch[22+c.outputOffset] = volume.Speaker()
+
+	err = c.set(patch)
+	if err != nil {
+		return Output{}, err
+	}
+
+	c.volume = copyVolume(volume)
+
+	return c.output(), nil
+}
+
+func (c *Controller) setPlayerLeds(leds PlayerLeds) (Output, error) {
+	if leds > PlayerLedsAll {
+		return Output{}, errors.Wrapf(ErrInvalidPlayerLeds, "%#x", byte(leds))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	if c.model != ModelDualSense {
+		return Output{}, errors.Wrap(ErrIsNotSupported, "player LEDs")
+	}
+
+	err = c.set(map[uint]byte{44 + c.outputOffset: byte(leds)})
+	if err != nil {
+		return Output{}, err
+	}
+
+	c.playerLeds = &leds
+
+	return c.output(), nil
+}
+
+func (c *Controller) setMicLed(mode MicLed) (Output, error) {
+	if mode > MicLedPulse {
+		return Output{}, errors.Wrapf(ErrInvalidMicLed, "%d", mode)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	if c.model != ModelDualSense {
+		return Output{}, errors.Wrap(ErrIsNotSupported, "mic LED")
+	}
+
+	err = c.set(map[uint]byte{9 + c.outputOffset: byte(mode)})
+	if err != nil {
+		return Output{}, err
+	}
+
+	c.micLed = &mode
+
+	return c.output(), nil
+}
+
+func (c *Controller) setTriggerEffect(button Button, effect *trigger.Effect) (Output, error) {
+	if button != ButtonL2 && button != ButtonR2 {
+		return Output{}, errors.Wrapf(ErrInvalidTrigger, "%s", button)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, err
+	}
+
+	if c.model != ModelDualSense {
+		return Output{}, errors.Wrap(ErrIsNotSupported, "trigger effects")
+	}
+
+	// Effects are 11 bytes, R2 comes first in the report
+	position, flag := uint(11), byte(dualSenseFlagR2Effect)
+	if button == ButtonL2 {
+		position, flag = 22, dualSenseFlagL2Effect
+	}
+
+	patch := make(map[uint]byte, 12)
+	patch[1+c.outputOffset] = c.outputState[1+c.outputOffset] | flag
+
+	for i, b := range effect.Bytes() {
+		patch[position+uint(i)+c.outputOffset] = b
+	}
+
+	err = c.set(patch)
+	if err != nil {
+		return Output{}, err
+	}
+
+	if button == ButtonL2 {
+		c.l2Effect = copyEffect(effect)
+	} else {
+		c.r2Effect = copyEffect(effect)
+	}
+
+	return c.output(), nil
+}
+
+func (c *Controller) output() Output {
+	return Output{
+		Led:        copyLed(c.led),
+		Rumble:     copyRumble(c.rumble),
+		Volume:     copyVolume(c.volume),
+		PlayerLeds: copyPlayerLeds(c.playerLeds),
+		MicLed:     copyMicLed(c.micLed),
+		L2Effect:   copyEffect(c.l2Effect),
+		R2Effect:   copyEffect(c.r2Effect),
+	}
+}
+
 func (c *Controller) set(patch map[uint]byte) error {
 	for i, b := range patch {
 		c.outputState[i] = b
 	}
 
-	switch c.connectionType {
-	case ConnectionTypeBluetooth:
+	switch {
+	case c.connectionType == ConnectionTypeBluetooth:
+		// The DualSense drops reports that reuse a sequence number
+		if c.model == ModelDualSense {
+			c.outputState[2] = c.outputSeq << 4
+			c.outputSeq = (c.outputSeq + 1) % 16
+		}
+
 		crc := crc32.ChecksumIEEE(c.outputState[0:75])
 		binary.LittleEndian.PutUint32(c.outputState[75:], crc)
 		_, err := c.device.Write(c.outputState[1:])
 		if err != nil {
 			return err
 		}
-	case ConnectionTypeUSB:
+	case c.connectionType == ConnectionTypeUSB && c.model == ModelDualSense:
+		_, err := c.device.Write(c.outputState[:dualSenseUSBOutputSize])
+		if err != nil {
+			return err
+		}
+	case c.connectionType == ConnectionTypeUSB:
 		_, err := c.device.Write(c.outputState)
 		if err != nil {
 			return err
 		}
 	}
 
+	// The startup lightbar is faded out once
+	if c.model == ModelDualSense {
+		c.outputState[39+c.outputOffset] = 0
+		c.outputState[42+c.outputOffset] = 0
+	}
+
 	return nil
 }
 
+// applyProfile applies the input configuration of an activated profile,
+// nil is returned if remapping is disabled.
+func (c *Controller) applyProfile(name string) (*Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := c.remapper.get(name)
+	if !ok {
+		return nil, errors.Wrapf(ErrProfileNotFound, "profile %q", name)
+	}
+
+	c.updateSettings(func(s *settings) {
+		if profile.L2 != nil {
+			s.l2 = *profile.L2
+		}
+
+		if profile.R2 != nil {
+			s.r2 = *profile.R2
+		}
+
+		if profile.LeftStick != nil {
+			s.leftStick = *profile.LeftStick
+		}
+
+		if profile.RightStick != nil {
+			s.rightStick = *profile.RightStick
+		}
+	})
+
+	if profile.Macros != nil {
+		c.emitter.macros.reset(profile.Macros)
+	}
+
+	return &profile, nil
+}
+
+// applyProfileOutput sets the LED and rumble scale of an activated profile.
+func (c *Controller) applyProfileOutput(profile Profile) error {
+	if profile.RumbleScale != nil {
+		err := c.SetRumbleScale(*profile.RumbleScale)
+		if err != nil {
+			return err
+		}
+	}
+
+	if profile.Led != nil {
+		err := c.Led(profile.Led)
+		if err != nil && err != ErrControllerIsNotConnected {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queueOutput hands an output change caused by input to the worker. False is returned
+// if Disconnect stopped the input goroutine while it was waiting for a full queue.
+func (c *Controller) queueOutput(worker *outputWorker, job outputJob) bool {
+	select {
+	case worker.jobs <- job:
+		return true
+	case <-c.quit:
+		return false
+	}
+}
+
+func (c *Controller) currentSettings() *settings {
+	c.settingsMutex.RLock()
+	defer c.settingsMutex.RUnlock()
+
+	return c.settings
+}
+
+func (c *Controller) updateSettings(fn func(s *settings)) {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+
+	s := *c.settings
+	fn(&s)
+	c.settings = &s
+}
+
+// hasPollInterval reports whether the output report sets the report interval, only a DualShock 4 over Bluetooth does.
+func (c *Controller) hasPollInterval() bool {
+	return c.connectionType == ConnectionTypeBluetooth && c.model == ModelDualShock4
+}
+
 func (c *Controller) errorIfConnected() error {
 	if c.connectionType != ConnectionTypeNone {
 		return ErrControllerIsConnected
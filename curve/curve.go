@@ -0,0 +1,55 @@
+package curve
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Curve maps an input in [0, 1] to an output in [0, 1].
+type Curve func(x float64) float64
+
+func Linear() Curve {
+	return func(x float64) float64 {
+		return clamp(x)
+	}
+}
+
+func Exponential(exponent float64) Curve {
+	return func(x float64) float64 {
+		return clamp(math.Pow(clamp(x), exponent))
+	}
+}
+
+// LUT builds a curve from outputs sampled at evenly spaced inputs,
+// from 0 to 1 inclusive, interpolating linearly between them.
+func LUT(points ...float64) (Curve, error) {
+	if len(points) < 2 {
+		return nil, errors.New("curve: LUT requires at least 2 points")
+	}
+
+	for i, point := range points {
+		if point < 0 || point > 1 || math.IsNaN(point) {
+			return nil, errors.Errorf("curve: LUT point #%d (%v) is out of range [0, 1]", i, point)
+		}
+	}
+
+	table := make([]float64, len(points))
+	copy(table, points)
+
+	return func(x float64) float64 {
+		position := clamp(x) * float64(len(table)-1)
+		i := int(position)
+		if i >= len(table)-1 {
+			return table[len(table)-1]
+		}
+
+		fraction := position - float64(i)
+
+		return table[i] + (table[i+1]-table[i])*fraction
+	}, nil
+}
+
+func clamp(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
@@ -0,0 +1,70 @@
+package curve
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCurves(t *testing.T) {
+	lut, err := LUT(0, 0.1, 0.5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	curves := map[string]Curve{
+		"linear":      Linear(),
+		"exponential": Exponential(2.5),
+		"LUT":         lut,
+	}
+
+	for name, c := range curves {
+		t.Run(name, func(t *testing.T) {
+			if c(0) != 0 || c(1) != 1 {
+				t.Errorf("endpoints are mapped to %v and %v, want 0 and 1", c(0), c(1))
+			}
+
+			if c(-0.5) != 0 || c(1.5) != 1 {
+				t.Errorf("inputs out of range are mapped to %v and %v, want 0 and 1", c(-0.5), c(1.5))
+			}
+
+			prev := 0.0
+
+			for i := 0; i <= 1000; i++ {
+				y := c(float64(i) / 1000)
+				if y < prev {
+					t.Fatalf("%v is mapped to %v, below %v", float64(i)/1000, y, prev)
+				}
+
+				prev = y
+			}
+		})
+	}
+}
+
+func TestLUTInterpolates(t *testing.T) {
+	c, err := LUT(0, 0.1, 0.5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[float64]float64{
+		1.0 / 3: 0.1,
+		0.5:     0.3,
+		5.0 / 6: 0.75,
+	}
+
+	for x, want := range tests {
+		if got := c(x); math.Abs(got-want) > 1e-9 {
+			t.Errorf("c(%v) = %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestLUTInvalid(t *testing.T) {
+	for _, points := range [][]float64{nil, {0.5}, {0, 1.5}, {-0.1, 1}, {0, math.NaN(), 1}} {
+		_, err := LUT(points...)
+		if err == nil {
+			t.Errorf("LUT(%v) is valid", points)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gods4
+
+import (
+	"io"
+	"sync"
+)
+
+// mockDevice is a DualShock 4 that answers reads from a list of input reports,
+// returns io.EOF after the last one and keeps all written output reports.
+type mockDevice struct {
+	mutex     sync.Mutex
+	productID uint16
+	serial    string
+	inputs    [][]byte
+	features  map[byte][]byte
+	writes    [][]byte
+	sent      [][]byte
+	isOpen    bool
+}
+
+func newMockDevice(inputs ...[]byte) *mockDevice {
+	return &mockDevice{
+		productID: 0x05C4,
+		inputs:    inputs,
+		features:  make(map[byte][]byte),
+	}
+}
+
+func (d *mockDevice) VendorID() uint16     { return 0x054C }
+func (d *mockDevice) ProductID() uint16    { return d.productID }
+func (d *mockDevice) Path() string         { return "mock" }
+func (d *mockDevice) Release() uint16      { return 0x0100 }
+func (d *mockDevice) Serial() string       { return d.serial }
+func (d *mockDevice) Manufacturer() string { return "Sony" }
+func (d *mockDevice) Product() string      { return "Wireless Controller" }
+
+func (d *mockDevice) Open() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.isOpen = true
+
+	return nil
+}
+
+func (d *mockDevice) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.isOpen = false
+
+	return nil
+}
+
+func (d *mockDevice) Read(b []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.inputs) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, d.inputs[0])
+	d.inputs = d.inputs[1:]
+
+	return n, nil
+}
+
+func (d *mockDevice) Write(b []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.writes = append(d.writes, append([]byte(nil), b...))
+
+	return len(b), nil
+}
+
+func (d *mockDevice) GetFeatureReport(code byte) ([]byte, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if bytes, ok := d.features[code]; ok {
+		return append([]byte(nil), bytes...), nil
+	}
+
+	return []byte{code}, nil
+}
+
+func (d *mockDevice) SendFeatureReport(b []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.sent = append(d.sent, append([]byte(nil), b...))
+
+	return len(b), nil
+}
+
+func (d *mockDevice) written() [][]byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([][]byte(nil), d.writes...)
+}
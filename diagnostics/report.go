@@ -0,0 +1,407 @@
+package diagnostics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// Range of motion is measured in this many angular sectors
+const sectors = 36
+
+// Stick samples closer to the center are ignored when measuring the range of motion
+const minEdgeRadius = 0.5
+
+// Thresholds of the health checks. Stick values are fractions of full deflection,
+// trigger values are raw in [0, 255] and gyroscope bias is in raw units.
+type Thresholds struct {
+	MaxStickCenterOffset float64
+	MaxStickNoise        float64
+	MinStickCircularity  float64
+	MaxTriggerRest       byte
+	MinTriggerTravel     byte
+	MaxGyroscopeBias     float64
+	MaxDroppedRate       float64
+	ChatterInterval      time.Duration
+	MaxChatter           int
+}
+
+var DefaultThresholds = Thresholds{
+	MaxStickCenterOffset: 0.06,
+	MaxStickNoise:        0.02,
+	MinStickCircularity:  0.9,
+	MaxTriggerRest:       5,
+	MinTriggerTravel:     250,
+	MaxGyroscopeBias:     40,
+	MaxDroppedRate:       0.01,
+	ChatterInterval:      10 * time.Millisecond,
+	MaxChatter:           0,
+}
+
+type Status string
+
+const (
+	StatusPass    Status = "pass"
+	StatusFail    Status = "fail"
+	StatusSkipped Status = "skipped"
+)
+
+// Check is a single measurement compared to its limit, a minimum if IsMinimum is set.
+// Checks without samples of the required phase are skipped.
+type Check struct {
+	Name      string
+	Status    Status
+	Value     float64
+	Limit     float64
+	IsMinimum bool
+}
+
+// StickReport holds the center (mean of rest samples) and the noise (standard deviation)
+// per axis, the offset of the center and the circularity, the mean of the largest radius
+// reached per angular sector with 1 for a full circle. Coverage is the fraction of sectors reached.
+type StickReport struct {
+	CenterX     float64
+	CenterY     float64
+	Offset      float64
+	NoiseX      float64
+	NoiseY      float64
+	Circularity float64
+	Coverage    float64
+}
+
+type TriggerReport struct {
+	Min byte
+	Max byte
+}
+
+// GyroscopeReport holds the mean raw angular velocity at rest.
+type GyroscopeReport struct {
+	BiasRoll  float64
+	BiasYaw   float64
+	BiasPitch float64
+}
+
+// Report is failed if any check failed and skipped if none failed but some were skipped.
+// ReportInterval is the mean time between consecutive reports and ReportJitter its standard deviation.
+// Chatter counts presses and releases shorter than the chatter interval per button.
+type Report struct {
+	Status         Status
+	Checks         []Check
+	RestSamples    int
+	MotionSamples  int
+	LeftStick      StickReport
+	RightStick     StickReport
+	L2             TriggerReport
+	R2             TriggerReport
+	Gyroscope      GyroscopeReport
+	Reports        int
+	DroppedReports int
+	ReportInterval time.Duration
+	ReportJitter   time.Duration
+	Chatter        map[gods4.Button]int
+}
+
+func (r Report) IsPassed() bool {
+	return r.Status == StatusPass
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+
+	for _, check := range r.Checks {
+		operator := "<="
+		if check.IsMinimum {
+			operator = ">="
+		}
+
+		_, _ = fmt.Fprintf(&b, "%-7s %-27s %.4g (%s %.4g)\n", check.Status, check.Name, check.Value, operator, check.Limit)
+	}
+
+	_, _ = fmt.Fprintf(&b, "%s\n", r.Status)
+
+	return b.String()
+}
+
+// Analyze measures the samples of the rest and the motion phase of a controller model.
+func Analyze(model gods4.Model, rest, motion []gods4.State, thresholds Thresholds) Report {
+	r := Report{
+		RestSamples:   len(rest),
+		MotionSamples: len(motion),
+		Chatter:       make(map[gods4.Button]int),
+	}
+
+	leftStick := func(s *gods4.State) gods4.Stick { return s.LeftStick }
+	rightStick := func(s *gods4.State) gods4.Stick { return s.RightStick }
+
+	r.LeftStick = analyzeStick(rest, motion, leftStick)
+	r.RightStick = analyzeStick(rest, motion, rightStick)
+	r.L2 = analyzeTrigger(rest, motion, func(s *gods4.State) byte { return s.L2.Value })
+	r.R2 = analyzeTrigger(rest, motion, func(s *gods4.State) byte { return s.R2.Value })
+	r.Gyroscope = analyzeGyroscope(rest)
+
+	// Reports between the phases are not sampled, so each phase is counted on its own
+	var intervals []float64
+
+	for _, states := range [][]gods4.State{rest, motion} {
+		r.Reports += len(states)
+		r.DroppedReports += droppedReports(states, counterMask(model))
+		countChatter(states, thresholds.ChatterInterval, r.Chatter)
+
+		for i := 1; i < len(states); i++ {
+			intervals = append(intervals, float64(states[i].Timestamp-states[i-1].Timestamp))
+		}
+	}
+
+	if len(intervals) > 0 {
+		interval, jitter := meanDeviation(intervals)
+		r.ReportInterval, r.ReportJitter = time.Duration(interval), time.Duration(jitter)
+	}
+
+	hasRest := len(rest) > 0
+	hasMotion := len(motion) > 0
+
+	for _, stick := range []struct {
+		name   string
+		report StickReport
+	}{
+		{"left_stick", r.LeftStick},
+		{"right_stick", r.RightStick},
+	} {
+		r.check(stick.name+".center_offset", hasRest, stick.report.Offset, thresholds.MaxStickCenterOffset, false)
+		r.check(stick.name+".noise", hasRest, math.Max(stick.report.NoiseX, stick.report.NoiseY), thresholds.MaxStickNoise, false)
+		r.check(stick.name+".circularity", hasMotion, stick.report.Circularity, thresholds.MinStickCircularity, true)
+	}
+
+	for _, trigger := range []struct {
+		name   string
+		report TriggerReport
+	}{
+		{"l2", r.L2},
+		{"r2", r.R2},
+	} {
+		r.check(trigger.name+".rest", hasRest || hasMotion, float64(trigger.report.Min), float64(thresholds.MaxTriggerRest), false)
+		r.check(trigger.name+".travel", hasMotion, float64(trigger.report.Max), float64(thresholds.MinTriggerTravel), true)
+	}
+
+	bias := math.Max(math.Abs(r.Gyroscope.BiasRoll), math.Max(math.Abs(r.Gyroscope.BiasYaw), math.Abs(r.Gyroscope.BiasPitch)))
+	r.check("gyroscope.bias", hasRest, bias, thresholds.MaxGyroscopeBias, false)
+
+	var droppedRate float64
+	if r.Reports > 0 {
+		droppedRate = float64(r.DroppedReports) / float64(r.Reports+r.DroppedReports)
+	}
+
+	r.check("dropped_reports", r.Reports > 1, droppedRate, thresholds.MaxDroppedRate, false)
+
+	var chatter int
+	for _, count := range r.Chatter {
+		chatter += count
+	}
+
+	r.check("button_chatter", r.Reports > 1, float64(chatter), float64(thresholds.MaxChatter), false)
+
+	r.Status = StatusPass
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			r.Status = StatusFail
+
+			break
+		}
+
+		if check.Status == StatusSkipped {
+			r.Status = StatusSkipped
+		}
+	}
+
+	return r
+}
+
+func (r *Report) check(name string, hasSamples bool, value, limit float64, isMinimum bool) {
+	check := Check{
+		Name:      name,
+		Status:    StatusPass,
+		Value:     value,
+		Limit:     limit,
+		IsMinimum: isMinimum,
+	}
+
+	switch {
+	case !hasSamples:
+		check.Status = StatusSkipped
+	case isMinimum && value < limit, !isMinimum && value > limit:
+		check.Status = StatusFail
+	}
+
+	r.Checks = append(r.Checks, check)
+}
+
+func analyzeStick(rest, motion []gods4.State, stick func(s *gods4.State) gods4.Stick) StickReport {
+	var report StickReport
+
+	if len(rest) > 0 {
+		xs := make([]float64, len(rest))
+		ys := make([]float64, len(rest))
+
+		for i := range rest {
+			xs[i], ys[i] = position(stick(&rest[i]))
+		}
+
+		report.CenterX, report.NoiseX = meanDeviation(xs)
+		report.CenterY, report.NoiseY = meanDeviation(ys)
+		report.Offset = math.Hypot(report.CenterX, report.CenterY)
+	}
+
+	var radii [sectors]float64
+
+	for i := range motion {
+		x, y := position(stick(&motion[i]))
+
+		radius := math.Hypot(x, y)
+		if radius < minEdgeRadius {
+			continue
+		}
+
+		angle := math.Atan2(y, x) + math.Pi
+		sector := int(angle/(2*math.Pi)*sectors) % sectors
+		radii[sector] = math.Max(radii[sector], radius)
+	}
+
+	var reached int
+
+	for _, radius := range radii {
+		if radius > 0 {
+			reached++
+		}
+
+		// A square gate reaches further than 1 in the corners
+		report.Circularity += math.Min(radius, 1) / sectors
+	}
+
+	report.Coverage = float64(reached) / sectors
+
+	return report
+}
+
+func analyzeTrigger(rest, motion []gods4.State, value func(s *gods4.State) byte) TriggerReport {
+	report := TriggerReport{Min: math.MaxUint8}
+
+	var hasSamples bool
+
+	for _, states := range [][]gods4.State{rest, motion} {
+		for i := range states {
+			v := value(&states[i])
+			hasSamples = true
+
+			if v < report.Min {
+				report.Min = v
+			}
+
+			if v > report.Max {
+				report.Max = v
+			}
+		}
+	}
+
+	if !hasSamples {
+		return TriggerReport{}
+	}
+
+	return report
+}
+
+func analyzeGyroscope(rest []gods4.State) GyroscopeReport {
+	if len(rest) == 0 {
+		return GyroscopeReport{}
+	}
+
+	var report GyroscopeReport
+
+	for i := range rest {
+		report.BiasRoll += float64(rest[i].Gyroscope.RawRoll)
+		report.BiasYaw += float64(rest[i].Gyroscope.RawYaw)
+		report.BiasPitch += float64(rest[i].Gyroscope.RawPitch)
+	}
+
+	n := float64(len(rest))
+	report.BiasRoll /= n
+	report.BiasYaw /= n
+	report.BiasPitch /= n
+
+	return report
+}
+
+// counterMask returns the mask counter gaps are taken with,
+// the DualShock 4 counter is 6 bits wide and the DualSense one 8.
+func counterMask(model gods4.Model) byte {
+	if model == gods4.ModelDualSense {
+		return 0xFF
+	}
+
+	return 0x3F
+}
+
+func droppedReports(states []gods4.State, mask byte) int {
+	var dropped int
+
+	for i := 1; i < len(states); i++ {
+		gap := (states[i].Counter - states[i-1].Counter - 1) & mask
+		dropped += int(gap)
+	}
+
+	return dropped
+}
+
+func countChatter(states []gods4.State, interval time.Duration, chatter map[gods4.Button]int) {
+	if len(states) == 0 {
+		return
+	}
+
+	for _, button := range gods4.Buttons() {
+		isPressed := states[0].IsPressed(button)
+		changedAt := states[0].Timestamp
+		hasChanged := false
+
+		for i := 1; i < len(states); i++ {
+			if states[i].IsPressed(button) == isPressed {
+				continue
+			}
+
+			// A press or a release shorter than the interval is a bounce, not a human
+			if hasChanged && states[i].Timestamp-changedAt < interval {
+				chatter[button]++
+			}
+
+			isPressed = !isPressed
+			changedAt = states[i].Timestamp
+			hasChanged = true
+		}
+	}
+}
+
+// position maps raw stick values to [-1, 1] around the center, Y grows downwards.
+func position(stick gods4.Stick) (float64, float64) {
+	normalize := func(v byte) float64 {
+		return math.Max(-1, (float64(v)-128)/127)
+	}
+
+	return normalize(stick.X), normalize(stick.Y)
+}
+
+func meanDeviation(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
@@ -0,0 +1,110 @@
+package diagnostics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kpeu3i/gods4"
+)
+
+// counted returns states with the counters, intervals apart in turn.
+func counted(counters []byte, intervals ...time.Duration) []gods4.State {
+	states := make([]gods4.State, len(counters))
+
+	var now time.Duration
+	for i, counter := range counters {
+		if i > 0 {
+			now += intervals[(i-1)%len(intervals)]
+		}
+
+		states[i] = gods4.State{Timestamp: now, Counter: counter}
+	}
+
+	return states
+}
+
+func TestDroppedReports(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    gods4.Model
+		counters []byte
+		want     int
+	}{
+		{"DualShock 4 in order", gods4.ModelDualShock4, []byte{10, 11, 12, 13}, 0},
+		{"DualShock 4 wraparound", gods4.ModelDualShock4, []byte{62, 63, 0, 1}, 0},
+		{"DualShock 4 drops", gods4.ModelDualShock4, []byte{10, 12, 20}, 8},
+		{"DualShock 4 drops over wraparound", gods4.ModelDualShock4, []byte{60, 2}, 5},
+		{"DualSense in order", gods4.ModelDualSense, []byte{62, 63, 64, 65}, 0},
+		{"DualSense wraparound", gods4.ModelDualSense, []byte{254, 255, 0, 1}, 0},
+		{"DualSense drops", gods4.ModelDualSense, []byte{10, 12, 90}, 78},
+		{"DualSense drops over wraparound", gods4.ModelDualSense, []byte{250, 2}, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := counted(tt.counters, 4*time.Millisecond)
+
+			r := Analyze(tt.model, states, nil, DefaultThresholds)
+			if r.DroppedReports != tt.want {
+				t.Errorf("got %d dropped reports, want %d", r.DroppedReports, tt.want)
+			}
+
+			if r.Reports != len(states) {
+				t.Errorf("got %d reports, want %d", r.Reports, len(states))
+			}
+
+			check := findCheck(r, "dropped_reports")
+			if tt.want == 0 && check.Status != StatusPass || tt.want > 0 && check.Status != StatusFail {
+				t.Errorf("got %s dropped_reports check with %v dropped", check.Status, check.Value)
+			}
+		})
+	}
+}
+
+func TestDroppedReportsPerPhase(t *testing.T) {
+	// The reports between the phases are not sampled
+	rest := counted([]byte{10, 11}, 4*time.Millisecond)
+	motion := counted([]byte{40, 41, 43}, 4*time.Millisecond)
+
+	r := Analyze(gods4.ModelDualSense, rest, motion, DefaultThresholds)
+	if r.DroppedReports != 1 {
+		t.Errorf("got %d dropped reports, want 1", r.DroppedReports)
+	}
+}
+
+func TestReportJitter(t *testing.T) {
+	const ms = time.Millisecond
+
+	for _, model := range []gods4.Model{gods4.ModelDualShock4, gods4.ModelDualSense} {
+		t.Run(model.String(), func(t *testing.T) {
+			steady := Analyze(model, counted([]byte{0, 1, 2, 3, 4}, 4*ms), nil, DefaultThresholds)
+			if steady.ReportInterval != 4*ms || steady.ReportJitter != 0 {
+				t.Errorf("got interval %s and jitter %s, want 4ms and 0s", steady.ReportInterval, steady.ReportJitter)
+			}
+
+			uneven := Analyze(model, counted([]byte{0, 1, 2, 3, 4}, 3*ms, 5*ms), nil, DefaultThresholds)
+			if uneven.ReportInterval != 4*ms || uneven.ReportJitter != ms {
+				t.Errorf("got interval %s and jitter %s, want 4ms and 1ms", uneven.ReportInterval, uneven.ReportJitter)
+			}
+
+			single := Analyze(model, counted([]byte{0}), nil, DefaultThresholds)
+			if single.ReportInterval != 0 || single.ReportJitter != 0 {
+				t.Errorf("got interval %s and jitter %s from a single report", single.ReportInterval, single.ReportJitter)
+			}
+
+			if findCheck(single, "dropped_reports").Status != StatusSkipped {
+				t.Error("dropped_reports checked with a single report")
+			}
+		})
+	}
+}
+
+func findCheck(r Report, name string) Check {
+	for _, check := range r.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+
+	return Check{}
+}
@@ -0,0 +1,121 @@
+// Package diagnostics measures the health of a controller from decoded input state
+// and produces a pass/fail report, e.g. to check refurbished controllers for stick drift.
+//
+// Samples are taken in two phases. In the rest phase the controller lies still with
+// sticks and triggers released, it is used for stick center offset and noise and for
+// gyroscope bias. In the motion phase both sticks are rotated along their edges and
+// both triggers are pulled fully, it is used for range of motion and trigger travel.
+// Dropped reports and button chatter are counted in both phases.
+package diagnostics
+
+import (
+	"sync"
+
+	"github.com/kpeu3i/gods4"
+)
+
+type Phase int
+
+const (
+	PhaseNone Phase = iota
+	PhaseRest
+	PhaseMotion
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseRest:
+		return "rest"
+	case PhaseMotion:
+		return "motion"
+	default:
+		return "none"
+	}
+}
+
+// Sampler collects states of the current phase, states are ignored in PhaseNone.
+// Gaps in the report counter are counted as dropped reports of the sampler's model.
+type Sampler struct {
+	mutex  sync.Mutex
+	detach func()
+	model  gods4.Model
+	phase  Phase
+	rest   []gods4.State
+	motion []gods4.State
+}
+
+func NewSampler(model gods4.Model) *Sampler {
+	return &Sampler{model: model}
+}
+
+// Attach samples every report of a live session as decoded, before profiles remap
+// it or turbo pulses buttons. Event callbacks are left alone.
+func (s *Sampler) Attach(controller *gods4.Controller) {
+	detach := controller.OnRawState(s.Add)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.detach != nil {
+		s.detach()
+	}
+
+	s.detach = detach
+}
+
+// Detach stops sampling the attached controller.
+func (s *Sampler) Detach() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.detach != nil {
+		s.detach()
+		s.detach = nil
+	}
+}
+
+func (s *Sampler) SetPhase(phase Phase) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.phase = phase
+}
+
+func (s *Sampler) Phase() Phase {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.phase
+}
+
+func (s *Sampler) Add(state gods4.State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch s.phase {
+	case PhaseRest:
+		s.rest = append(s.rest, state)
+	case PhaseMotion:
+		s.motion = append(s.motion, state)
+	}
+}
+
+// Reset drops all samples and returns to PhaseNone.
+func (s *Sampler) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.phase = PhaseNone
+	s.rest = nil
+	s.motion = nil
+}
+
+func (s *Sampler) Report(thresholds Thresholds) Report {
+	s.mutex.Lock()
+	rest := append([]gods4.State(nil), s.rest...)
+	motion := append([]gods4.State(nil), s.motion...)
+	model := s.model
+	s.mutex.Unlock()
+
+	return Analyze(model, rest, motion, thresholds)
+}
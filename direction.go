@@ -0,0 +1,136 @@
+package gods4
+
+import (
+	"math"
+)
+
+type Direction uint
+
+const (
+	DirectionNone Direction = iota
+	DirectionUp
+	DirectionUpRight
+	DirectionRight
+	DirectionDownRight
+	DirectionDown
+	DirectionDownLeft
+	DirectionLeft
+	DirectionUpLeft
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionNone:
+		return "none"
+	case DirectionUp:
+		return "up"
+	case DirectionUpRight:
+		return "up_right"
+	case DirectionRight:
+		return "right"
+	case DirectionDownRight:
+		return "down_right"
+	case DirectionDown:
+		return "down"
+	case DirectionDownLeft:
+		return "down_left"
+	case DirectionLeft:
+		return "left"
+	case DirectionUpLeft:
+		return "up_left"
+	default:
+		return ""
+	}
+}
+
+func (d Direction) IsUp() bool {
+	return d == DirectionUp || d == DirectionUpRight || d == DirectionUpLeft
+}
+
+func (d Direction) IsDown() bool {
+	return d == DirectionDown || d == DirectionDownRight || d == DirectionDownLeft
+}
+
+func (d Direction) IsLeft() bool {
+	return d == DirectionLeft || d == DirectionUpLeft || d == DirectionDownLeft
+}
+
+func (d Direction) IsRight() bool {
+	return d == DirectionRight || d == DirectionUpRight || d == DirectionDownRight
+}
+
+// Angle returns the direction angle in degrees clockwise from up.
+func (d Direction) Angle() float64 {
+	if d == DirectionNone || d > DirectionUpLeft {
+		return 0
+	}
+
+	return float64(d-DirectionUp) * 45
+}
+
+// directionOf combines D-pad buttons into a direction, opposite buttons cancel each other.
+func directionOf(up, down, left, right bool) Direction {
+	if up && down {
+		up, down = false, false
+	}
+
+	if left && right {
+		left, right = false, false
+	}
+
+	switch {
+	case up && right:
+		return DirectionUpRight
+	case up && left:
+		return DirectionUpLeft
+	case down && right:
+		return DirectionDownRight
+	case down && left:
+		return DirectionDownLeft
+	case up:
+		return DirectionUp
+	case down:
+		return DirectionDown
+	case left:
+		return DirectionLeft
+	case right:
+		return DirectionRight
+	default:
+		return DirectionNone
+	}
+}
+
+// stickDirection quantizes stick position into 4 or 8 directions. Once a direction
+// is engaged, it is kept until the stick leaves its sector by more than the angular
+// hysteresis or its magnitude drops below the threshold minus the hysteresis.
+func stickDirection(stick Stick, prevDirection Direction, sectors int, config StickConfig) Direction {
+	config = config.withDirectionDefaults()
+
+	threshold := config.DirectionThreshold
+	if prevDirection != DirectionNone {
+		threshold -= config.DirectionHysteresis
+	}
+
+	if stick.Magnitude() < threshold || stick.Magnitude() == 0 {
+		return DirectionNone
+	}
+
+	angle := stick.Angle()
+	width := 360 / float64(sectors)
+
+	if prevDirection != DirectionNone && (sectors == 8 || int(prevDirection-DirectionUp)%2 == 0) {
+		if angleDistance(angle, prevDirection.Angle()) <= width/2+config.DirectionAngleHysteresis {
+			return prevDirection
+		}
+	}
+
+	sector := int(math.Floor((angle+width/2)/width)) % sectors
+
+	return DirectionUp + Direction(sector*8/sectors)
+}
+
+func angleDistance(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+
+	return math.Min(d, 360-d)
+}
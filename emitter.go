@@ -2,12 +2,19 @@ package gods4
 
 import (
 	"sync"
+	"time"
 )
 
 type emitter struct {
-	mutex     sync.RWMutex
-	callbacks map[Event]Callback
-	checkers  []func(currState, prevState *state) error
+	mutex        sync.RWMutex
+	callbacks    map[Event]Callback
+	checkers     []func(currState, prevState *state) error
+	timing       TimingConfig
+	timers       [len(buttons)]buttonTimer
+	combos       *combos
+	macros       *macros
+	rawListeners []rawListener
+	nextRawID    int
 }
 
 func (e *emitter) emit(currState, prevState *state) error {
@@ -21,6 +28,87 @@ func (e *emitter) emit(currState, prevState *state) error {
 	return nil
 }
 
+// emitOutput calls the output callback. It runs on the caller goroutine and,
+// as output is not input, is not seen by combos and macros.
+func (e *emitter) emitOutput(output Output) error {
+	if callback, ok := e.callback(EventOutputUpdate); ok {
+		return callback(output)
+	}
+
+	return nil
+}
+
+// fire dispatches an input event, recording it if a macro is being recorded.
+// Now is the timestamp of the report that caused the event.
+func (e *emitter) fire(now time.Duration, event Event, data interface{}) error {
+	e.macros.record(event, data, now)
+
+	return e.dispatch(now, event, data)
+}
+
+// dispatch calls the callback registered for event and fires combos completed by it.
+func (e *emitter) dispatch(now time.Duration, event Event, data interface{}) error {
+	if callback, ok := e.callback(event); ok {
+		err := callback(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, combo := range e.combos.observe(event, now) {
+		err := e.dispatch(now, combo, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rawListener receives decoded reports before remapping.
+type rawListener struct {
+	id int
+	fn func(State)
+}
+
+func (e *emitter) addRawListener(fn func(State)) func() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	id := e.nextRawID
+	e.nextRawID++
+	e.rawListeners = append(e.rawListeners, rawListener{id: id, fn: fn})
+
+	return func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		listeners := make([]rawListener, 0, len(e.rawListeners))
+		for _, listener := range e.rawListeners {
+			if listener.id != id {
+				listeners = append(listeners, listener)
+			}
+		}
+		e.rawListeners = listeners
+	}
+}
+
+// emitRaw passes a decoded report to the raw listeners, the snapshot is only built if there are any.
+func (e *emitter) emitRaw(raw *state) {
+	e.mutex.RLock()
+	listeners := e.rawListeners
+	e.mutex.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+
+	snapshot := raw.snapshot()
+	for _, listener := range listeners {
+		listener.fn(snapshot)
+	}
+}
+
 func (e *emitter) callback(event Event) (Callback, bool) {
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
@@ -32,6 +120,20 @@ func (e *emitter) callback(event Event) (Callback, bool) {
 	return nil, false
 }
 
+func (e *emitter) currentTiming() TimingConfig {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.timing
+}
+
+func (e *emitter) setTiming(timing TimingConfig) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.timing = timing
+}
+
 func (e *emitter) setCallback(event Event, fn Callback) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
@@ -50,12 +152,51 @@ func (e *emitter) checkBattery(currState, prevState *state) error {
 	if currState.battery.Capacity != prevState.battery.Capacity ||
 		currState.battery.IsCharging != prevState.battery.IsCharging ||
 		currState.battery.IsCableConnected != prevState.battery.IsCableConnected {
-		event := EventBatteryUpdate
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.battery)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventBatteryUpdate, currState.battery)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkState fires on every report, the snapshot is only built if there is a callback.
+func (e *emitter) checkState(currState, prevState *state) error {
+	if _, ok := e.callback(EventStateUpdate); !ok {
+		return nil
+	}
+
+	return e.dispatch(currState.timestamp, EventStateUpdate, currState.snapshot())
+}
+
+// checkHeadset fires one event per change. A change of type while something stays
+// plugged, like headphones replaced by a headset, is a plug of the new type.
+func (e *emitter) checkHeadset(currState, prevState *state) error {
+	if currState.headset == prevState.headset {
+		return nil
+	}
+
+	event := EventHeadsetPlug
+	if currState.headset == (Headset{}) {
+		event = EventHeadsetUnplug
+	}
+
+	return e.fire(currState.timestamp, event, currState.headset)
+}
+
+func (e *emitter) checkExtension(currState, prevState *state) error {
+	if currState.extension && !prevState.extension {
+		err := e.fire(currState.timestamp, EventExtensionPlug, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !currState.extension && prevState.extension {
+		err := e.fire(currState.timestamp, EventExtensionUnplug, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -79,32 +220,23 @@ func (e *emitter) checkTouchpad(currState, prevState *state) error {
 	}
 
 	if isSwipeChanged {
-		event := EventTouchpadSwipe
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.touchpad)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventTouchpadSwipe, currState.touchpad)
+		if err != nil {
+			return err
 		}
 	}
 
 	if currState.touchpad.Press && !prevState.touchpad.Press {
-		event := EventTouchpadPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.touchpad)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventTouchpadPress, currState.touchpad)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.touchpad.Press && prevState.touchpad.Press {
-		event := EventTouchpadRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.touchpad)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventTouchpadRelease, currState.touchpad)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -115,12 +247,9 @@ func (e *emitter) checkAccelerometer(currState, prevState *state) error {
 	if currState.accelerometer.X != prevState.accelerometer.X ||
 		currState.accelerometer.Y != prevState.accelerometer.Y ||
 		currState.accelerometer.Z != prevState.accelerometer.Z {
-		event := EventAccelerometerUpdate
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.accelerometer)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventAccelerometerUpdate, currState.accelerometer)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -131,12 +260,9 @@ func (e *emitter) checkGyroscope(currState, prevState *state) error {
 	if currState.gyroscope.Roll != prevState.gyroscope.Roll ||
 		currState.gyroscope.Yaw != prevState.gyroscope.Yaw ||
 		currState.gyroscope.Pitch != prevState.gyroscope.Pitch {
-		event := EventGyroscopeUpdate
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.gyroscope)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventGyroscopeUpdate, currState.gyroscope)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -144,14 +270,11 @@ func (e *emitter) checkGyroscope(currState, prevState *state) error {
 }
 
 func (e *emitter) checkRightStick(currState, prevState *state) error {
-	if currState.rightStick.X != prevState.rightStick.X ||
-		currState.rightStick.Y != prevState.rightStick.Y {
-		event := EventRightStickMove
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.rightStick)
-			if err != nil {
-				return err
-			}
+	if currState.rightStick.AxisX != prevState.rightStick.AxisX ||
+		currState.rightStick.AxisY != prevState.rightStick.AxisY {
+		err := e.fire(currState.timestamp, EventRightStickMove, currState.rightStick)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -159,14 +282,83 @@ func (e *emitter) checkRightStick(currState, prevState *state) error {
 }
 
 func (e *emitter) checkLeftStick(currState, prevState *state) error {
-	if currState.leftStick.X != prevState.leftStick.X ||
-		currState.leftStick.Y != prevState.leftStick.Y {
-		event := EventLeftStickMove
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.leftStick)
-			if err != nil {
-				return err
-			}
+	if currState.leftStick.AxisX != prevState.leftStick.AxisX ||
+		currState.leftStick.AxisY != prevState.leftStick.AxisY {
+		err := e.fire(currState.timestamp, EventLeftStickMove, currState.leftStick)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *emitter) checkLeftStickDirection(currState, prevState *state) error {
+	events := directionEvents{
+		upPress:      EventLeftStickUpPress,
+		upRelease:    EventLeftStickUpRelease,
+		downPress:    EventLeftStickDownPress,
+		downRelease:  EventLeftStickDownRelease,
+		leftPress:    EventLeftStickLeftPress,
+		leftRelease:  EventLeftStickLeftRelease,
+		rightPress:   EventLeftStickRightPress,
+		rightRelease: EventLeftStickRightRelease,
+	}
+
+	return e.checkDirection(currState.timestamp, currState.leftStick.Direction8, prevState.leftStick.Direction8, events)
+}
+
+func (e *emitter) checkRightStickDirection(currState, prevState *state) error {
+	events := directionEvents{
+		upPress:      EventRightStickUpPress,
+		upRelease:    EventRightStickUpRelease,
+		downPress:    EventRightStickDownPress,
+		downRelease:  EventRightStickDownRelease,
+		leftPress:    EventRightStickLeftPress,
+		leftRelease:  EventRightStickLeftRelease,
+		rightPress:   EventRightStickRightPress,
+		rightRelease: EventRightStickRightRelease,
+	}
+
+	return e.checkDirection(currState.timestamp, currState.rightStick.Direction8, prevState.rightStick.Direction8, events)
+}
+
+type directionEvents struct {
+	upPress      Event
+	upRelease    Event
+	downPress    Event
+	downRelease  Event
+	leftPress    Event
+	leftRelease  Event
+	rightPress   Event
+	rightRelease Event
+}
+
+// checkDirection emits all releases before presses, so during a diagonal
+// transition handlers never see both opposite directions pressed.
+func (e *emitter) checkDirection(now time.Duration, currDirection, prevDirection Direction, events directionEvents) error {
+	transitions := []struct {
+		event Event
+		fire  bool
+	}{
+		{events.upRelease, !currDirection.IsUp() && prevDirection.IsUp()},
+		{events.downRelease, !currDirection.IsDown() && prevDirection.IsDown()},
+		{events.leftRelease, !currDirection.IsLeft() && prevDirection.IsLeft()},
+		{events.rightRelease, !currDirection.IsRight() && prevDirection.IsRight()},
+		{events.upPress, currDirection.IsUp() && !prevDirection.IsUp()},
+		{events.downPress, currDirection.IsDown() && !prevDirection.IsDown()},
+		{events.leftPress, currDirection.IsLeft() && !prevDirection.IsLeft()},
+		{events.rightPress, currDirection.IsRight() && !prevDirection.IsRight()},
+	}
+
+	for _, transition := range transitions {
+		if !transition.fire {
+			continue
+		}
+
+		err := e.fire(now, transition.event, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -175,22 +367,16 @@ func (e *emitter) checkLeftStick(currState, prevState *state) error {
 
 func (e *emitter) checkPS(currState, prevState *state) error {
 	if currState.ps && !prevState.ps {
-		event := EventPSPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventPSPress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.ps && prevState.ps {
-		event := EventPSRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventPSRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -199,22 +385,16 @@ func (e *emitter) checkPS(currState, prevState *state) error {
 
 func (e *emitter) checkOptions(currState, prevState *state) error {
 	if currState.options && !prevState.options {
-		event := EventOptionsPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventOptionsPress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.options && prevState.options {
-		event := EventOptionsRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventOptionsRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -223,22 +403,16 @@ func (e *emitter) checkOptions(currState, prevState *state) error {
 
 func (e *emitter) checkShare(currState, prevState *state) error {
 	if currState.share && !prevState.share {
-		event := EventSharePress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventSharePress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.share && prevState.share {
-		event := EventShareRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventShareRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -246,87 +420,26 @@ func (e *emitter) checkShare(currState, prevState *state) error {
 }
 
 func (e *emitter) checkDPad(currState, prevState *state) error {
-	// D-pad up
-	if currState.dPadUp && !prevState.dPadUp {
-		event := EventDPadUpPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if !currState.dPadUp && prevState.dPadUp {
-		event := EventDPadUpRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// D-pad down
-	if currState.dPadDown && !prevState.dPadDown {
-		event := EventDPadDownPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if !currState.dPadDown && prevState.dPadDown {
-		event := EventDPadDownRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// D-pad left
-	if currState.dPadLeft && !prevState.dPadLeft {
-		event := EventDPadLeftPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
+	events := directionEvents{
+		upPress:      EventDPadUpPress,
+		upRelease:    EventDPadUpRelease,
+		downPress:    EventDPadDownPress,
+		downRelease:  EventDPadDownRelease,
+		leftPress:    EventDPadLeftPress,
+		leftRelease:  EventDPadLeftRelease,
+		rightPress:   EventDPadRightPress,
+		rightRelease: EventDPadRightRelease,
 	}
 
-	if !currState.dPadLeft && prevState.dPadLeft {
-		event := EventDPadLeftRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// D-pad right
-	if currState.dPadRight && !prevState.dPadRight {
-		event := EventDPadRightPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
-		}
+	err := e.checkDirection(currState.timestamp, currState.dPad, prevState.dPad, events)
+	if err != nil {
+		return err
 	}
 
-	if !currState.dPadRight && prevState.dPadRight {
-		event := EventDPadRightRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+	if currState.dPad != prevState.dPad {
+		err := e.fire(currState.timestamp, EventDPadChange, DPadChange{Old: prevState.dPad, New: currState.dPad})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -335,22 +448,16 @@ func (e *emitter) checkDPad(currState, prevState *state) error {
 
 func (e *emitter) checkR3(currState, prevState *state) error {
 	if currState.r3 && !prevState.r3 {
-		event := EventR3Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventR3Press, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.r3 && prevState.r3 {
-		event := EventR3Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventR3Release, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -358,23 +465,31 @@ func (e *emitter) checkR3(currState, prevState *state) error {
 }
 
 func (e *emitter) checkR2(currState, prevState *state) error {
-	if currState.r2 != prevState.r2 {
-		event := EventR2Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.r2)
-			if err != nil {
-				return err
-			}
+	if currState.r2.Value != prevState.r2.Value {
+		err := e.fire(currState.timestamp, EventR2Move, currState.r2)
+		if err != nil {
+			return err
 		}
 	}
 
-	if currState.r2 == 0 && prevState.r2 != 0 {
-		event := EventR2Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.r2)
-			if err != nil {
-				return err
-			}
+	if currState.r2.IsPressed && !prevState.r2.IsPressed {
+		err := e.fire(currState.timestamp, EventR2Press, currState.r2.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if currState.r2.IsFullPull && !prevState.r2.IsFullPull {
+		err := e.fire(currState.timestamp, EventR2FullPull, currState.r2)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !currState.r2.IsPressed && prevState.r2.IsPressed {
+		err := e.fire(currState.timestamp, EventR2Release, currState.r2.Value)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -383,22 +498,16 @@ func (e *emitter) checkR2(currState, prevState *state) error {
 
 func (e *emitter) checkR1(currState, prevState *state) error {
 	if currState.r1 && !prevState.r1 {
-		event := EventR1Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventR1Press, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.r1 && prevState.r1 {
-		event := EventR1Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventR1Release, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -407,22 +516,16 @@ func (e *emitter) checkR1(currState, prevState *state) error {
 
 func (e *emitter) checkL3(currState, prevState *state) error {
 	if currState.l3 && !prevState.l3 {
-		event := EventL3Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventL3Press, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.l3 && prevState.l3 {
-		event := EventL3Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventL3Release, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -430,23 +533,31 @@ func (e *emitter) checkL3(currState, prevState *state) error {
 }
 
 func (e *emitter) checkL2(currState, prevState *state) error {
-	if currState.l2 != prevState.l2 {
-		event := EventL2Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.l2)
-			if err != nil {
-				return err
-			}
+	if currState.l2.Value != prevState.l2.Value {
+		err := e.fire(currState.timestamp, EventL2Move, currState.l2)
+		if err != nil {
+			return err
 		}
 	}
 
-	if currState.l2 == 0 && prevState.l2 != 0 {
-		event := EventL2Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(currState.l2)
-			if err != nil {
-				return err
-			}
+	if currState.l2.IsPressed && !prevState.l2.IsPressed {
+		err := e.fire(currState.timestamp, EventL2Press, currState.l2.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if currState.l2.IsFullPull && !prevState.l2.IsFullPull {
+		err := e.fire(currState.timestamp, EventL2FullPull, currState.l2)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !currState.l2.IsPressed && prevState.l2.IsPressed {
+		err := e.fire(currState.timestamp, EventL2Release, currState.l2.Value)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -455,22 +566,16 @@ func (e *emitter) checkL2(currState, prevState *state) error {
 
 func (e *emitter) checkL1(currState, prevState *state) error {
 	if currState.l1 && !prevState.l1 {
-		event := EventL1Press
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventL1Press, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.l1 && prevState.l1 {
-		event := EventL1Release
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventL1Release, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -479,22 +584,16 @@ func (e *emitter) checkL1(currState, prevState *state) error {
 
 func (e *emitter) checkTriangle(currState, prevState *state) error {
 	if currState.triangle && !prevState.triangle {
-		event := EventTrianglePress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventTrianglePress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.triangle && prevState.triangle {
-		event := EventTriangleRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventTriangleRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -503,22 +602,16 @@ func (e *emitter) checkTriangle(currState, prevState *state) error {
 
 func (e *emitter) checkSquare(currState, prevState *state) error {
 	if currState.square && !prevState.square {
-		event := EventSquarePress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventSquarePress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.square && prevState.square {
-		event := EventSquareRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventSquareRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -527,22 +620,16 @@ func (e *emitter) checkSquare(currState, prevState *state) error {
 
 func (e *emitter) checkCircle(currState, prevState *state) error {
 	if currState.circle && !prevState.circle {
-		event := EventCirclePress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventCirclePress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.circle && prevState.circle {
-		event := EventCircleRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventCircleRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -551,22 +638,16 @@ func (e *emitter) checkCircle(currState, prevState *state) error {
 
 func (e *emitter) checkCross(currState, prevState *state) error {
 	if currState.cross && !prevState.cross {
-		event := EventCrossPress
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventCrossPress, nil)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !currState.cross && prevState.cross {
-		event := EventCrossRelease
-		if callback, ok := e.callback(event); ok {
-			err := callback(nil)
-			if err != nil {
-				return err
-			}
+		err := e.fire(currState.timestamp, EventCrossRelease, nil)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -574,8 +655,14 @@ func (e *emitter) checkCross(currState, prevState *state) error {
 }
 
 func newEmitter() *emitter {
-	e := &emitter{callbacks: make(map[Event]Callback)}
+	e := &emitter{
+		callbacks: make(map[Event]Callback),
+		timing:    DefaultTimingConfig(),
+		combos:    newCombos(),
+		macros:    newMacros(),
+	}
 	e.checkers = []func(currState, prevState *state) error{
+		e.checkState,
 		e.checkCross,
 		e.checkCircle,
 		e.checkSquare,
@@ -592,10 +679,16 @@ func newEmitter() *emitter {
 		e.checkPS,
 		e.checkLeftStick,
 		e.checkRightStick,
+		e.checkLeftStickDirection,
+		e.checkRightStickDirection,
+		e.checkTiming,
+		e.checkMacros,
 		e.checkTouchpad,
 		e.checkAccelerometer,
 		e.checkGyroscope,
 		e.checkBattery,
+		e.checkHeadset,
+		e.checkExtension,
 	}
 
 	return e
@@ -0,0 +1,133 @@
+package gods4
+
+import (
+	"testing"
+	"time"
+)
+
+// eventLog records events in the order they are dispatched.
+type eventLog struct {
+	events []Event
+	data   []interface{}
+}
+
+func (l *eventLog) listen(e *emitter, events ...Event) {
+	for _, event := range events {
+		event := event
+		e.setCallback(event, func(data interface{}) error {
+			l.events = append(l.events, event)
+			l.data = append(l.data, data)
+
+			return nil
+		})
+	}
+}
+
+func TestHeadsetEvents(t *testing.T) {
+	var (
+		nothing    = Headset{}
+		headphones = Headset{IsHeadphonesConnected: true}
+		headset    = Headset{IsHeadphonesConnected: true, IsMicConnected: true}
+	)
+
+	steps := []struct {
+		headset Headset
+		event   Event
+	}{
+		{headphones, EventHeadsetPlug},
+		{headphones, ""},
+		// Replaced by a headset between two reports
+		{headset, EventHeadsetPlug},
+		{headphones, EventHeadsetPlug},
+		{nothing, EventHeadsetUnplug},
+	}
+
+	e := newEmitter()
+	log := &eventLog{}
+	log.listen(e, EventHeadsetPlug, EventHeadsetUnplug)
+
+	prevState := &state{}
+	for i, step := range steps {
+		currState := &state{timestamp: time.Duration(i+1) * time.Millisecond, headset: step.headset}
+
+		err := e.emit(currState, prevState)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []Event
+		if step.event != "" {
+			want = []Event{step.event}
+		}
+
+		if len(log.events) != len(want) || len(want) == 1 && (log.events[0] != want[0] || log.data[0] != step.headset) {
+			t.Fatalf("step %d: got %v with %v, want %v with %+v", i, log.events, log.data, want, step.headset)
+		}
+
+		log.events, log.data = nil, nil
+		prevState = currState
+	}
+}
+
+func TestDPadEvents(t *testing.T) {
+	tests := []struct {
+		prev, curr DPad
+		want       []Event
+	}{
+		{DirectionNone, DirectionUp, []Event{EventDPadUpPress}},
+		{DirectionUp, DirectionUpRight, []Event{EventDPadRightPress}},
+		{DirectionUpRight, DirectionRight, []Event{EventDPadUpRelease}},
+		{DirectionRight, DirectionNone, []Event{EventDPadRightRelease}},
+		// Releases come first, so opposite directions are never held together
+		{DirectionUp, DirectionDown, []Event{EventDPadUpRelease, EventDPadDownPress}},
+		{DirectionUpRight, DirectionDownLeft, []Event{EventDPadUpRelease, EventDPadRightRelease, EventDPadDownPress, EventDPadLeftPress}},
+		{DirectionDownRight, DirectionUpLeft, []Event{EventDPadDownRelease, EventDPadRightRelease, EventDPadUpPress, EventDPadLeftPress}},
+		{DirectionLeft, DirectionLeft, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prev.String()+"_to_"+tt.curr.String(), func(t *testing.T) {
+			e := newEmitter()
+			log := &eventLog{}
+			log.listen(e,
+				EventDPadUpPress, EventDPadUpRelease, EventDPadDownPress, EventDPadDownRelease,
+				EventDPadLeftPress, EventDPadLeftRelease, EventDPadRightPress, EventDPadRightRelease,
+				EventDPadChange,
+			)
+
+			err := e.emit(&state{timestamp: time.Millisecond, dPad: tt.curr}, &state{dPad: tt.prev})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := tt.want
+			if tt.prev != tt.curr {
+				want = append(want, EventDPadChange)
+			}
+
+			if !equalEvents(log.events, want) {
+				t.Fatalf("got %v, want %v", log.events, want)
+			}
+
+			if tt.prev != tt.curr {
+				if change := log.data[len(log.data)-1].(DPadChange); change.Old != tt.prev || change.New != tt.curr {
+					t.Errorf("got change %+v", change)
+				}
+			}
+		})
+	}
+}
+
+func equalEvents(a, b []Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,84 +4,201 @@ type Event string
 
 const (
 	// Cross
-	EventCrossPress   Event = "cross.press"
-	EventCrossRelease Event = "cross.release"
+	EventCrossPress     Event = "cross.press"
+	EventCrossRelease   Event = "cross.release"
+	EventCrossHold      Event = "cross.hold"
+	EventCrossLongPress Event = "cross.long_press"
+	EventCrossClick     Event = "cross.click"
+	EventCrossDoubleTap Event = "cross.double_tap"
+	EventCrossRepeat    Event = "cross.repeat"
 
 	// Circle
-	EventCirclePress   Event = "circle.press"
-	EventCircleRelease Event = "circle.release"
+	EventCirclePress     Event = "circle.press"
+	EventCircleRelease   Event = "circle.release"
+	EventCircleHold      Event = "circle.hold"
+	EventCircleLongPress Event = "circle.long_press"
+	EventCircleClick     Event = "circle.click"
+	EventCircleDoubleTap Event = "circle.double_tap"
+	EventCircleRepeat    Event = "circle.repeat"
 
 	// Square
-	EventSquarePress   Event = "square.press"
-	EventSquareRelease Event = "square.release"
+	EventSquarePress     Event = "square.press"
+	EventSquareRelease   Event = "square.release"
+	EventSquareHold      Event = "square.hold"
+	EventSquareLongPress Event = "square.long_press"
+	EventSquareClick     Event = "square.click"
+	EventSquareDoubleTap Event = "square.double_tap"
+	EventSquareRepeat    Event = "square.repeat"
 
 	// Triangle
-	EventTrianglePress   Event = "triangle.press"
-	EventTriangleRelease Event = "triangle.release"
+	EventTrianglePress     Event = "triangle.press"
+	EventTriangleRelease   Event = "triangle.release"
+	EventTriangleHold      Event = "triangle.hold"
+	EventTriangleLongPress Event = "triangle.long_press"
+	EventTriangleClick     Event = "triangle.click"
+	EventTriangleDoubleTap Event = "triangle.double_tap"
+	EventTriangleRepeat    Event = "triangle.repeat"
 
 	// L1
-	EventL1Press   Event = "l1.press"
-	EventL1Release Event = "l1.release"
+	EventL1Press     Event = "l1.press"
+	EventL1Release   Event = "l1.release"
+	EventL1Hold      Event = "l1.hold"
+	EventL1LongPress Event = "l1.long_press"
+	EventL1Click     Event = "l1.click"
+	EventL1DoubleTap Event = "l1.double_tap"
+	EventL1Repeat    Event = "l1.repeat"
 
 	// L2
-	EventL2Press   Event = "l2.press"
-	EventL2Release Event = "l2.release"
+	EventL2Press     Event = "l2.press"
+	EventL2Release   Event = "l2.release"
+	EventL2Move      Event = "l2.move"
+	EventL2FullPull  Event = "l2.full_pull"
+	EventL2Hold      Event = "l2.hold"
+	EventL2LongPress Event = "l2.long_press"
+	EventL2Click     Event = "l2.click"
+	EventL2DoubleTap Event = "l2.double_tap"
+	EventL2Repeat    Event = "l2.repeat"
 
 	// L3
-	EventL3Press   Event = "l3.press"
-	EventL3Release Event = "l3.release"
+	EventL3Press     Event = "l3.press"
+	EventL3Release   Event = "l3.release"
+	EventL3Hold      Event = "l3.hold"
+	EventL3LongPress Event = "l3.long_press"
+	EventL3Click     Event = "l3.click"
+	EventL3DoubleTap Event = "l3.double_tap"
+	EventL3Repeat    Event = "l3.repeat"
 
 	// R1
-	EventR1Press   Event = "r1.press"
-	EventR1Release Event = "r1.release"
+	EventR1Press     Event = "r1.press"
+	EventR1Release   Event = "r1.release"
+	EventR1Hold      Event = "r1.hold"
+	EventR1LongPress Event = "r1.long_press"
+	EventR1Click     Event = "r1.click"
+	EventR1DoubleTap Event = "r1.double_tap"
+	EventR1Repeat    Event = "r1.repeat"
 
 	// R2
-	EventR2Press   Event = "r2.press"
-	EventR2Release Event = "r2.release"
+	EventR2Press     Event = "r2.press"
+	EventR2Release   Event = "r2.release"
+	EventR2Move      Event = "r2.move"
+	EventR2FullPull  Event = "r2.full_pull"
+	EventR2Hold      Event = "r2.hold"
+	EventR2LongPress Event = "r2.long_press"
+	EventR2Click     Event = "r2.click"
+	EventR2DoubleTap Event = "r2.double_tap"
+	EventR2Repeat    Event = "r2.repeat"
 
 	// R3
-	EventR3Press   Event = "r3.press"
-	EventR3Release Event = "r3.release"
+	EventR3Press     Event = "r3.press"
+	EventR3Release   Event = "r3.release"
+	EventR3Hold      Event = "r3.hold"
+	EventR3LongPress Event = "r3.long_press"
+	EventR3Click     Event = "r3.click"
+	EventR3DoubleTap Event = "r3.double_tap"
+	EventR3Repeat    Event = "r3.repeat"
+
+	// D-pad (hat switch position)
+	EventDPadChange Event = "dpad.change"
 
 	// D-pad up
-	EventDPadUpPress   Event = "dpad_up.press"
-	EventDPadUpRelease Event = "dpad_up.release"
+	EventDPadUpPress     Event = "dpad_up.press"
+	EventDPadUpRelease   Event = "dpad_up.release"
+	EventDPadUpHold      Event = "dpad_up.hold"
+	EventDPadUpLongPress Event = "dpad_up.long_press"
+	EventDPadUpClick     Event = "dpad_up.click"
+	EventDPadUpDoubleTap Event = "dpad_up.double_tap"
+	EventDPadUpRepeat    Event = "dpad_up.repeat"
 
 	// D-pad down
-	EventDPadDownPress   Event = "dpad_down.press"
-	EventDPadDownRelease Event = "dpad_down.release"
+	EventDPadDownPress     Event = "dpad_down.press"
+	EventDPadDownRelease   Event = "dpad_down.release"
+	EventDPadDownHold      Event = "dpad_down.hold"
+	EventDPadDownLongPress Event = "dpad_down.long_press"
+	EventDPadDownClick     Event = "dpad_down.click"
+	EventDPadDownDoubleTap Event = "dpad_down.double_tap"
+	EventDPadDownRepeat    Event = "dpad_down.repeat"
 
 	// D-pad left
-	EventDPadLeftPress   Event = "dpad_left.press"
-	EventDPadLeftRelease Event = "dpad_left.release"
+	EventDPadLeftPress     Event = "dpad_left.press"
+	EventDPadLeftRelease   Event = "dpad_left.release"
+	EventDPadLeftHold      Event = "dpad_left.hold"
+	EventDPadLeftLongPress Event = "dpad_left.long_press"
+	EventDPadLeftClick     Event = "dpad_left.click"
+	EventDPadLeftDoubleTap Event = "dpad_left.double_tap"
+	EventDPadLeftRepeat    Event = "dpad_left.repeat"
 
 	// D-pad right
-	EventDPadRightPress   Event = "dpad_right.press"
-	EventDPadRightRelease Event = "dpad_right.release"
+	EventDPadRightPress     Event = "dpad_right.press"
+	EventDPadRightRelease   Event = "dpad_right.release"
+	EventDPadRightHold      Event = "dpad_right.hold"
+	EventDPadRightLongPress Event = "dpad_right.long_press"
+	EventDPadRightClick     Event = "dpad_right.click"
+	EventDPadRightDoubleTap Event = "dpad_right.double_tap"
+	EventDPadRightRepeat    Event = "dpad_right.repeat"
 
 	// Share
-	EventSharePress   Event = "share.press"
-	EventShareRelease Event = "share.release"
+	EventSharePress     Event = "share.press"
+	EventShareRelease   Event = "share.release"
+	EventShareHold      Event = "share.hold"
+	EventShareLongPress Event = "share.long_press"
+	EventShareClick     Event = "share.click"
+	EventShareDoubleTap Event = "share.double_tap"
+	EventShareRepeat    Event = "share.repeat"
 
 	// Options
-	EventOptionsPress   Event = "options.press"
-	EventOptionsRelease Event = "options.release"
+	EventOptionsPress     Event = "options.press"
+	EventOptionsRelease   Event = "options.release"
+	EventOptionsHold      Event = "options.hold"
+	EventOptionsLongPress Event = "options.long_press"
+	EventOptionsClick     Event = "options.click"
+	EventOptionsDoubleTap Event = "options.double_tap"
+	EventOptionsRepeat    Event = "options.repeat"
 
 	// Touchpad
-	EventTouchpadSwipe   Event = "touchpad.swipe"
-	EventTouchpadPress   Event = "touchpad.press"
-	EventTouchpadRelease Event = "touchpad.release"
+	EventTouchpadSwipe     Event = "touchpad.swipe"
+	EventTouchpadPress     Event = "touchpad.press"
+	EventTouchpadRelease   Event = "touchpad.release"
+	EventTouchpadHold      Event = "touchpad.hold"
+	EventTouchpadLongPress Event = "touchpad.long_press"
+	EventTouchpadClick     Event = "touchpad.click"
+	EventTouchpadDoubleTap Event = "touchpad.double_tap"
+	EventTouchpadRepeat    Event = "touchpad.repeat"
 
 	// PS
-	EventPSPress   Event = "ps.press"
-	EventPSRelease Event = "ps.release"
+	EventPSPress     Event = "ps.press"
+	EventPSRelease   Event = "ps.release"
+	EventPSHold      Event = "ps.hold"
+	EventPSLongPress Event = "ps.long_press"
+	EventPSClick     Event = "ps.click"
+	EventPSDoubleTap Event = "ps.double_tap"
+	EventPSRepeat    Event = "ps.repeat"
 
 	// Left stick
 	EventLeftStickMove Event = "left_stick.move"
 
+	// Left stick as a D-pad
+	EventLeftStickUpPress      Event = "left_stick_up.press"
+	EventLeftStickUpRelease    Event = "left_stick_up.release"
+	EventLeftStickDownPress    Event = "left_stick_down.press"
+	EventLeftStickDownRelease  Event = "left_stick_down.release"
+	EventLeftStickLeftPress    Event = "left_stick_left.press"
+	EventLeftStickLeftRelease  Event = "left_stick_left.release"
+	EventLeftStickRightPress   Event = "left_stick_right.press"
+	EventLeftStickRightRelease Event = "left_stick_right.release"
+
 	// Right stick
 	EventRightStickMove Event = "right_stick.move"
 
+	// Right stick as a D-pad
+	EventRightStickUpPress      Event = "right_stick_up.press"
+	EventRightStickUpRelease    Event = "right_stick_up.release"
+	EventRightStickDownPress    Event = "right_stick_down.press"
+	EventRightStickDownRelease  Event = "right_stick_down.release"
+	EventRightStickLeftPress    Event = "right_stick_left.press"
+	EventRightStickLeftRelease  Event = "right_stick_left.release"
+	EventRightStickRightPress   Event = "right_stick_right.press"
+	EventRightStickRightRelease Event = "right_stick_right.release"
+
 	// Accelerometer
 	EventAccelerometerUpdate Event = "accelerometer.update"
 
@@ -90,4 +207,31 @@ const (
 
 	// Battery
 	EventBatteryUpdate Event = "battery.update"
+
+	// Headset (headphones and microphone)
+	EventHeadsetPlug   Event = "headset.plug"
+	EventHeadsetUnplug Event = "headset.unplug"
+
+	// Extension port
+	EventExtensionPlug   Event = "extension.plug"
+	EventExtensionUnplug Event = "extension.unplug"
+
+	// Remapping profile
+	EventProfileChange Event = "profile.change"
+
+	// Macros (the data is the trigger button)
+	EventMacroRecordStart   Event = "macro.record_start"
+	EventMacroRecordStop    Event = "macro.record_stop"
+	EventMacroPlaybackStart Event = "macro.playback_start"
+	EventMacroPlaybackStop  Event = "macro.playback_stop"
+
+	// Turbo toggle mode (the data is the button)
+	EventTurboEnable  Event = "turbo.enable"
+	EventTurboDisable Event = "turbo.disable"
+
+	// Decoded state, fired on every report
+	EventStateUpdate Event = "state.update"
+
+	// Output (LED, rumble, volume)
+	EventOutputUpdate Event = "output.update"
 )
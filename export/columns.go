@@ -0,0 +1,88 @@
+package export
+
+import (
+	"github.com/kpeu3i/gods4"
+)
+
+type column struct {
+	name  string
+	value func(s *gods4.State) interface{}
+}
+
+func touch(s *gods4.State, i int) gods4.Touch {
+	if i >= len(s.Touchpad.Swipe) {
+		return gods4.Touch{}
+	}
+
+	return s.Touchpad.Swipe[i]
+}
+
+// Timestamps are exported in seconds
+var columns = []column{
+	{"timestamp", func(s *gods4.State) interface{} { return s.Timestamp.Seconds() }},
+	{"counter", func(s *gods4.State) interface{} { return s.Counter }},
+	{"cross", func(s *gods4.State) interface{} { return s.Cross }},
+	{"circle", func(s *gods4.State) interface{} { return s.Circle }},
+	{"square", func(s *gods4.State) interface{} { return s.Square }},
+	{"triangle", func(s *gods4.State) interface{} { return s.Triangle }},
+	{"l1", func(s *gods4.State) interface{} { return s.L1 }},
+	{"r1", func(s *gods4.State) interface{} { return s.R1 }},
+	{"l3", func(s *gods4.State) interface{} { return s.L3 }},
+	{"r3", func(s *gods4.State) interface{} { return s.R3 }},
+	{"share", func(s *gods4.State) interface{} { return s.Share }},
+	{"options", func(s *gods4.State) interface{} { return s.Options }},
+	{"ps", func(s *gods4.State) interface{} { return s.PS }},
+	{"dpad_up", func(s *gods4.State) interface{} { return s.DPad.IsUp() }},
+	{"dpad_down", func(s *gods4.State) interface{} { return s.DPad.IsDown() }},
+	{"dpad_left", func(s *gods4.State) interface{} { return s.DPad.IsLeft() }},
+	{"dpad_right", func(s *gods4.State) interface{} { return s.DPad.IsRight() }},
+	{"l2", func(s *gods4.State) interface{} { return s.L2.Value }},
+	{"l2_pressure", func(s *gods4.State) interface{} { return s.L2.Pressure }},
+	{"l2_pressed", func(s *gods4.State) interface{} { return s.L2.IsPressed }},
+	{"r2", func(s *gods4.State) interface{} { return s.R2.Value }},
+	{"r2_pressure", func(s *gods4.State) interface{} { return s.R2.Pressure }},
+	{"r2_pressed", func(s *gods4.State) interface{} { return s.R2.IsPressed }},
+	{"left_x", func(s *gods4.State) interface{} { return s.LeftStick.X }},
+	{"left_y", func(s *gods4.State) interface{} { return s.LeftStick.Y }},
+	{"left_axis_x", func(s *gods4.State) interface{} { return s.LeftStick.AxisX }},
+	{"left_axis_y", func(s *gods4.State) interface{} { return s.LeftStick.AxisY }},
+	{"right_x", func(s *gods4.State) interface{} { return s.RightStick.X }},
+	{"right_y", func(s *gods4.State) interface{} { return s.RightStick.Y }},
+	{"right_axis_x", func(s *gods4.State) interface{} { return s.RightStick.AxisX }},
+	{"right_axis_y", func(s *gods4.State) interface{} { return s.RightStick.AxisY }},
+	{"touchpad", func(s *gods4.State) interface{} { return s.Touchpad.Press }},
+	{"touch1_active", func(s *gods4.State) interface{} { return touch(s, 0).IsActive }},
+	{"touch1_x", func(s *gods4.State) interface{} { return touch(s, 0).X }},
+	{"touch1_y", func(s *gods4.State) interface{} { return touch(s, 0).Y }},
+	{"touch2_active", func(s *gods4.State) interface{} { return touch(s, 1).IsActive }},
+	{"touch2_x", func(s *gods4.State) interface{} { return touch(s, 1).X }},
+	{"touch2_y", func(s *gods4.State) interface{} { return touch(s, 1).Y }},
+	{"accelerometer_x", func(s *gods4.State) interface{} { return s.Accelerometer.X }},
+	{"accelerometer_y", func(s *gods4.State) interface{} { return s.Accelerometer.Y }},
+	{"accelerometer_z", func(s *gods4.State) interface{} { return s.Accelerometer.Z }},
+	{"accelerometer_raw_x", func(s *gods4.State) interface{} { return s.Accelerometer.RawX }},
+	{"accelerometer_raw_y", func(s *gods4.State) interface{} { return s.Accelerometer.RawY }},
+	{"accelerometer_raw_z", func(s *gods4.State) interface{} { return s.Accelerometer.RawZ }},
+	{"gyroscope_roll", func(s *gods4.State) interface{} { return s.Gyroscope.Roll }},
+	{"gyroscope_yaw", func(s *gods4.State) interface{} { return s.Gyroscope.Yaw }},
+	{"gyroscope_pitch", func(s *gods4.State) interface{} { return s.Gyroscope.Pitch }},
+	{"gyroscope_raw_roll", func(s *gods4.State) interface{} { return s.Gyroscope.RawRoll }},
+	{"gyroscope_raw_yaw", func(s *gods4.State) interface{} { return s.Gyroscope.RawYaw }},
+	{"gyroscope_raw_pitch", func(s *gods4.State) interface{} { return s.Gyroscope.RawPitch }},
+	{"battery", func(s *gods4.State) interface{} { return s.Battery.Capacity }},
+	{"battery_charging", func(s *gods4.State) interface{} { return s.Battery.IsCharging }},
+	{"cable", func(s *gods4.State) interface{} { return s.Battery.IsCableConnected }},
+	{"headphones", func(s *gods4.State) interface{} { return s.Headset.IsHeadphonesConnected }},
+	{"mic", func(s *gods4.State) interface{} { return s.Headset.IsMicConnected }},
+	{"extension", func(s *gods4.State) interface{} { return s.Extension }},
+}
+
+// Columns returns the names of all columns in export order.
+func Columns() []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	return names
+}
@@ -0,0 +1,254 @@
+// Package export writes decoded controller state to CSV or JSON Lines for analysis.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+	"github.com/kpeu3i/gods4/capture"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+var (
+	ErrUnknownFormat = errors.New("export: unknown format")
+	ErrUnknownColumn = errors.New("export: unknown column")
+)
+
+// Config selects the columns to export, all of them if Columns is empty.
+// With a positive Interval rows are downsampled to at most one per Interval of report time.
+type Config struct {
+	Format   Format
+	Columns  []string
+	Interval time.Duration
+}
+
+// Exporter writes a row per state. CSV booleans are written as 0 and 1.
+type Exporter struct {
+	mutex     sync.Mutex
+	detach    func()
+	err       error
+	config    Config
+	columns   []column
+	writer    *bufio.Writer
+	csv       *csv.Writer
+	lastAt    time.Duration
+	isStarted bool
+}
+
+func NewExporter(w io.Writer, config Config) (*Exporter, error) {
+	if config.Format != FormatCSV && config.Format != FormatJSONL {
+		return nil, errors.Wrapf(ErrUnknownFormat, "%q", config.Format)
+	}
+
+	e := &Exporter{config: config, writer: bufio.NewWriter(w)}
+
+	if len(config.Columns) == 0 {
+		e.columns = columns
+	}
+
+	for _, name := range config.Columns {
+		column, ok := findColumn(name)
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownColumn, "%q", name)
+		}
+
+		e.columns = append(e.columns, column)
+	}
+
+	if config.Format == FormatCSV {
+		e.csv = csv.NewWriter(e.writer)
+
+		header := make([]string, len(e.columns))
+		for i, column := range e.columns {
+			header[i] = column.name
+		}
+
+		err := e.csv.Write(header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func (e *Exporter) Write(state gods4.State) error {
+	if e.isStarted && e.config.Interval > 0 && state.Timestamp-e.lastAt < e.config.Interval {
+		return nil
+	}
+
+	e.isStarted = true
+	e.lastAt = state.Timestamp
+
+	if e.csv != nil {
+		record := make([]string, len(e.columns))
+		for i, column := range e.columns {
+			record[i] = formatCSV(column.value(&state))
+		}
+
+		return e.csv.Write(record)
+	}
+
+	// Keys are written in column order, which a map would not keep
+	line := make([]byte, 0, 32*len(e.columns))
+	line = append(line, '{')
+
+	for i, column := range e.columns {
+		if i > 0 {
+			line = append(line, ',')
+		}
+
+		value, err := json.Marshal(column.value(&state))
+		if err != nil {
+			return err
+		}
+
+		line = strconv.AppendQuote(line, column.name)
+		line = append(line, ':')
+		line = append(line, value...)
+	}
+
+	line = append(line, '}', '\n')
+
+	_, err := e.writer.Write(line)
+
+	return err
+}
+
+// Flush writes buffered rows and returns the first error of an attached session.
+func (e *Exporter) Flush() error {
+	e.mutex.Lock()
+	err := e.err
+	e.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if e.csv != nil {
+		e.csv.Flush()
+
+		err := e.csv.Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.writer.Flush()
+}
+
+// Attach exports every report of a live session as decoded, before profiles remap
+// it or turbo pulses buttons. Event callbacks are left alone. Rows are no longer
+// written after an error, which Flush returns.
+func (e *Exporter) Attach(controller *gods4.Controller) {
+	detach := controller.OnRawState(func(state gods4.State) {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		if e.err == nil {
+			e.err = e.Write(state)
+		}
+	})
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.detach != nil {
+		e.detach()
+	}
+
+	e.detach = detach
+}
+
+// Detach stops exporting the attached controller.
+func (e *Exporter) Detach() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.detach != nil {
+		e.detach()
+		e.detach = nil
+	}
+}
+
+// ExportCapture decodes a capture as fast as possible and exports its states.
+func ExportCapture(r io.Reader, w io.Writer, config Config) error {
+	player, err := capture.NewPlayer(r, capture.TimingFast)
+	if err != nil {
+		return err
+	}
+
+	exporter, err := NewExporter(w, config)
+	if err != nil {
+		return err
+	}
+
+	controller := gods4.NewController(player)
+	exporter.Attach(controller)
+
+	err = controller.Connect()
+	if err != nil {
+		return err
+	}
+
+	err = controller.Listen()
+
+	// The capture ends with EOF or with the read error of the recorded device
+	if _, ok := err.(capture.ReadError); err != io.EOF && !ok {
+		_ = controller.Disconnect()
+
+		return err
+	}
+
+	err = controller.Disconnect()
+	if err != nil {
+		return err
+	}
+
+	return exporter.Flush()
+}
+
+func findColumn(name string) (column, bool) {
+	for _, column := range columns {
+		if column.name == name {
+			return column, true
+		}
+	}
+
+	return column{}, false
+}
+
+func formatCSV(value interface{}) string {
+	switch value := value.(type) {
+	case bool:
+		if value {
+			return "1"
+		}
+
+		return "0"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case byte:
+		return strconv.Itoa(int(value))
+	case uint16:
+		return strconv.Itoa(int(value))
+	case int16:
+		return strconv.Itoa(int(value))
+	default:
+		return ""
+	}
+}
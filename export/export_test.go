@@ -0,0 +1,298 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4"
+	"github.com/kpeu3i/gods4/capture"
+)
+
+// fakeDevice is a USB DualShock 4 answering reads from a list of input reports.
+type fakeDevice struct {
+	inputs [][]byte
+}
+
+func (d *fakeDevice) VendorID() uint16     { return 0x054C }
+func (d *fakeDevice) ProductID() uint16    { return 0x09CC }
+func (d *fakeDevice) Path() string         { return "fake" }
+func (d *fakeDevice) Release() uint16      { return 0x0100 }
+func (d *fakeDevice) Serial() string       { return "a4:53:85:00:00:01" }
+func (d *fakeDevice) Manufacturer() string { return "Sony" }
+func (d *fakeDevice) Product() string      { return "Wireless Controller" }
+func (d *fakeDevice) Open() error          { return nil }
+func (d *fakeDevice) Close() error         { return nil }
+
+func (d *fakeDevice) Read(b []byte) (int, error) {
+	if len(d.inputs) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, d.inputs[0])
+	d.inputs = d.inputs[1:]
+
+	return n, nil
+}
+
+func (d *fakeDevice) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (d *fakeDevice) GetFeatureReport(code byte) ([]byte, error) {
+	return []byte{code}, nil
+}
+
+func (d *fakeDevice) SendFeatureReport(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// usbInput returns a USB input report at ticks of 16/3 µs with cross pressed or not and L2 pulled to l2.
+func usbInput(ticks uint16, cross bool, l2 byte) []byte {
+	report := make([]byte, 64)
+	report[0] = 0x01
+	report[1], report[2], report[3], report[4] = 128, 128, 128, 128
+	report[5] = 0x08
+	if cross {
+		report[5] |= 0x20
+	}
+
+	report[8] = l2
+	binary.LittleEndian.PutUint16(report[10:], ticks)
+
+	return report
+}
+
+// record returns a capture of a USB session sending the reports.
+func record(t *testing.T, inputs ...[]byte) []byte {
+	t.Helper()
+
+	var file bytes.Buffer
+
+	recorder, err := capture.NewRecorder(&fakeDevice{inputs: inputs}, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.SetConnectionType(gods4.ConnectionTypeUSB)
+
+	buf := make([]byte, 64)
+	for range inputs {
+		_, err = recorder.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = recorder.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file.Bytes()
+}
+
+func TestWrite(t *testing.T) {
+	states := []gods4.State{
+		{Timestamp: 0, L2: gods4.Trigger{Value: 0}},
+		{
+			Timestamp: 10 * time.Millisecond,
+			Cross:     true,
+			L2:        gods4.Trigger{Value: 255, Pressure: 1, IsPressed: true},
+			Touchpad:  gods4.Touchpad{Swipe: []gods4.Touch{{IsActive: true, X: 1919, Y: 942}}},
+		},
+	}
+
+	columns := []string{"timestamp", "cross", "l2", "l2_pressure", "touch1_active", "touch1_x", "touch2_x"}
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			FormatCSV,
+			"timestamp,cross,l2,l2_pressure,touch1_active,touch1_x,touch2_x\n" +
+				"0,0,0,0,0,0,0\n" +
+				"0.01,1,255,1,1,1919,0\n",
+		},
+		{
+			FormatJSONL,
+			`{"timestamp":0,"cross":false,"l2":0,"l2_pressure":0,"touch1_active":false,"touch1_x":0,"touch2_x":0}` + "\n" +
+				`{"timestamp":0.01,"cross":true,"l2":255,"l2_pressure":1,"touch1_active":true,"touch1_x":1919,"touch2_x":0}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b strings.Builder
+
+			exporter, err := NewExporter(&b, Config{Format: tt.format, Columns: columns})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, state := range states {
+				err = exporter.Write(state)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = exporter.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteAllColumns(t *testing.T) {
+	var b strings.Builder
+
+	exporter, err := NewExporter(&b, Config{Format: FormatCSV})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = exporter.Write(gods4.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = exporter.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+
+	if lines[0] != strings.Join(Columns(), ",") {
+		t.Errorf("got header %q", lines[0])
+	}
+
+	// Every column has a value, none is left empty by an unformatted type
+	for i, value := range strings.Split(lines[1], ",") {
+		if value == "" {
+			t.Errorf("column %s is empty", Columns()[i])
+		}
+	}
+}
+
+func TestWriteInterval(t *testing.T) {
+	var b strings.Builder
+
+	exporter, err := NewExporter(&b, Config{Format: FormatCSV, Columns: []string{"timestamp"}, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for now := time.Duration(0); now <= 30*time.Millisecond; now += 4 * time.Millisecond {
+		err = exporter.Write(gods4.State{Timestamp: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = exporter.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "timestamp\n0\n0.012\n0.024\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := NewExporter(io.Discard, Config{Format: "xml"})
+	if errors.Cause(err) != ErrUnknownFormat {
+		t.Errorf("got error %v, want %v", err, ErrUnknownFormat)
+	}
+
+	_, err = NewExporter(io.Discard, Config{Format: FormatCSV, Columns: []string{"timestamp", "cross_pressed"}})
+	if errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("got error %v, want %v", err, ErrUnknownColumn)
+	}
+}
+
+func TestExportCapture(t *testing.T) {
+	file := record(t, usbInput(0, false, 0), usbInput(1875, true, 200))
+
+	var b strings.Builder
+
+	err := ExportCapture(bytes.NewReader(file), &b, Config{Format: FormatCSV, Columns: []string{"timestamp", "cross", "l2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "timestamp,cross,l2\n0,0,0\n0.01,1,200\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestAttachExportsRawState(t *testing.T) {
+	file := record(t, usbInput(0, false, 0), usbInput(1875, true, 0))
+
+	player, err := capture.NewPlayer(bytes.NewReader(file), capture.TimingFast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := gods4.NewController(player)
+
+	err = controller.AddProfile(gods4.Profile{Name: "swap", Rules: []gods4.Rule{gods4.SwapButtons(gods4.ButtonCross, gods4.ButtonCircle)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.SetProfile("swap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+
+	exporter, err := NewExporter(&b, Config{Format: FormatCSV, Columns: []string{"cross", "circle"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.Attach(controller)
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = controller.Listen()
+
+	err = controller.Disconnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = exporter.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cross is exported as the controller sent it, not swapped with circle
+	want := "cross,circle\n0,0\n1,0\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,171 @@
+package filter
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Used when samples arrive without a usable time delta
+const defaultSamplePeriod = 4 * time.Millisecond
+
+// Filter smooths a stream of samples of a single axis.
+// Filters keep history, so every axis needs its own instance.
+type Filter interface {
+	// Filter returns the smoothed value of a sample taken dt after the previous one
+	Filter(value float64, dt time.Duration) float64
+	// Clone returns a filter with the same parameters and empty history
+	Clone() Filter
+}
+
+type none struct{}
+
+func None() Filter {
+	return none{}
+}
+
+func (none) Filter(value float64, dt time.Duration) float64 {
+	return value
+}
+
+func (none) Clone() Filter {
+	return none{}
+}
+
+type threshold struct {
+	delta     float64
+	last      float64
+	isStarted bool
+}
+
+// Threshold ignores changes smaller than delta.
+func Threshold(delta float64) Filter {
+	return &threshold{delta: delta}
+}
+
+func (f *threshold) Filter(value float64, dt time.Duration) float64 {
+	if !f.isStarted || math.Abs(value-f.last) >= f.delta {
+		f.last = value
+		f.isStarted = true
+	}
+
+	return f.last
+}
+
+func (f *threshold) Clone() Filter {
+	return Threshold(f.delta)
+}
+
+type ema struct {
+	alpha     float64
+	last      float64
+	isStarted bool
+}
+
+// EMA is an exponential moving average, alpha in (0, 1] is the weight of a new sample.
+func EMA(alpha float64) Filter {
+	return &ema{alpha: alpha}
+}
+
+func (f *ema) Filter(value float64, dt time.Duration) float64 {
+	if !f.isStarted {
+		f.last = value
+		f.isStarted = true
+
+		return value
+	}
+
+	f.last = f.alpha*value + (1-f.alpha)*f.last
+
+	return f.last
+}
+
+func (f *ema) Clone() Filter {
+	return EMA(f.alpha)
+}
+
+type median struct {
+	window  int
+	samples []float64
+	sorted  []float64
+}
+
+// Median returns the median of the last window samples.
+func Median(window int) Filter {
+	if window < 1 {
+		window = 1
+	}
+
+	return &median{window: window}
+}
+
+func (f *median) Filter(value float64, dt time.Duration) float64 {
+	f.samples = append(f.samples, value)
+	if len(f.samples) > f.window {
+		f.samples = f.samples[1:]
+	}
+
+	f.sorted = append(f.sorted[:0], f.samples...)
+	sort.Float64s(f.sorted)
+
+	middle := len(f.sorted) / 2
+	if len(f.sorted)%2 == 0 {
+		return (f.sorted[middle-1] + f.sorted[middle]) / 2
+	}
+
+	return f.sorted[middle]
+}
+
+func (f *median) Clone() Filter {
+	return Median(f.window)
+}
+
+type oneEuro struct {
+	minCutoff        float64
+	beta             float64
+	derivativeCutoff float64
+	last             float64
+	lastDerivative   float64
+	isStarted        bool
+}
+
+// OneEuro is the 1€ filter (Casiez et al.): a low-pass filter whose cutoff frequency (Hz)
+// grows with speed, so slow movements are smoothed and fast ones keep low latency.
+func OneEuro(minCutoff, beta, derivativeCutoff float64) Filter {
+	return &oneEuro{minCutoff: minCutoff, beta: beta, derivativeCutoff: derivativeCutoff}
+}
+
+func (f *oneEuro) Filter(value float64, dt time.Duration) float64 {
+	if !f.isStarted {
+		f.last = value
+		f.isStarted = true
+
+		return value
+	}
+
+	if dt <= 0 {
+		dt = defaultSamplePeriod
+	}
+
+	period := dt.Seconds()
+
+	derivative := (value - f.last) / period
+	a := smoothingFactor(period, f.derivativeCutoff)
+	f.lastDerivative = a*derivative + (1-a)*f.lastDerivative
+
+	cutoff := f.minCutoff + f.beta*math.Abs(f.lastDerivative)
+	a = smoothingFactor(period, cutoff)
+	f.last = a*value + (1-a)*f.last
+
+	return f.last
+}
+
+func (f *oneEuro) Clone() Filter {
+	return OneEuro(f.minCutoff, f.beta, f.derivativeCutoff)
+}
+
+func smoothingFactor(period, cutoff float64) float64 {
+	tau := 1 / (2 * math.Pi * cutoff)
+
+	return 1 / (1 + tau/period)
+}
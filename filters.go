@@ -0,0 +1,73 @@
+package gods4
+
+import (
+	"reflect"
+
+	"github.com/kpeu3i/gods4/filter"
+)
+
+// filters holds per-axis filter instances built from settings.
+// They are owned by the goroutine decoding input reports.
+type filters struct {
+	settings      *settings
+	leftStick     [2]filter.Filter
+	rightStick    [2]filter.Filter
+	accelerometer [3]filter.Filter
+	gyroscope     [3]filter.Filter
+}
+
+func newFilters(settings *settings) *filters {
+	f := &filters{settings: settings}
+
+	cloneFilters(f.leftStick[:], settings.leftStick.Filter)
+	cloneFilters(f.rightStick[:], settings.rightStick.Filter)
+	cloneFilters(f.accelerometer[:], settings.accelerometerFilter)
+	cloneFilters(f.gyroscope[:], settings.gyroscopeFilter)
+
+	return f
+}
+
+// update rebuilds the filters whose configuration changed, the others keep their history.
+func (f *filters) update(settings *settings) {
+	if f.settings == settings {
+		return
+	}
+
+	if !isSameFilter(f.settings.leftStick.Filter, settings.leftStick.Filter) {
+		cloneFilters(f.leftStick[:], settings.leftStick.Filter)
+	}
+
+	if !isSameFilter(f.settings.rightStick.Filter, settings.rightStick.Filter) {
+		cloneFilters(f.rightStick[:], settings.rightStick.Filter)
+	}
+
+	if !isSameFilter(f.settings.accelerometerFilter, settings.accelerometerFilter) {
+		cloneFilters(f.accelerometer[:], settings.accelerometerFilter)
+	}
+
+	if !isSameFilter(f.settings.gyroscopeFilter, settings.gyroscopeFilter) {
+		cloneFilters(f.gyroscope[:], settings.gyroscopeFilter)
+	}
+
+	f.settings = settings
+}
+
+func cloneFilters(filters []filter.Filter, source filter.Filter) {
+	for i := range filters {
+		filters[i] = source.Clone()
+	}
+}
+
+// isSameFilter reports whether both settings hold the same filter. Filters of
+// types that can't be compared are treated as changed.
+func isSameFilter(a, b filter.Filter) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
@@ -0,0 +1,37 @@
+package gods4
+
+import (
+	"testing"
+
+	"github.com/kpeu3i/gods4/filter"
+)
+
+func TestFiltersKeepHistoryOnUnrelatedChanges(t *testing.T) {
+	s := defaultSettings()
+	s.leftStick.Filter = filter.EMA(0.5)
+
+	f := newFilters(s)
+	f.leftStick[0].Filter(1, 0)
+
+	changed := *s
+	changed.l2.PressThreshold++
+	changed.gyroscopeFilter = filter.EMA(0.5)
+	f.update(&changed)
+
+	// An EMA with history moves halfway from 1 towards 0
+	if got := f.leftStick[0].Filter(0, 0); got != 0.5 {
+		t.Errorf("left stick filter lost its history: got %v, want 0.5", got)
+	}
+
+	if f.settings != &changed {
+		t.Error("filters don't track the new settings")
+	}
+
+	reconfigured := changed
+	reconfigured.leftStick.Filter = filter.EMA(0.5)
+	f.update(&reconfigured)
+
+	if got := f.leftStick[0].Filter(0, 0); got != 0 {
+		t.Errorf("reconfigured left stick filter kept its history: got %v, want 0", got)
+	}
+}
@@ -1,6 +1,10 @@
 module github.com/kpeu3i/gods4
 
+go 1.19
+
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/pkg/errors v0.8.0
 	github.com/stamp/hid v0.0.0-20190105143849-bc55d7d13ce1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,13 +1,19 @@
 package hid
 
 import (
+	"runtime"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/stamp/hid"
 )
 
+// Windows paths of Bluetooth devices name the HID service class
+const bluetoothHIDServiceUUID = "00001124-0000-1000-8000-00805f9b34fb"
+
 var (
 	vendorIDs  = [...]uint16{1356}
-	productIDs = [...]uint16{2508, 1476}
+	productIDs = [...]uint16{2508, 1476, 3302, 3570}
 )
 
 type Device struct {
@@ -43,6 +49,23 @@ func (d *Device) Product() string {
 	return d.hidDeviceInfo.Product
 }
 
+// IsBluetooth tells the transport from the device path. It always does, so Connect
+// never detects it by reading: hidapi can't cancel a pending read on Close.
+// On Linux devices are found through libusb, which only sees USB devices.
+func (d *Device) IsBluetooth() (bool, bool) {
+	path := strings.ToLower(d.hidDeviceInfo.Path)
+
+	switch runtime.GOOS {
+	case "windows":
+		return strings.Contains(path, bluetoothHIDServiceUUID), true
+	case "darwin":
+		// The IOService path names the driver of the device
+		return strings.Contains(path, "bluetooth"), true
+	default:
+		return false, true
+	}
+}
+
 func (d *Device) Open() error {
 	hidDevice, err := d.hidDeviceInfo.Open()
 	if err != nil {
@@ -72,18 +95,31 @@ func (d *Device) GetFeatureReport(code byte) ([]byte, error) {
 	switch code {
 	case 0x04:
 		bytes = make([]byte, 67)
-		bytes[0] = code
-		_, err := d.hidDevice.GetFeatureReport(bytes)
-		if err != nil {
-			return nil, err
-		}
+	case 0x05:
+		bytes = make([]byte, 41)
+	case 0x09:
+		bytes = make([]byte, 20)
+	case 0x12:
+		bytes = make([]byte, 16)
 	default:
 		return nil, errors.Errorf("hid: unsupported report code: %v", code)
 	}
 
+	bytes[0] = code
+
+	_, err := d.hidDevice.GetFeatureReport(bytes)
+	if err != nil {
+		return nil, err
+	}
+
 	return bytes, nil
 }
 
+// SendFeatureReport sends a feature report, the first byte is the report code.
+func (d *Device) SendFeatureReport(b []byte) (int, error) {
+	return d.hidDevice.SendFeatureReport(b)
+}
+
 func Find() []*Device {
 	var devices []*Device
 
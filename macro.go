@@ -0,0 +1,492 @@
+package gods4
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidMacro        = errors.New("ds4: invalid macro")
+	ErrMacroIsRecording    = errors.New("ds4: macro is already being recorded")
+	ErrMacroIsNotRecording = errors.New("ds4: macro is not being recorded")
+)
+
+// MacroStep fires Event with Data, Delay after the previous step.
+type MacroStep struct {
+	Delay time.Duration
+	Event Event
+	Data  interface{}
+}
+
+// Macro is a sequence of events played back into the event pipeline.
+// Speed scales the playback rate (0 means 1, 2 plays twice as fast).
+// A looping macro plays until it is cancelled or its trigger is pressed again.
+type Macro struct {
+	Steps []MacroStep
+	Speed float64
+	Loop  bool
+}
+
+func (m Macro) Validate() error {
+	if len(m.Steps) == 0 {
+		return errors.Wrap(ErrInvalidMacro, "at least one step is required")
+	}
+
+	if m.Speed < 0 || math.IsNaN(m.Speed) || math.IsInf(m.Speed, 0) {
+		return errors.Wrapf(ErrInvalidMacro, "speed (%v) must be positive", m.Speed)
+	}
+
+	var duration time.Duration
+
+	for i, step := range m.Steps {
+		if step.Delay < 0 {
+			return errors.Wrapf(ErrInvalidMacro, "step #%d has negative delay", i)
+		}
+
+		if step.Event == "" {
+			return errors.Wrapf(ErrInvalidMacro, "step #%d has no event", i)
+		}
+
+		duration += m.delay(i)
+	}
+
+	// A high speed can scale the delays down to nothing
+	if m.Loop && duration == 0 {
+		return errors.Wrapf(ErrInvalidMacro, "looping macro must have a positive duration at speed %v", m.Speed)
+	}
+
+	return nil
+}
+
+func (m Macro) delay(i int) time.Duration {
+	if m.Speed == 0 {
+		return m.Steps[i].Delay
+	}
+
+	return time.Duration(float64(m.Steps[i].Delay) / m.Speed)
+}
+
+// Events that describe the controller rather than user input are not recorded
+var unrecordedEvents = map[Event]bool{
+	EventAccelerometerUpdate: true,
+	EventGyroscopeUpdate:     true,
+	EventBatteryUpdate:       true,
+	EventHeadsetPlug:         true,
+	EventHeadsetUnplug:       true,
+	EventExtensionPlug:       true,
+	EventExtensionUnplug:     true,
+	EventProfileChange:       true,
+	EventOutputUpdate:        true,
+}
+
+type playback struct {
+	trigger     Button
+	macro       Macro
+	next        int
+	dueAt       time.Duration
+	held        map[Event]bool
+	isCancelled bool
+}
+
+type recording struct {
+	trigger Button
+	steps   []MacroStep
+	lastAt  time.Duration
+	// Index of the step recorded when the modifier was last pressed,
+	// the stop gesture is cut from there
+	modifierMark   int
+	isStarted      bool
+	isGesture      bool
+	isTriggerMuted bool
+}
+
+type firing struct {
+	event Event
+	data  interface{}
+}
+
+// macros records macros and plays them back, driven by report timestamps.
+type macros struct {
+	mutex     sync.Mutex
+	bindings  map[Button]Macro
+	playbacks []*playback
+	recording *recording
+	modifier  Button
+	isEnabled bool
+}
+
+func newMacros() *macros {
+	return &macros{bindings: make(map[Button]Macro)}
+}
+
+func (m *macros) bind(button Button, macro Macro) error {
+	if button.String() == "" {
+		return errors.Wrapf(ErrInvalidMacro, "unknown trigger button: %d", button)
+	}
+
+	err := macro.Validate()
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.bindings[button] = copyMacro(macro)
+
+	return nil
+}
+
+func (m *macros) unbind(button Button) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.bindings, button)
+	m.cancel(button)
+}
+
+func (m *macros) macro(button Button) (Macro, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	macro, ok := m.bindings[button]
+	if !ok {
+		return Macro{}, false
+	}
+
+	return copyMacro(macro), true
+}
+
+func (m *macros) reset(bindings map[Button]Macro) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.bindings = make(map[Button]Macro, len(bindings))
+	for button, macro := range bindings {
+		m.bindings[button] = copyMacro(macro)
+	}
+}
+
+// cancel stops playback on the next report, releasing the buttons it holds.
+func (m *macros) cancel(button Button) {
+	for _, playback := range m.playbacks {
+		if playback.trigger == button {
+			playback.isCancelled = true
+		}
+	}
+}
+
+func (m *macros) cancelAll() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, playback := range m.playbacks {
+		playback.isCancelled = true
+	}
+}
+
+func (m *macros) cancelOne(button Button) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.cancel(button)
+}
+
+func (m *macros) enableRecording(modifier Button) error {
+	if modifier.String() == "" {
+		return errors.Wrapf(ErrInvalidMacro, "unknown modifier button: %d", modifier)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.modifier = modifier
+	m.isEnabled = true
+
+	return nil
+}
+
+func (m *macros) disableRecording() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.isEnabled = false
+	if m.recording != nil && m.recording.isGesture {
+		m.recording = nil
+	}
+}
+
+func (m *macros) startRecording(trigger Button) error {
+	if trigger.String() == "" {
+		return errors.Wrapf(ErrInvalidMacro, "unknown trigger button: %d", trigger)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.recording != nil {
+		return ErrMacroIsRecording
+	}
+
+	m.recording = &recording{trigger: trigger, modifierMark: -1}
+
+	return nil
+}
+
+func (m *macros) stopRecording() (Macro, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.recording == nil {
+		return Macro{}, ErrMacroIsNotRecording
+	}
+
+	macro := Macro{Steps: m.recording.steps}
+	trigger := m.recording.trigger
+	m.recording = nil
+
+	err := macro.Validate()
+	if err != nil {
+		return Macro{}, err
+	}
+
+	m.bindings[trigger] = macro
+
+	return copyMacro(macro), nil
+}
+
+// record appends an event fired while recording.
+func (m *macros) record(event Event, data interface{}, now time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	r := m.recording
+	if r == nil || unrecordedEvents[event] || strings.HasPrefix(string(event), "macro.") {
+		return
+	}
+
+	if r.isGesture && isButtonEvent(event, m.modifier) {
+		if event == m.modifier.PressEvent() {
+			r.modifierMark = len(r.steps)
+		}
+
+		return
+	}
+
+	if r.isTriggerMuted && isButtonEvent(event, r.trigger) {
+		return
+	}
+
+	if !r.isStarted {
+		r.lastAt, r.isStarted = now, true
+	}
+
+	r.steps = append(r.steps, MacroStep{Delay: now - r.lastAt, Event: event, Data: data})
+	r.lastAt = now
+}
+
+// update handles recording gestures, starts and cancels playbacks and
+// returns the events due at the current report.
+func (m *macros) update(currState, prevState *state) []firing {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var firings []firing
+
+	now := currState.timestamp
+	isModified := m.isEnabled && currState.button(m.modifier)
+
+	for _, button := range buttons {
+		if !currState.button(button) || prevState.button(button) || button == m.modifier && m.isEnabled {
+			continue
+		}
+
+		if isModified {
+			firings = append(firings, m.toggleRecording(button, now)...)
+
+			continue
+		}
+
+		macro, ok := m.bindings[button]
+		if !ok {
+			continue
+		}
+
+		firings = append(firings, m.play(button, macro, now)...)
+	}
+
+	if m.recording != nil && m.recording.isTriggerMuted && !currState.button(m.recording.trigger) {
+		m.recording.isTriggerMuted = false
+	}
+
+	playbacks := m.playbacks[:0]
+	for _, playback := range m.playbacks {
+		if playback.isCancelled {
+			firings = append(firings, playback.release()...)
+			firings = append(firings, firing{event: EventMacroPlaybackStop, data: playback.trigger})
+
+			continue
+		}
+
+		firings = append(firings, playback.due(now)...)
+
+		if playback.next < len(playback.macro.Steps) {
+			playbacks = append(playbacks, playback)
+		} else {
+			firings = append(firings, playback.release()...)
+			firings = append(firings, firing{event: EventMacroPlaybackStop, data: playback.trigger})
+		}
+	}
+	m.playbacks = playbacks
+
+	return firings
+}
+
+// toggleRecording starts recording on the first modifier gesture and stops it
+// when the gesture is repeated with the same button.
+func (m *macros) toggleRecording(button Button, now time.Duration) []firing {
+	if m.recording == nil {
+		m.recording = &recording{
+			trigger:        button,
+			lastAt:         now,
+			modifierMark:   -1,
+			isStarted:      true,
+			isGesture:      true,
+			isTriggerMuted: true,
+		}
+
+		return []firing{{event: EventMacroRecordStart, data: button}}
+	}
+
+	if m.recording.trigger != button {
+		return nil
+	}
+
+	steps := m.recording.steps
+	if m.recording.modifierMark >= 0 {
+		steps = steps[:m.recording.modifierMark]
+	}
+
+	m.recording = nil
+
+	// If nothing was recorded, the previous macro is kept
+	macro := Macro{Steps: steps}
+	if macro.Validate() == nil {
+		m.bindings[button] = macro
+	}
+
+	return []firing{{event: EventMacroRecordStop, data: button}}
+}
+
+// play restarts a macro that is already playing, a looping one is stopped instead.
+func (m *macros) play(button Button, macro Macro, now time.Duration) []firing {
+	for _, playback := range m.playbacks {
+		if playback.trigger != button || playback.isCancelled {
+			continue
+		}
+
+		if playback.macro.Loop {
+			playback.isCancelled = true
+
+			return nil
+		}
+
+		firings := playback.release()
+		playback.macro, playback.next, playback.dueAt = macro, 0, now+macro.delay(0)
+
+		return firings
+	}
+
+	m.playbacks = append(m.playbacks, &playback{
+		trigger: button,
+		macro:   macro,
+		dueAt:   now + macro.delay(0),
+		held:    make(map[Event]bool),
+	})
+
+	return []firing{{event: EventMacroPlaybackStart, data: button}}
+}
+
+// due fires the steps due at now, at most one loop of them. A playback left
+// further behind, as after a gap in reports, times the next step from now.
+func (p *playback) due(now time.Duration) []firing {
+	var firings []firing
+
+	steps := p.macro.Steps
+	for len(firings) < len(steps) && p.next < len(steps) && p.dueAt <= now {
+		step := steps[p.next]
+		firings = append(firings, firing{event: step.Event, data: step.Data})
+
+		if strings.HasSuffix(string(step.Event), ".press") {
+			p.held[step.Event] = true
+		} else if strings.HasSuffix(string(step.Event), ".release") {
+			delete(p.held, Event(strings.TrimSuffix(string(step.Event), ".release")+".press"))
+		}
+
+		p.next++
+		if p.next == len(steps) && p.macro.Loop {
+			p.next = 0
+		}
+
+		if p.next < len(steps) {
+			p.dueAt += p.macro.delay(p.next)
+		}
+	}
+
+	if p.next < len(steps) && p.dueAt <= now {
+		p.dueAt = now + p.macro.delay(p.next)
+	}
+
+	return firings
+}
+
+// release fires release events for presses the playback has not released yet,
+// so a finished or cancelled macro never leaves a button pressed.
+func (p *playback) release() []firing {
+	events := make([]string, 0, len(p.held))
+	for event := range p.held {
+		events = append(events, strings.TrimSuffix(string(event), ".press")+".release")
+	}
+	sort.Strings(events)
+
+	firings := make([]firing, len(events))
+	for i, event := range events {
+		firings[i] = firing{event: Event(event)}
+		// Trigger releases carry the analog value
+		if Event(event) == EventL2Release || Event(event) == EventR2Release {
+			firings[i].data = byte(0)
+		}
+	}
+
+	p.held = make(map[Event]bool)
+
+	return firings
+}
+
+func (e *emitter) checkMacros(currState, prevState *state) error {
+	for _, firing := range e.macros.update(currState, prevState) {
+		err := e.dispatch(currState.timestamp, firing.event, firing.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isButtonEvent(event Event, button Button) bool {
+	return strings.HasPrefix(string(event), button.String()+".")
+}
+
+func copyMacro(macro Macro) Macro {
+	steps := make([]MacroStep, len(macro.Steps))
+	copy(steps, macro.Steps)
+	macro.Steps = steps
+
+	return macro
+}
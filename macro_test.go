@@ -0,0 +1,333 @@
+package gods4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// report holds the buttons pressed at a time in milliseconds.
+type report struct {
+	at   time.Duration
+	held []Button
+}
+
+// hold is a button held from a time up to another, in milliseconds.
+type hold struct {
+	button   Button
+	from, to time.Duration
+}
+
+// everyReport returns a report every 10 ms from 10 ms up to end, with the holds.
+func everyReport(end time.Duration, holds ...hold) []report {
+	var reports []report
+	for at := time.Duration(10); at <= end; at += 10 {
+		r := report{at: at}
+		for _, hold := range holds {
+			if at >= hold.from && at < hold.to {
+				r.held = append(r.held, hold.button)
+			}
+		}
+
+		reports = append(reports, r)
+	}
+
+	return reports
+}
+
+var macroEvents = []Event{
+	EventCrossPress, EventCrossRelease, EventCirclePress, EventCircleRelease,
+	EventMacroRecordStart, EventMacroRecordStop, EventMacroPlaybackStart, EventMacroPlaybackStop,
+}
+
+// emitReports emits the reports and returns the macro events with the time they are fired at.
+func emitReports(t *testing.T, e *emitter, reports []report) []input {
+	log := &eventLog{}
+	log.listen(e, macroEvents...)
+
+	var fired []input
+
+	prevState := &state{}
+	for _, r := range reports {
+		currState := &state{timestamp: r.at * time.Millisecond}
+		for _, button := range r.held {
+			currState.setButton(button, true)
+		}
+
+		err := e.emit(currState, prevState)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, event := range log.events[len(fired):] {
+			fired = append(fired, input{event: event, at: r.at})
+		}
+
+		prevState = currState
+	}
+
+	return fired
+}
+
+func equalInputs(a, b []input) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMacroPlayback(t *testing.T) {
+	const ms = time.Millisecond
+
+	// Circle is left pressed, playback releases it when it ends
+	macro := Macro{Steps: []MacroStep{
+		{Event: EventCrossPress},
+		{Delay: 50 * ms, Event: EventCrossRelease},
+		{Delay: 100 * ms, Event: EventCirclePress},
+	}}
+
+	fast := macro
+	fast.Speed = 2
+
+	tap := Macro{Steps: []MacroStep{
+		{Event: EventCrossPress},
+		{Delay: 100 * ms, Event: EventCrossRelease},
+	}}
+
+	loop := Macro{
+		Steps: []MacroStep{
+			{Delay: 20 * ms, Event: EventCrossPress},
+			{Delay: 20 * ms, Event: EventCrossRelease},
+		},
+		Loop: true,
+	}
+
+	tests := []struct {
+		name    string
+		macro   Macro
+		reports []report
+		want    []input
+	}{
+		{
+			"timing",
+			macro,
+			everyReport(300, hold{ButtonTriangle, 10, 20}),
+			[]input{
+				{EventMacroPlaybackStart, 10},
+				{EventCrossPress, 10},
+				{EventCrossRelease, 60},
+				{EventCirclePress, 160},
+				{EventCircleRelease, 160},
+				{EventMacroPlaybackStop, 160},
+			},
+		},
+		{
+			"twice as fast",
+			fast,
+			everyReport(300, hold{ButtonTriangle, 10, 20}),
+			[]input{
+				{EventMacroPlaybackStart, 10},
+				{EventCrossPress, 10},
+				{EventCrossRelease, 40},
+				{EventCirclePress, 90},
+				{EventCircleRelease, 90},
+				{EventMacroPlaybackStop, 90},
+			},
+		},
+		{
+			"restarted by its trigger",
+			tap,
+			everyReport(300, hold{ButtonTriangle, 10, 20}, hold{ButtonTriangle, 50, 60}),
+			[]input{
+				{EventMacroPlaybackStart, 10},
+				{EventCrossPress, 10},
+				{EventCrossRelease, 50},
+				{EventCrossPress, 50},
+				{EventCrossRelease, 150},
+				{EventMacroPlaybackStop, 150},
+			},
+		},
+		{
+			"loop cancelled by its trigger",
+			loop,
+			everyReport(300, hold{ButtonTriangle, 10, 20}, hold{ButtonTriangle, 80, 90}),
+			[]input{
+				{EventMacroPlaybackStart, 10},
+				{EventCrossPress, 30},
+				{EventCrossRelease, 50},
+				{EventCrossPress, 70},
+				{EventCrossRelease, 80},
+				{EventMacroPlaybackStop, 80},
+			},
+		},
+		{
+			"loop behind after a gap in reports",
+			loop,
+			[]report{
+				{at: 10, held: []Button{ButtonTriangle}},
+				{at: 1010},
+				{at: 1020},
+				{at: 1030},
+				{at: 1040, held: []Button{ButtonTriangle}},
+			},
+			[]input{
+				{EventMacroPlaybackStart, 10},
+				{EventCrossPress, 1010},
+				{EventCrossRelease, 1010},
+				{EventCrossPress, 1030},
+				{EventCrossRelease, 1040},
+				{EventMacroPlaybackStop, 1040},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEmitter()
+
+			err := e.macros.bind(ButtonTriangle, tt.macro)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := emitReports(t, e, tt.reports)
+			if !equalInputs(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancelMacro(t *testing.T) {
+	e := newEmitter()
+
+	err := e.macros.bind(ButtonTriangle, Macro{Steps: []MacroStep{
+		{Event: EventL2Press, Data: byte(255)},
+		{Delay: time.Second, Event: EventL2Release, Data: byte(0)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := &eventLog{}
+	log.listen(e, EventL2Press, EventL2Release, EventMacroPlaybackStop)
+
+	prevState := &state{}
+	for i, isPressed := range []bool{true, false, false} {
+		if i == 2 {
+			e.macros.cancelOne(ButtonTriangle)
+		}
+
+		currState := &state{timestamp: time.Duration(i+1) * 10 * time.Millisecond, triangle: isPressed}
+
+		err = e.emit(currState, prevState)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prevState = currState
+	}
+
+	want := []Event{EventL2Press, EventL2Release, EventMacroPlaybackStop}
+	if !equalEvents(log.events, want) {
+		t.Fatalf("got %v, want %v", log.events, want)
+	}
+
+	// The held trigger is released with its analog value
+	if log.data[1] != byte(0) {
+		t.Errorf("got release data %v, want 0", log.data[1])
+	}
+}
+
+func TestMacroRecordingGesture(t *testing.T) {
+	const ms = time.Millisecond
+
+	e := newEmitter()
+
+	err := e.macros.enableRecording(ButtonL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// L1+triangle starts recording, cross is recorded, L1+triangle stops it.
+	// Circle pressed while L1 is held for the stop gesture is cut.
+	got := emitReports(t, e, everyReport(300,
+		hold{ButtonL1, 10, 30},
+		hold{ButtonTriangle, 20, 30},
+		hold{ButtonCross, 50, 80},
+		hold{ButtonL1, 100, 130},
+		hold{ButtonCircle, 110, 130},
+		hold{ButtonTriangle, 120, 130},
+		hold{ButtonTriangle, 200, 210},
+	))
+
+	want := []input{
+		{EventMacroRecordStart, 20},
+		{EventCrossPress, 50},
+		{EventCrossRelease, 80},
+		{EventCirclePress, 110},
+		{EventMacroRecordStop, 120},
+		{EventCircleRelease, 130},
+		{EventMacroPlaybackStart, 200},
+		{EventCrossPress, 230},
+		{EventCrossRelease, 260},
+		{EventMacroPlaybackStop, 260},
+	}
+	if !equalInputs(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	macro, ok := e.macros.macro(ButtonTriangle)
+	if !ok {
+		t.Fatal("no macro recorded")
+	}
+
+	steps := []MacroStep{
+		{Delay: 30 * ms, Event: EventCrossPress},
+		{Delay: 30 * ms, Event: EventCrossRelease},
+		{Event: EventCrossClick},
+	}
+	if len(macro.Steps) != len(steps) {
+		t.Fatalf("got steps %v, want %v", macro.Steps, steps)
+	}
+
+	for i, step := range macro.Steps {
+		if step.Delay != steps[i].Delay || step.Event != steps[i].Event {
+			t.Errorf("got step #%d %v, want %v", i, step, steps[i])
+		}
+	}
+}
+
+func TestInvalidMacros(t *testing.T) {
+	tests := []struct {
+		name  string
+		macro Macro
+	}{
+		{"no steps", Macro{}},
+		{"negative speed", Macro{Steps: []MacroStep{{Event: EventCrossPress}}, Speed: -1}},
+		{"negative delay", Macro{Steps: []MacroStep{{Delay: -1, Event: EventCrossPress}}}},
+		{"no event", Macro{Steps: []MacroStep{{}}}},
+		{"loop without duration", Macro{Steps: []MacroStep{{Event: EventCrossPress}}, Loop: true}},
+		{
+			"loop without duration at speed",
+			Macro{Steps: []MacroStep{{Delay: 1, Event: EventCrossPress}, {Delay: 1, Event: EventCrossRelease}}, Speed: 10, Loop: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.macro.Validate()
+			if errors.Cause(err) != ErrInvalidMacro {
+				t.Errorf("got %v, want %v", err, ErrInvalidMacro)
+			}
+		})
+	}
+}
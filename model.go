@@ -0,0 +1,261 @@
+package gods4
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Product IDs of Sony controllers
+const (
+	productIDDualSense     = 0x0CE6
+	productIDDualSenseEdge = 0x0DF2
+)
+
+var (
+	ErrInvalidPlayerLeds = errors.New("ds4: invalid player LEDs")
+	ErrInvalidMicLed     = errors.New("ds4: invalid mic LED mode")
+)
+
+// Enables input reports over Bluetooth, the lower 6 bits are the report interval in milliseconds
+const bluetoothOutputFlagHID = 0x80
+
+// The DualSense ignores bytes of USB output reports beyond this size
+const dualSenseUSBOutputSize = 63
+
+const (
+	getFeatureReportCode0x05 = 0x05
+	getFeatureReportCode0x09 = 0x09
+)
+
+// DualSense output report flags, the first byte enables rumble and trigger effects, the second the LEDs
+const (
+	dualSenseFlagRumble        = 0x03
+	dualSenseFlagR2Effect      = 0x04
+	dualSenseFlagL2Effect      = 0x08
+	dualSenseFlagMicLed        = 0x01
+	dualSenseFlagLightbar      = 0x04
+	dualSenseFlagPlayerLeds    = 0x10
+	dualSenseFlagLightbarSetup = 0x02
+	dualSenseLightbarFadeOut   = 0x02
+)
+
+type Model uint
+
+const (
+	ModelDualShock4 Model = iota
+	ModelDualSense
+)
+
+func (m Model) String() string {
+	switch m {
+	case ModelDualShock4:
+		return "DualShock 4"
+	case ModelDualSense:
+		return "DualSense"
+	default:
+		return ""
+	}
+}
+
+// PlayerLeds is a mask of the five DualSense player LEDs, PlayerLed1 is the leftmost.
+type PlayerLeds byte
+
+const (
+	PlayerLed1 PlayerLeds = 1 << iota
+	PlayerLed2
+	PlayerLed3
+	PlayerLed4
+	PlayerLed5
+
+	PlayerLedsNone PlayerLeds = 0
+	PlayerLedsAll             = PlayerLed1 | PlayerLed2 | PlayerLed3 | PlayerLed4 | PlayerLed5
+)
+
+// MicLed is the mode of the DualSense mute button LED.
+type MicLed byte
+
+const (
+	MicLedOff MicLed = iota
+	MicLedOn
+	MicLedPulse
+)
+
+func detectModel(device Device) Model {
+	switch device.ProductID() {
+	case productIDDualSense, productIDDualSenseEdge:
+		return ModelDualSense
+	default:
+		return ModelDualShock4
+	}
+}
+
+// inputLayout holds the positions of the input report fields of a model. Positions are
+// given for USB reports including the report ID and are shifted by the input offset
+// over Bluetooth. Timestamp ticks are given in thirds to keep both models exact.
+type inputLayout struct {
+	model          Model
+	leftStick      uint
+	rightStick     uint
+	l2             uint
+	r2             uint
+	buttons        [3]uint
+	counter        uint
+	counterShift   uint
+	timestamp      uint
+	timestampSize  uint
+	timestampTicks time.Duration
+	// Reports are stamped on arrival with the time since epoch if timestampSize is 0
+	epoch         time.Time
+	accelerometer uint
+	gyroscope     uint
+	touch         uint
+	status        uint
+}
+
+var dualShock4InputLayout = inputLayout{
+	model:          ModelDualShock4,
+	leftStick:      1,
+	rightStick:     3,
+	l2:             8,
+	r2:             9,
+	buttons:        [3]uint{5, 6, 7},
+	counter:        7,
+	counterShift:   2,
+	timestamp:      10,
+	timestampSize:  2,
+	timestampTicks: 16 * time.Microsecond,
+	accelerometer:  13,
+	gyroscope:      19,
+	touch:          35,
+	status:         30,
+}
+
+var dualSenseInputLayout = inputLayout{
+	model:          ModelDualSense,
+	leftStick:      1,
+	rightStick:     3,
+	l2:             5,
+	r2:             6,
+	buttons:        [3]uint{8, 9, 10},
+	counter:        7,
+	timestamp:      28,
+	timestampSize:  4,
+	timestampTicks: time.Microsecond,
+	accelerometer:  16,
+	gyroscope:      22,
+	touch:          33,
+	status:         53,
+}
+
+func newInputLayout(model Model, offset uint) *inputLayout {
+	l := dualShock4InputLayout
+	if model == ModelDualSense {
+		l = dualSenseInputLayout
+	}
+
+	l.at(offset)
+
+	return &l
+}
+
+// reducedInputLayout returns the layout of reduced Bluetooth reports, both models put
+// sticks, buttons and triggers where a DualShock 4 does over USB. Other fields stay neutral.
+// Reduced reports carry no timestamp, so they are stamped on arrival.
+func reducedInputLayout(epoch time.Time) *inputLayout {
+	l := dualShock4InputLayout
+	l.stampOnArrival(epoch)
+
+	return &l
+}
+
+// stampOnArrival makes reports decoded with the layout use the monotonic clock instead of their timestamp.
+// Layouts which share the epoch can decode reports of the same stream.
+func (l *inputLayout) stampOnArrival(epoch time.Time) {
+	l.timestampSize = 0
+	l.epoch = epoch
+}
+
+func (l *inputLayout) at(offset uint) {
+	for _, position := range []*uint{
+		&l.leftStick, &l.rightStick, &l.l2, &l.r2,
+		&l.buttons[0], &l.buttons[1], &l.buttons[2],
+		&l.counter, &l.timestamp, &l.accelerometer, &l.gyroscope, &l.touch, &l.status,
+	} {
+		*position += offset
+	}
+}
+
+// neutral fills bytes with a report with centered sticks and nothing pressed.
+func (l *inputLayout) neutral(bytes []byte) {
+	bytes[l.leftStick] = 128
+	bytes[l.leftStick+1] = 128
+	bytes[l.rightStick] = 128
+	bytes[l.rightStick+1] = 128
+	bytes[l.buttons[0]] = 8
+	bytes[l.touch] = 0x80
+	bytes[l.touch+4] = 0x80
+}
+
+// Reading the calibration feature report over Bluetooth switches the controller to full input reports
+func (m Model) bluetoothFeatureReport() byte {
+	if m == ModelDualSense {
+		return getFeatureReportCode0x05
+	}
+
+	return getFeatureReportCode0x04
+}
+
+func (m Model) inputOffset(connectionType ConnectionType) uint {
+	if connectionType != ConnectionTypeBluetooth {
+		return 0
+	}
+
+	if m == ModelDualSense {
+		return 1
+	}
+
+	return 2
+}
+
+// newOutputState returns an output report and the offset of its fields. Over Bluetooth the first
+// byte is the CRC seed of the report and is not written, so both models use the same offset.
+func newOutputState(m Model, connectionType ConnectionType) ([]byte, uint) {
+	outputState := make([]byte, 79)
+
+	switch {
+	case m == ModelDualSense && connectionType == ConnectionTypeBluetooth:
+		outputState[0] = 0xA2
+		outputState[1] = 0x31
+		outputState[3] = 0x10
+		dualSenseOutputFlags(outputState, 3)
+
+		return outputState, 3
+	case m == ModelDualSense:
+		outputState[0] = 0x02
+		dualSenseOutputFlags(outputState, 0)
+
+		return outputState, 0
+	case connectionType == ConnectionTypeBluetooth:
+		outputState[0] = 0xA2
+		outputState[1] = 0x11
+		outputState[2] = bluetoothOutputFlagHID
+		outputState[4] = 0x0F
+
+		return outputState, 3
+	default:
+		outputState[0] = 0x05
+		outputState[1] = 0x07
+
+		return outputState, 0
+	}
+}
+
+// dualSenseOutputFlags enables rumble, lightbar, player LEDs and mic LED in every report
+// and fades out the blue startup lightbar with the first report.
+func dualSenseOutputFlags(outputState []byte, offset uint) {
+	outputState[1+offset] = dualSenseFlagRumble
+	outputState[2+offset] = dualSenseFlagMicLed | dualSenseFlagLightbar | dualSenseFlagPlayerLeds
+	outputState[39+offset] = dualSenseFlagLightbarSetup
+	outputState[42+offset] = dualSenseLightbarFadeOut
+}
@@ -0,0 +1,180 @@
+package gods4
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+// dualSenseUSBInput is a DualSense USB report: left stick at (0x10, 0xF0), right stick at (0x80, 0x7F),
+// L2 at 0x40 and R2 at 0xFF (both pressed), counter 200, D-pad right with cross and triangle, L1 and R3,
+// PS and the touchpad button, the first finger touching at (1919, 1079), 55% charging and headphones.
+var dualSenseUSBInput = []byte{
+	0x01, 0x10, 0xF0, 0x80, 0x7F, 0x40, 0xFF, 0xC8, 0xA2, 0x81, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x42, 0x0F, 0x00,
+	0x00, 0x05, 0x7F, 0x77, 0x43, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x15, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// dualSenseBluetoothInput returns the same input as a DualSense extended Bluetooth report,
+// which puts a sequence byte after the report ID and ends with a CRC.
+func dualSenseBluetoothInput() []byte {
+	report := make([]byte, bluetoothInputReportSize)
+	report[0] = dualSenseBluetoothInputReportID
+	report[1] = 0x10
+	copy(report[2:], dualSenseUSBInput[1:])
+
+	crc := crc32.ChecksumIEEE(append([]byte{0xA1}, report[:bluetoothInputCRCOffset]...))
+	binary.LittleEndian.PutUint32(report[bluetoothInputCRCOffset:], crc)
+
+	return report
+}
+
+func TestDualSenseReports(t *testing.T) {
+	tests := []struct {
+		name           string
+		connectionType ConnectionType
+		report         []byte
+	}{
+		{"USB", ConnectionTypeUSB, dualSenseUSBInput},
+		{"BT", ConnectionTypeBluetooth, dualSenseBluetoothInput()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newMockDevice(tt.report)
+			device.productID = 0x0CE6
+
+			c := NewController(device)
+
+			err := c.ConfigureConnection(ConnectionConfig{Type: tt.connectionType})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var states []State
+			c.OnRawState(func(s State) {
+				states = append(states, s)
+			})
+
+			err = c.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.Model() != ModelDualSense {
+				t.Fatalf("got model %v, want DualSense", c.Model())
+			}
+
+			err = c.Listen()
+			if err != io.EOF {
+				t.Fatalf("got error %v, want EOF", err)
+			}
+
+			if len(states) != 1 {
+				t.Fatalf("decoded %d reports, want 1", len(states))
+			}
+
+			s := states[0]
+
+			if s.LeftStick.X != 0x10 || s.LeftStick.Y != 0xF0 || s.RightStick.X != 0x80 || s.RightStick.Y != 0x7F {
+				t.Errorf("got sticks %+v and %+v", s.LeftStick, s.RightStick)
+			}
+
+			if s.L2.Value != 0x40 || s.R2.Value != 0xFF || !s.R2.IsPressed {
+				t.Errorf("got triggers %+v and %+v", s.L2, s.R2)
+			}
+
+			if s.Counter != 200 {
+				t.Errorf("got counter %d, want 200", s.Counter)
+			}
+
+			pressed := []Button{
+				ButtonCross, ButtonTriangle, ButtonDPadRight, ButtonL1, ButtonL2, ButtonR2, ButtonR3, ButtonPS, ButtonTouchpad,
+			}
+			for _, button := range Buttons() {
+				want := false
+				for _, b := range pressed {
+					want = want || b == button
+				}
+
+				if s.IsPressed(button) != want {
+					t.Errorf("got %s pressed %v, want %v", button, s.IsPressed(button), want)
+				}
+			}
+
+			if len(s.Touchpad.Swipe) != 2 {
+				t.Fatalf("got %d touches, want 2", len(s.Touchpad.Swipe))
+			}
+
+			touch := Touch{IsActive: true, X: 1919, Y: 1079}
+			if s.Touchpad.Swipe[0] != touch || s.Touchpad.Swipe[1].IsActive {
+				t.Errorf("got touches %+v, want %+v and an inactive one", s.Touchpad.Swipe, touch)
+			}
+
+			battery := Battery{Capacity: 55, IsCharging: true, IsCableConnected: true}
+			if s.Battery != battery {
+				t.Errorf("got battery %+v, want %+v", s.Battery, battery)
+			}
+
+			if s.Headset != (Headset{IsHeadphonesConnected: true}) || s.Extension {
+				t.Errorf("got headset %+v and extension %v", s.Headset, s.Extension)
+			}
+		})
+	}
+}
+
+func TestDualSenseReducedReport(t *testing.T) {
+	// A reduced report puts sticks, buttons and triggers where a DualShock 4 does over USB
+	report := []byte{0x01, 0x10, 0xF0, 0x80, 0x7F, 0xA2, 0x81, 0x03, 0x40, 0xFF}
+
+	device := newMockDevice(report)
+	device.productID = 0x0CE6
+
+	c := NewController(device)
+
+	err := c.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeBluetooth, ReportMode: ReportModeReduced})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var states []State
+	c.OnRawState(func(s State) {
+		states = append(states, s)
+	})
+
+	err = c.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want EOF", err)
+	}
+
+	if len(states) != 1 {
+		t.Fatalf("decoded %d reports, want 1", len(states))
+	}
+
+	s := states[0]
+
+	if s.LeftStick.X != 0x10 || s.LeftStick.Y != 0xF0 || s.RightStick.X != 0x80 || s.RightStick.Y != 0x7F {
+		t.Errorf("got sticks %+v and %+v", s.LeftStick, s.RightStick)
+	}
+
+	if s.L2.Value != 0x40 || s.R2.Value != 0xFF {
+		t.Errorf("got triggers %+v and %+v", s.L2, s.R2)
+	}
+
+	for _, button := range []Button{ButtonCross, ButtonTriangle, ButtonDPadRight, ButtonL1, ButtonR3, ButtonPS, ButtonTouchpad} {
+		if !s.IsPressed(button) {
+			t.Errorf("%s is not pressed", button)
+		}
+	}
+
+	if s.IsPressed(ButtonCircle) || s.IsPressed(ButtonSquare) || s.IsPressed(ButtonR1) {
+		t.Error("a released button is pressed")
+	}
+}
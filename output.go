@@ -0,0 +1,154 @@
+package gods4
+
+import (
+	"github.com/kpeu3i/gods4/led"
+	"github.com/kpeu3i/gods4/rumble"
+	"github.com/kpeu3i/gods4/trigger"
+	"github.com/kpeu3i/gods4/volume"
+)
+
+// Output holds the last values sent to the controller.
+// A nil field means the value has not been set since the controller was created.
+// PlayerLeds, MicLed and trigger effects are set on DualSense controllers only.
+type Output struct {
+	Led        *led.Led
+	Rumble     *rumble.Rumble
+	Volume     *volume.Volume
+	PlayerLeds *PlayerLeds
+	MicLed     *MicLed
+	L2Effect   *trigger.Effect
+	R2Effect   *trigger.Effect
+}
+
+func copyLed(l *led.Led) *led.Led {
+	if l == nil {
+		return nil
+	}
+
+	return led.RGB(l.Red(), l.Green(), l.Blue()).Flash(l.FlashOn(), l.FlashOff())
+}
+
+func copyRumble(r *rumble.Rumble) *rumble.Rumble {
+	if r == nil {
+		return nil
+	}
+
+	return rumble.New(r.Left(), r.Right())
+}
+
+func copyVolume(v *volume.Volume) *volume.Volume {
+	if v == nil {
+		return nil
+	}
+
+	return volume.New(v.Left(), v.Right(), v.Mic(), v.Speaker())
+}
+
+func copyPlayerLeds(l *PlayerLeds) *PlayerLeds {
+	if l == nil {
+		return nil
+	}
+
+	leds := *l
+
+	return &leds
+}
+
+func copyMicLed(m *MicLed) *MicLed {
+	if m == nil {
+		return nil
+	}
+
+	mode := *m
+
+	return &mode
+}
+
+func copyEffect(e *trigger.Effect) *trigger.Effect {
+	if e == nil {
+		return nil
+	}
+
+	effect := *e
+
+	return &effect
+}
+
+func equalLed(a, b *led.Led) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalRumble(a, b *rumble.Rumble) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// Enough for the output changes caused by a burst of profile switches and turbo toggles
+const outputQueueSize = 16
+
+// outputJob sends an output change, quit is closed once the controller is disconnected.
+type outputJob func(quit <-chan struct{}) error
+
+// outputWorker sends the output changes caused by input, in order. The input
+// goroutine must not wait for c.mutex, Disconnect holds it while stopping that goroutine.
+// The first error is passed to the input goroutine, which returns it from Listen.
+type outputWorker struct {
+	jobs   chan outputJob
+	errors chan error
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+func newOutputWorker() *outputWorker {
+	w := &outputWorker{
+		jobs:   make(chan outputJob, outputQueueSize),
+		errors: make(chan error, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *outputWorker) run() {
+	defer close(w.done)
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case job := <-w.jobs:
+			select {
+			case <-w.quit:
+				return
+			default:
+			}
+
+			err := job(w.quit)
+			if err != nil && err != ErrControllerIsNotConnected {
+				select {
+				case w.errors <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// stop makes the worker drop queued jobs and cut short a running cue.
+func (w *outputWorker) stop() {
+	close(w.quit)
+}
+
+func (w *outputWorker) wait() {
+	<-w.done
+}
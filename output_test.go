@@ -0,0 +1,92 @@
+package gods4
+
+import (
+	"testing"
+
+	"github.com/kpeu3i/gods4/led"
+	"github.com/kpeu3i/gods4/rumble"
+	"github.com/kpeu3i/gods4/volume"
+)
+
+func TestOutputGetters(t *testing.T) {
+	device := newMockDevice()
+	controller := NewController(device)
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outputs []Output
+
+	controller.On(EventOutputUpdate, func(data interface{}) error {
+		outputs = append(outputs, data.(Output))
+
+		return nil
+	})
+
+	if output := controller.CurrentOutput(); output != (Output{}) {
+		t.Fatalf("got output %+v before anything was sent", output)
+	}
+
+	if controller.Led(led.RGB(1, 2, 3)) != ErrControllerIsNotConnected {
+		t.Fatal("LED was set while disconnected")
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lightbar := led.RGB(10, 20, 30).Flash(40, 50)
+
+	err = controller.Led(lightbar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Rumble(rumble.New(60, 70))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Volume(volume.New(80, 90, 100, 110))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := controller.CurrentLed()
+	if l == lightbar {
+		t.Error("CurrentLed returns the caller's value instead of a copy")
+	}
+
+	if l.Red() != 10 || l.Green() != 20 || l.Blue() != 30 || l.FlashOn() != 40 || l.FlashOff() != 50 {
+		t.Errorf("got LED %+v", *l)
+	}
+
+	if r := controller.CurrentRumble(); r.Left() != 60 || r.Right() != 70 {
+		t.Errorf("got rumble %+v", *r)
+	}
+
+	if v := controller.CurrentVolume(); v.Left() != 80 || v.Right() != 90 || v.Mic() != 100 || v.Speaker() != 110 {
+		t.Errorf("got volume %+v", *v)
+	}
+
+	if len(outputs) != 3 {
+		t.Fatalf("got %d output events, want 3", len(outputs))
+	}
+
+	if outputs[0].Rumble != nil || outputs[1].Volume != nil || !equalLed(outputs[2].Led, controller.CurrentLed()) {
+		t.Errorf("output events don't follow the changes: %+v", outputs)
+	}
+
+	written := device.written()
+	if len(written) != 3 {
+		t.Fatalf("got %d output reports, want 3", len(written))
+	}
+
+	last := written[2]
+	if last[6] != 10 || last[9] != 40 || last[4] != 60 || last[19] != 80 || last[22] != 110 {
+		t.Errorf("last output report doesn't hold all values: % x", last[:23])
+	}
+}
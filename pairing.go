@@ -0,0 +1,119 @@
+package gods4
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Feature report setting the paired host address and link key, available over USB only
+const setFeatureReportCode0x13 = 0x13
+
+const linkKeySize = 16
+
+// featureReportSender is implemented by devices that can send feature reports, such as hid.Device.
+type featureReportSender interface {
+	SendFeatureReport(b []byte) (int, error)
+}
+
+var (
+	ErrPairingIsNotSupported = errors.New("ds4: pairing is supported over USB only")
+	ErrInvalidMAC            = errors.New("ds4: invalid MAC address")
+	ErrInvalidLinkKey        = errors.New("ds4: invalid link key")
+)
+
+// PairedHost returns the Bluetooth address of the host the controller connects to, read over USB.
+func (c *Controller) PairedHost() (string, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	err := c.errorIfPairingIsNotSupported()
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := c.device.GetFeatureReport(getFeatureReportCode0x12)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bytes) < 16 {
+		return "", errors.Errorf("ds4: feature report 0x12 is too short: %d bytes", len(bytes))
+	}
+
+	// The host address follows the controller address and 3 bytes of device class, in reverse byte order
+	mac := make([]byte, 6)
+	for i := range mac {
+		mac[i] = bytes[15-i]
+	}
+
+	return formatMAC(mac), nil
+}
+
+// SetPairedHost pairs the controller over USB to the host with the given Bluetooth address
+// (like "aa:bb:cc:dd:ee:ff") and 16-byte link key. The host must store the same link key
+// for the controller address, see Serial, before the controller connects over Bluetooth.
+// The device must be able to send feature reports, otherwise ErrIsNotSupported is returned.
+func (c *Controller) SetPairedHost(mac string, linkKey []byte) error {
+	address, err := parseMAC(mac)
+	if err != nil {
+		return err
+	}
+
+	if len(linkKey) != linkKeySize {
+		return errors.Wrapf(ErrInvalidLinkKey, "must be %d bytes, got %d", linkKeySize, len(linkKey))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err = c.errorIfPairingIsNotSupported()
+	if err != nil {
+		return err
+	}
+
+	sender, ok := c.device.(featureReportSender)
+	if !ok {
+		return errors.Wrap(ErrIsNotSupported, "sending feature reports")
+	}
+
+	bytes := make([]byte, 0, 1+len(address)+linkKeySize)
+	bytes = append(bytes, setFeatureReportCode0x13)
+
+	for i := len(address) - 1; i >= 0; i-- {
+		bytes = append(bytes, address[i])
+	}
+
+	bytes = append(bytes, linkKey...)
+
+	_, err = sender.SendFeatureReport(bytes)
+
+	return err
+}
+
+func (c *Controller) errorIfPairingIsNotSupported() error {
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return err
+	}
+
+	if c.model != ModelDualShock4 {
+		return errors.Wrap(ErrIsNotSupported, "pairing")
+	}
+
+	if c.connectionType != ConnectionTypeUSB {
+		return ErrPairingIsNotSupported
+	}
+
+	return nil
+}
+
+func parseMAC(mac string) ([]byte, error) {
+	address, err := hex.DecodeString(strings.NewReplacer(":", "", "-", "").Replace(strings.TrimSpace(mac)))
+	if err != nil || len(address) != 6 {
+		return nil, errors.Wrapf(ErrInvalidMAC, "%q", mac)
+	}
+
+	return address, nil
+}
@@ -0,0 +1,108 @@
+package gods4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func connectedOverUSB(t *testing.T, device Device) *Controller {
+	t.Helper()
+
+	controller := NewController(device)
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return controller
+}
+
+func TestPairedHost(t *testing.T) {
+	device := newMockDevice()
+	// Controller and host addresses in reverse byte order, separated by the device class
+	device.features[0x12] = []byte{
+		0x12,
+		0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+		0x08, 0x25, 0x00,
+		0xFF, 0xEE, 0xDD, 0xCC, 0xBB, 0xAA,
+	}
+
+	controller := connectedOverUSB(t, device)
+
+	if serial := controller.Serial(); serial != "01:02:03:04:05:06" {
+		t.Errorf("got serial %q, want 01:02:03:04:05:06", serial)
+	}
+
+	host, err := controller.PairedHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if host != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("got host %q, want aa:bb:cc:dd:ee:ff", host)
+	}
+}
+
+func TestSetPairedHost(t *testing.T) {
+	device := newMockDevice()
+	controller := connectedOverUSB(t, device)
+
+	linkKey := bytes.Repeat([]byte{0x5A}, linkKeySize)
+
+	err := controller.SetPairedHost("AA:BB:CC:DD:EE:FF", linkKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]byte{0x13, 0xFF, 0xEE, 0xDD, 0xCC, 0xBB, 0xAA}, linkKey...)
+	if len(device.sent) != 1 || !bytes.Equal(device.sent[0], want) {
+		t.Errorf("sent % x, want % x", device.sent, want)
+	}
+
+	err = controller.SetPairedHost("aa:bb:cc:dd:ee", linkKey)
+	if errors.Cause(err) != ErrInvalidMAC {
+		t.Errorf("got error %v, want %v", err, ErrInvalidMAC)
+	}
+
+	err = controller.SetPairedHost("aa:bb:cc:dd:ee:ff", linkKey[1:])
+	if errors.Cause(err) != ErrInvalidLinkKey {
+		t.Errorf("got error %v, want %v", err, ErrInvalidLinkKey)
+	}
+}
+
+func TestSetPairedHostIsNotSupported(t *testing.T) {
+	linkKey := make([]byte, linkKeySize)
+
+	// Only the methods of Device are promoted, so feature reports can't be sent
+	controller := connectedOverUSB(t, struct{ Device }{newMockDevice()})
+
+	err := controller.SetPairedHost("aa:bb:cc:dd:ee:ff", linkKey)
+	if errors.Cause(err) != ErrIsNotSupported {
+		t.Errorf("got error %v, want %v", err, ErrIsNotSupported)
+	}
+
+	controller = NewController(newMockDevice())
+
+	err = controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeBluetooth, ReportMode: ReportModeReduced})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.SetPairedHost("aa:bb:cc:dd:ee:ff", linkKey)
+	if err != ErrPairingIsNotSupported {
+		t.Errorf("got error %v, want %v", err, ErrPairingIsNotSupported)
+	}
+}
@@ -0,0 +1,68 @@
+package gods4
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReducedReportsAreStampedOnArrival(t *testing.T) {
+	layout := reducedInputLayout(time.Now().Add(-time.Second))
+	bytes := usbInput(128, 128, 128, 128)[:reducedInputReportSize]
+
+	_, timestamp := timestampState(bytes, layout, nil)
+	if timestamp < time.Second {
+		t.Errorf("got timestamp %s, want at least 1s since the epoch", timestamp)
+	}
+}
+
+func TestReducedModeDecodesExtendedReports(t *testing.T) {
+	reduced := usbInput(128, 128, 128, 128)[:reducedInputReportSize]
+	reduced[5] |= 0x20
+
+	// Cross is released in the extended report that follows
+	extended := bluetoothInput()
+	extended[7] = 0x08
+	binary.LittleEndian.PutUint32(extended[bluetoothInputCRCOffset:],
+		crc32.ChecksumIEEE(append([]byte{0xA1}, extended[:bluetoothInputCRCOffset]...)))
+
+	controller := NewController(newMockDevice(reduced, extended))
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeBluetooth, ReportMode: ReportModeReduced})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var states []State
+
+	controller.On(EventStateUpdate, func(data interface{}) error {
+		states = append(states, data.(State))
+
+		return nil
+	})
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want io.EOF", err)
+	}
+
+	stats := controller.ReportStats()
+	if stats.Accepted != 2 || stats.UnknownID != 0 {
+		t.Fatalf("got stats %+v, want both reports accepted", stats)
+	}
+
+	if len(states) != 2 || !states[0].Cross || states[1].Cross {
+		t.Fatalf("got states %+v, want cross pressed and released", states)
+	}
+
+	if states[0].Timestamp <= 0 || states[1].Timestamp < states[0].Timestamp {
+		t.Errorf("timestamps %s and %s don't advance", states[0].Timestamp, states[1].Timestamp)
+	}
+}
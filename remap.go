@@ -0,0 +1,486 @@
+package gods4
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4/led"
+)
+
+var (
+	ErrInvalidProfile  = errors.New("ds4: invalid profile")
+	ErrProfileNotFound = errors.New("ds4: profile not found")
+)
+
+type ruleKind uint
+
+const (
+	ruleRemapButton ruleKind = iota
+	ruleSwapButtons
+	ruleDisableButton
+	ruleButtonToAxis
+	ruleAxisToButton
+	ruleInvertAxis
+	ruleDisableAxis
+	ruleSwapSticks
+)
+
+// Rule is a single remapping of a profile. Remapping is applied to physical
+// inputs, so the order of rules does not matter and a physical button can be
+// assigned only once.
+type Rule struct {
+	kind   ruleKind
+	button Button
+	target Button
+	axis   Axis
+	value  float64
+}
+
+// RemapButton makes the physical button from act as the button to.
+func RemapButton(from, to Button) Rule {
+	return Rule{kind: ruleRemapButton, button: from, target: to}
+}
+
+func SwapButtons(a, b Button) Rule {
+	return Rule{kind: ruleSwapButtons, button: a, target: b}
+}
+
+func DisableButton(button Button) Rule {
+	return Rule{kind: ruleDisableButton, button: button}
+}
+
+// ButtonToAxis moves axis to value while the button is held.
+func ButtonToAxis(button Button, axis Axis, value float64) Rule {
+	return Rule{kind: ruleButtonToAxis, button: button, axis: axis, value: value}
+}
+
+// AxisToButton presses the button while axis is beyond threshold,
+// a negative threshold is used for the negative half of a stick axis.
+func AxisToButton(axis Axis, threshold float64, button Button) Rule {
+	return Rule{kind: ruleAxisToButton, axis: axis, value: threshold, target: button}
+}
+
+func InvertAxis(axis Axis) Rule {
+	return Rule{kind: ruleInvertAxis, axis: axis}
+}
+
+func DisableAxis(axis Axis) Rule {
+	return Rule{kind: ruleDisableAxis, axis: axis}
+}
+
+func SwapSticks() Rule {
+	return Rule{kind: ruleSwapSticks}
+}
+
+// Profile is a named set of remapping rules. The optional fields are applied
+// to the controller when the profile is activated, nil keeps the current value.
+type Profile struct {
+	Name        string
+	Rules       []Rule
+	L2          *TriggerConfig
+	R2          *TriggerConfig
+	LeftStick   *StickConfig
+	RightStick  *StickConfig
+	Led         *led.Led
+	RumbleScale *float64
+	Macros      map[Button]Macro
+}
+
+// Validate checks the profile the same way AddProfile does.
+func (p Profile) Validate() error {
+	_, err := newRemapping(p)
+
+	return err
+}
+
+func (p Profile) validate() error {
+	for _, config := range []*TriggerConfig{p.L2, p.R2} {
+		if config == nil {
+			continue
+		}
+
+		err := config.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "profile %q", p.Name)
+		}
+	}
+
+	for _, config := range []*StickConfig{p.LeftStick, p.RightStick} {
+		if config == nil {
+			continue
+		}
+
+		err := config.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "profile %q", p.Name)
+		}
+	}
+
+	if p.RumbleScale != nil && (*p.RumbleScale < 0 || math.IsNaN(*p.RumbleScale)) {
+		return errors.Wrapf(ErrInvalidRumbleScale, "profile %q: %v", p.Name, *p.RumbleScale)
+	}
+
+	for button, macro := range p.Macros {
+		if button.String() == "" {
+			return errors.Wrapf(ErrInvalidMacro, "profile %q: unknown trigger button: %d", p.Name, button)
+		}
+
+		err := macro.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "profile %q: macro on %s", p.Name, button)
+		}
+	}
+
+	return nil
+}
+
+type buttonAxis struct {
+	button Button
+	axis   Axis
+	value  float64
+}
+
+type axisButton struct {
+	axis      Axis
+	threshold float64
+	button    Button
+}
+
+// remapping is a profile compiled into physical to virtual input assignments.
+type remapping struct {
+	name          string
+	profile       Profile
+	buttonTargets [len(buttons)][]Button
+	buttonAxes    []buttonAxis
+	axisButtons   []axisButton
+	invertedAxes  [len(axes)]bool
+	disabledAxes  [len(axes)]bool
+	isSwapSticks  bool
+}
+
+func newRemapping(profile Profile) (*remapping, error) {
+	if profile.Name == "" {
+		return nil, errors.Wrap(ErrInvalidProfile, "name is required")
+	}
+
+	err := profile.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &remapping{name: profile.Name, profile: profile}
+	for _, button := range buttons {
+		r.buttonTargets[button] = []Button{button}
+	}
+
+	var isAssigned [len(buttons)]bool
+
+	assign := func(button Button, targets ...Button) error {
+		if button.String() == "" {
+			return errors.Wrapf(ErrInvalidProfile, "profile %q: unknown button: %d", profile.Name, button)
+		}
+
+		for _, target := range targets {
+			if target.String() == "" {
+				return errors.Wrapf(ErrInvalidProfile, "profile %q: unknown button: %d", profile.Name, target)
+			}
+		}
+
+		if isAssigned[button] {
+			return errors.Wrapf(ErrInvalidProfile, "profile %q: button %s is remapped more than once", profile.Name, button)
+		}
+
+		isAssigned[button] = true
+		r.buttonTargets[button] = targets
+
+		return nil
+	}
+
+	for _, rule := range profile.Rules {
+		if rule.kind >= ruleButtonToAxis && rule.kind <= ruleDisableAxis && rule.axis.String() == "" {
+			return nil, errors.Wrapf(ErrInvalidProfile, "profile %q: unknown axis: %d", profile.Name, rule.axis)
+		}
+
+		var err error
+
+		switch rule.kind {
+		case ruleRemapButton:
+			err = assign(rule.button, rule.target)
+		case ruleSwapButtons:
+			err = assign(rule.button, rule.target)
+			if err == nil {
+				err = assign(rule.target, rule.button)
+			}
+		case ruleDisableButton:
+			err = assign(rule.button)
+		case ruleButtonToAxis:
+			min := -1.0
+			if rule.axis.isTrigger() {
+				min = 0
+			}
+
+			if rule.value < min || rule.value > 1 {
+				return nil, errors.Wrapf(ErrInvalidProfile, "profile %q: value %v is out of range [%v, 1] for axis %s", profile.Name, rule.value, min, rule.axis)
+			}
+
+			err = assign(rule.button)
+			r.buttonAxes = append(r.buttonAxes, buttonAxis{button: rule.button, axis: rule.axis, value: rule.value})
+		case ruleAxisToButton:
+			if rule.value == 0 || rule.value < -1 || rule.value > 1 || rule.axis.isTrigger() && rule.value < 0 {
+				return nil, errors.Wrapf(ErrInvalidProfile, "profile %q: threshold %v is out of range for axis %s", profile.Name, rule.value, rule.axis)
+			}
+
+			if rule.target.String() == "" {
+				return nil, errors.Wrapf(ErrInvalidProfile, "profile %q: unknown button: %d", profile.Name, rule.target)
+			}
+
+			r.axisButtons = append(r.axisButtons, axisButton{axis: rule.axis, threshold: rule.value, button: rule.target})
+		case ruleInvertAxis:
+			r.invertedAxes[rule.axis] = true
+		case ruleDisableAxis:
+			r.disabledAxes[rule.axis] = true
+		case ruleSwapSticks:
+			r.isSwapSticks = true
+		default:
+			return nil, errors.Wrapf(ErrInvalidProfile, "profile %q: unknown rule", profile.Name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// apply maps the decoded state into the state seen by the emitter. Derived values
+// (trigger presses, stick directions) are recomputed against the previous output
+// so their hysteresis keeps working on remapped inputs.
+func (r *remapping) apply(src, prevOut *state, settings *settings, suppressed map[Button]bool) *state {
+	out := *src
+
+	if r.isSwapSticks {
+		out.leftStick, out.rightStick = src.rightStick, src.leftStick
+	}
+
+	for _, axis := range axes {
+		if r.invertedAxes[axis] {
+			out.invertAxis(axis)
+		}
+
+		if r.disabledAxes[axis] {
+			out.setAxis(axis, 0)
+		}
+	}
+
+	for _, buttonAxis := range r.buttonAxes {
+		if src.button(buttonAxis.button) && !suppressed[buttonAxis.button] {
+			out.setAxis(buttonAxis.axis, buttonAxis.value)
+		}
+	}
+
+	out.l2 = triggerState(out.l2.Value, prevOut.l2, settings.l2)
+	out.r2 = triggerState(out.r2.Value, prevOut.r2, settings.r2)
+	out.leftStick.Direction4 = stickDirection(out.leftStick, prevOut.leftStick.Direction4, 4, settings.leftStick)
+	out.leftStick.Direction8 = stickDirection(out.leftStick, prevOut.leftStick.Direction8, 8, settings.leftStick)
+	out.rightStick.Direction4 = stickDirection(out.rightStick, prevOut.rightStick.Direction4, 4, settings.rightStick)
+	out.rightStick.Direction8 = stickDirection(out.rightStick, prevOut.rightStick.Direction8, 8, settings.rightStick)
+
+	var isPressed [len(buttons)]bool
+
+	for _, button := range buttons {
+		if suppressed[button] {
+			continue
+		}
+
+		for _, target := range r.buttonTargets[button] {
+			// Triggers assigned to themselves follow the remapped axis
+			if target == button {
+				isPressed[target] = isPressed[target] || out.button(button)
+			} else {
+				isPressed[target] = isPressed[target] || src.button(button)
+			}
+		}
+	}
+
+	for _, axisButton := range r.axisButtons {
+		value := src.axis(axisButton.axis)
+		if axisButton.threshold > 0 && value >= axisButton.threshold ||
+			axisButton.threshold < 0 && value <= axisButton.threshold {
+			isPressed[axisButton.button] = true
+		}
+	}
+
+	for _, button := range buttons {
+		out.setButton(button, isPressed[button])
+	}
+
+	return &out
+}
+
+// remapper holds profiles and switches between them when PS is held
+// together with a bound button.
+type remapper struct {
+	mutex    sync.RWMutex
+	profiles map[string]*remapping
+	active   *remapping
+	identity *remapping
+	bindings map[Button]string
+	// Bound buttons pressed together with PS, hidden until they are released
+	captured map[Button]bool
+}
+
+func newRemapper() *remapper {
+	identity, _ := newRemapping(Profile{Name: "identity"})
+
+	return &remapper{
+		profiles: make(map[string]*remapping),
+		identity: identity,
+		bindings: make(map[Button]string),
+		captured: make(map[Button]bool),
+	}
+}
+
+func (r *remapper) addProfile(profile Profile) error {
+	remapping, err := newRemapping(profile)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.profiles[profile.Name] = remapping
+	if r.active != nil && r.active.name == profile.Name {
+		r.active = remapping
+	}
+
+	return nil
+}
+
+// removeProfile deletes a profile along with its bindings. Removing the active
+// profile disables remapping.
+func (r *remapper) removeProfile(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.profiles, name)
+	if r.active != nil && r.active.name == name {
+		r.active = nil
+	}
+
+	for button, bound := range r.bindings {
+		if bound == name {
+			delete(r.bindings, button)
+		}
+	}
+}
+
+func (r *remapper) setProfile(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if name == "" {
+		r.active = nil
+
+		return nil
+	}
+
+	remapping, ok := r.profiles[name]
+	if !ok {
+		return errors.Wrapf(ErrProfileNotFound, "profile %q", name)
+	}
+
+	r.active = remapping
+
+	return nil
+}
+
+func (r *remapper) get(name string) (Profile, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	remapping, ok := r.profiles[name]
+	if !ok {
+		return Profile{}, false
+	}
+
+	return remapping.profile, true
+}
+
+func (r *remapper) profile() string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.active == nil {
+		return ""
+	}
+
+	return r.active.name
+}
+
+func (r *remapper) bind(button Button, name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if button == ButtonPS || button.String() == "" {
+		return errors.Wrapf(ErrInvalidProfile, "button %d can't be bound to a profile", button)
+	}
+
+	if name == "" {
+		delete(r.bindings, button)
+
+		return nil
+	}
+
+	r.bindings[button] = name
+
+	return nil
+}
+
+// apply switches the profile on PS + bound button and returns the remapped
+// state along with the name of the newly activated profile, if any.
+// The bound button is hidden until it is released, even if PS is released first.
+func (r *remapper) apply(src, prevSrc, prevOut *state, settings *settings) (*state, string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var (
+		switched   string
+		isSwitched bool
+	)
+
+	for button := range r.captured {
+		if !src.button(button) {
+			delete(r.captured, button)
+		}
+	}
+
+	if src.ps {
+		for button, name := range r.bindings {
+			if !src.button(button) || prevSrc.button(button) {
+				continue
+			}
+
+			r.captured[button] = true
+
+			remapping, ok := r.profiles[name]
+			if ok {
+				r.active = remapping
+				switched, isSwitched = name, true
+			}
+		}
+	}
+
+	if r.active == nil {
+		if len(r.captured) == 0 {
+			return src, switched, isSwitched
+		}
+
+		return r.identity.apply(src, prevOut, settings, r.captured), switched, isSwitched
+	}
+
+	return r.active.apply(src, prevOut, settings, r.captured), switched, isSwitched
+}
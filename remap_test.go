@@ -0,0 +1,256 @@
+package gods4
+
+import (
+	"io"
+	"testing"
+)
+
+// Positions of buttons in a USB report, as byte index and mask
+var (
+	bitSquare = [2]byte{5, 0x10}
+	bitCross  = [2]byte{5, 0x20}
+	bitCircle = [2]byte{5, 0x40}
+	bitL1     = [2]byte{6, 0x01}
+	bitR1     = [2]byte{6, 0x02}
+	bitPS     = [2]byte{7, 0x01}
+)
+
+// usbButtons returns a USB report with centered sticks and the given buttons held.
+func usbButtons(bits ...[2]byte) []byte {
+	report := usbInput(128, 128, 128, 128)
+	for _, bit := range bits {
+		report[bit[0]] |= bit[1]
+	}
+
+	return report
+}
+
+// listenEvents plays reports through a USB controller set up by configure
+// and returns the listed events in the order they fired.
+func listenEvents(t *testing.T, reports [][]byte, configure func(c *Controller), events ...Event) []Event {
+	t.Helper()
+
+	controller := NewController(newMockDevice(reports...))
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configure(controller)
+
+	var fired []Event
+	for _, event := range events {
+		event := event
+		controller.On(event, func(interface{}) error {
+			fired = append(fired, event)
+
+			return nil
+		})
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want io.EOF", err)
+	}
+
+	return fired
+}
+
+func mustProfile(t *testing.T, c *Controller, profile Profile, active bool) {
+	t.Helper()
+
+	err := c.AddProfile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active {
+		err = c.SetProfile(profile.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRemapButtons(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		reports [][]byte
+		want    []Event
+	}{
+		{
+			"swap",
+			[]Rule{SwapButtons(ButtonCross, ButtonCircle)},
+			[][]byte{usbButtons(bitCross), usbButtons(bitCross, bitCircle), usbButtons()},
+			[]Event{EventCirclePress, EventCrossPress, EventCrossRelease, EventCircleRelease},
+		},
+		{
+			"remap",
+			[]Rule{RemapButton(ButtonSquare, ButtonCross)},
+			[][]byte{usbButtons(bitSquare), usbButtons()},
+			[]Event{EventCrossPress, EventCrossRelease},
+		},
+		{
+			// Both physical buttons act as cross, it is released once neither is held
+			"remap onto a held button",
+			[]Rule{RemapButton(ButtonSquare, ButtonCross)},
+			[][]byte{usbButtons(bitCross), usbButtons(bitCross, bitSquare), usbButtons(bitSquare), usbButtons()},
+			[]Event{EventCrossPress, EventCrossRelease},
+		},
+		{
+			"disable",
+			[]Rule{DisableButton(ButtonCross)},
+			[][]byte{usbButtons(bitCross), usbButtons()},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := listenEvents(t, tt.reports, func(c *Controller) {
+				mustProfile(t, c, Profile{Name: "remapped", Rules: tt.rules}, true)
+			}, EventCrossPress, EventCrossRelease, EventCirclePress, EventCircleRelease, EventSquarePress, EventSquareRelease)
+
+			if !equalEvents(events, tt.want) {
+				t.Errorf("got %v, want %v", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemapAxes(t *testing.T) {
+	settings := defaultSettings()
+	src := decode(usbInput(0, 64, 255, 128), newInputLayout(ModelDualShock4, 0), nil)
+
+	tests := []struct {
+		name    string
+		rules   []Rule
+		left    [2]byte
+		right   [2]byte
+		leftY   float64
+		rightX  float64
+		isCross bool
+	}{
+		{"identity", nil, [2]byte{0, 64}, [2]byte{255, 128}, src.leftStick.AxisY, 1, false},
+		{"swap sticks", []Rule{SwapSticks()}, [2]byte{255, 128}, [2]byte{0, 64}, src.rightStick.AxisY, src.leftStick.AxisX, false},
+		{"invert", []Rule{InvertAxis(AxisLeftY), InvertAxis(AxisRightX)}, [2]byte{0, 192}, [2]byte{0, 128}, -src.leftStick.AxisY, -1, false},
+		{"disable", []Rule{DisableAxis(AxisRightX)}, [2]byte{0, 64}, [2]byte{128, 128}, src.leftStick.AxisY, 0, false},
+		{"to button", []Rule{AxisToButton(AxisRightX, 0.9, ButtonCross)}, [2]byte{0, 64}, [2]byte{255, 128}, src.leftStick.AxisY, 1, true},
+		{"to button below threshold", []Rule{AxisToButton(AxisLeftY, -0.9, ButtonCross)}, [2]byte{0, 64}, [2]byte{255, 128}, src.leftStick.AxisY, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRemapping(Profile{Name: tt.name, Rules: tt.rules})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := r.apply(src, &state{}, settings, nil)
+
+			if left := [2]byte{out.leftStick.X, out.leftStick.Y}; left != tt.left {
+				t.Errorf("left stick = %v, want %v", left, tt.left)
+			}
+
+			if right := [2]byte{out.rightStick.X, out.rightStick.Y}; right != tt.right {
+				t.Errorf("right stick = %v, want %v", right, tt.right)
+			}
+
+			if out.leftStick.AxisY != tt.leftY || out.rightStick.AxisX != tt.rightX {
+				t.Errorf("axes = %v, %v, want %v, %v", out.leftStick.AxisY, out.rightStick.AxisX, tt.leftY, tt.rightX)
+			}
+
+			if out.cross != tt.isCross {
+				t.Errorf("cross = %v, want %v", out.cross, tt.isCross)
+			}
+		})
+	}
+}
+
+func TestRemapTriggerToButton(t *testing.T) {
+	trigger := func(value byte) []byte {
+		report := usbButtons()
+		report[8] = value
+
+		return report
+	}
+
+	events := listenEvents(t, [][]byte{trigger(100), trigger(200), trigger(130), trigger(120), trigger(0)}, func(c *Controller) {
+		mustProfile(t, c, Profile{Name: "fps", Rules: []Rule{AxisToButton(AxisL2, 0.5, ButtonCross)}}, true)
+	}, EventCrossPress, EventCrossRelease, EventL2Press, EventL2Release)
+
+	// L2 is still reported as a trigger, cross follows its travel past half
+	want := []Event{EventL2Press, EventCrossPress, EventCrossRelease, EventL2Release}
+	if !equalEvents(events, want) {
+		t.Errorf("got %v, want %v", events, want)
+	}
+}
+
+func TestRemapButtonToAxis(t *testing.T) {
+	r, err := newRemapping(Profile{Name: "dpad", Rules: []Rule{ButtonToAxis(ButtonCross, AxisLeftX, -1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := decode(usbButtons(bitCross), newInputLayout(ModelDualShock4, 0), nil)
+	out := r.apply(src, &state{}, defaultSettings(), nil)
+
+	if out.leftStick.AxisX != -1 || out.leftStick.X != 0 || out.cross {
+		t.Errorf("got left x %v (%d) and cross %v, want -1 (0) and no cross", out.leftStick.AxisX, out.leftStick.X, out.cross)
+	}
+}
+
+func TestProfileSwitching(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports [][]byte
+		want    []Event
+	}{
+		{
+			"PS released last",
+			[][]byte{usbButtons(bitPS), usbButtons(bitPS, bitSquare), usbButtons(bitPS), usbButtons(), usbButtons(bitL1)},
+			[]Event{EventProfileChange, EventR1Press},
+		},
+		{
+			// Square stays hidden until it is released, even from the new profile
+			"PS released first",
+			[][]byte{usbButtons(bitPS), usbButtons(bitPS, bitSquare), usbButtons(bitSquare), usbButtons(), usbButtons(bitL1)},
+			[]Event{EventProfileChange, EventR1Press},
+		},
+		{
+			// A bound button held before PS is not a switch and stays visible
+			"bound button held first",
+			[][]byte{usbButtons(bitSquare), usbButtons(bitSquare, bitPS), usbButtons(bitSquare), usbButtons()},
+			[]Event{EventSquarePress, EventSquareRelease},
+		},
+		{
+			"without PS",
+			[][]byte{usbButtons(bitSquare), usbButtons(), usbButtons(bitL1)},
+			[]Event{EventSquarePress, EventSquareRelease, EventL1Press},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := listenEvents(t, tt.reports, func(c *Controller) {
+				mustProfile(t, c, Profile{Name: "left-handed", Rules: []Rule{SwapButtons(ButtonL1, ButtonR1)}}, false)
+
+				err := c.BindProfile(ButtonSquare, "left-handed")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}, EventProfileChange, EventSquarePress, EventSquareRelease, EventL1Press, EventR1Press)
+
+			if !equalEvents(events, tt.want) {
+				t.Errorf("got %v, want %v", events, tt.want)
+			}
+		})
+	}
+}
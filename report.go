@@ -0,0 +1,226 @@
+package gods4
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidReportConfig = errors.New("ds4: invalid report config")
+	ErrCorruptReport       = errors.New("ds4: corrupt input report")
+)
+
+// Input report IDs and sizes, Bluetooth reports end with a CRC-32
+const (
+	usbInputReportID                = 0x01
+	usbInputReportSize              = 64
+	bluetoothInputReportID          = 0x11
+	dualSenseBluetoothInputReportID = 0x31
+	bluetoothInputReportSize        = 78
+	reducedInputReportSize          = 10
+	bluetoothInputCRCOffset         = 74
+)
+
+// The report rate is measured over windows of this length
+const reportRateWindow = time.Second
+
+// Bluetooth input reports are checksummed with the HID input header byte prepended
+var bluetoothInputCRCSeed = crc32.Update(0, crc32.IEEETable, []byte{0xA1})
+
+// CorruptReportPolicy decides what happens to Bluetooth reports with a wrong CRC and truncated reports.
+type CorruptReportPolicy uint
+
+const (
+	// The report is skipped
+	CorruptReportDrop CorruptReportPolicy = iota
+	// The report is decoded anyway
+	CorruptReportAccept
+	// Listen returns ErrCorruptReport
+	CorruptReportFail
+)
+
+func (p CorruptReportPolicy) String() string {
+	switch p {
+	case CorruptReportDrop:
+		return "drop"
+	case CorruptReportAccept:
+		return "accept"
+	case CorruptReportFail:
+		return "fail"
+	default:
+		return ""
+	}
+}
+
+type ReportConfig struct {
+	CorruptPolicy CorruptReportPolicy
+}
+
+func DefaultReportConfig() ReportConfig {
+	return ReportConfig{
+		CorruptPolicy: CorruptReportDrop,
+	}
+}
+
+func (c ReportConfig) Validate() error {
+	if c.CorruptPolicy > CorruptReportFail {
+		return errors.Wrapf(ErrInvalidReportConfig, "unknown corrupt report policy: %d", c.CorruptPolicy)
+	}
+
+	return nil
+}
+
+// ReportStats counts input reports read since the controller was connected. Rejected reports
+// are counted by reason: a wrong CRC, shorter than the full report, an unexpected report ID,
+// or a reduced Bluetooth report (ID 0x01) received in the extended report mode.
+// Corrupt and short reports are also counted as accepted under CorruptReportAccept.
+type ReportStats struct {
+	Received  uint64
+	Accepted  uint64
+	Corrupt   uint64
+	Short     uint64
+	UnknownID uint64
+	Reduced   uint64
+}
+
+type reportCounters struct {
+	received  atomic.Uint64
+	accepted  atomic.Uint64
+	corrupt   atomic.Uint64
+	short     atomic.Uint64
+	unknownID atomic.Uint64
+	reduced   atomic.Uint64
+	rate      reportRate
+}
+
+func (c *reportCounters) reset() {
+	for _, counter := range []*atomic.Uint64{&c.received, &c.accepted, &c.corrupt, &c.short, &c.unknownID, &c.reduced} {
+		counter.Store(0)
+	}
+
+	c.rate.reset()
+}
+
+func (c *reportCounters) stats() ReportStats {
+	return ReportStats{
+		Received:  c.received.Load(),
+		Accepted:  c.accepted.Load(),
+		Corrupt:   c.corrupt.Load(),
+		Short:     c.short.Load(),
+		UnknownID: c.unknownID.Load(),
+		Reduced:   c.reduced.Load(),
+	}
+}
+
+// reportRate measures the rate of received reports. Only the input goroutine ticks,
+// the rate of the last complete window is read concurrently.
+type reportRate struct {
+	windowStart time.Time
+	count       uint64
+	rate        atomic.Uint64
+}
+
+func (r *reportRate) reset() {
+	r.windowStart = time.Time{}
+	r.count = 0
+	r.rate.Store(0)
+}
+
+func (r *reportRate) tick(now time.Time) {
+	if r.windowStart.IsZero() {
+		r.windowStart = now
+
+		return
+	}
+
+	r.count++
+
+	elapsed := now.Sub(r.windowStart)
+	if elapsed < reportRateWindow {
+		return
+	}
+
+	r.rate.Store(math.Float64bits(float64(r.count) / elapsed.Seconds()))
+	r.windowStart = now
+	r.count = 0
+}
+
+func (r *reportRate) current() float64 {
+	return math.Float64frombits(r.rate.Load())
+}
+
+// inputReport returns the ID and the size of the full input report.
+func (m Model) inputReport(connectionType ConnectionType, mode ReportMode) (byte, int) {
+	switch {
+	case connectionType != ConnectionTypeBluetooth:
+		return usbInputReportID, usbInputReportSize
+	case mode == ReportModeReduced:
+		return usbInputReportID, reducedInputReportSize
+	case m == ModelDualSense:
+		return dualSenseBluetoothInputReportID, bluetoothInputReportSize
+	default:
+		return bluetoothInputReportID, bluetoothInputReportSize
+	}
+}
+
+// checkReport reports whether an input report is decoded, counting it by the reason it is not.
+func (c *Controller) checkReport(bytes []byte, config ReportConfig) (bool, error) {
+	c.reportCounters.received.Add(1)
+	c.reportCounters.rate.tick(time.Now())
+
+	id, size := c.model.inputReport(c.connectionType, c.reportMode)
+	isExtended := c.connectionType == ConnectionTypeBluetooth && c.reportMode == ReportModeExtended
+
+	// A controller which sent extended reports before Connect keeps sending them in the reduced mode
+	if c.connectionType == ConnectionTypeBluetooth && c.reportMode == ReportModeReduced && len(bytes) > 0 {
+		extendedID, extendedSize := c.model.inputReport(ConnectionTypeBluetooth, ReportModeExtended)
+		if bytes[0] == extendedID {
+			id, size, isExtended = extendedID, extendedSize, true
+		}
+	}
+
+	if len(bytes) == 0 || bytes[0] != id {
+		if len(bytes) > 0 && bytes[0] == usbInputReportID && c.connectionType == ConnectionTypeBluetooth && c.reportMode == ReportModeExtended {
+			c.reportCounters.reduced.Add(1)
+		} else {
+			c.reportCounters.unknownID.Add(1)
+		}
+
+		return false, nil
+	}
+
+	var reason string
+
+	switch {
+	case len(bytes) < size:
+		c.reportCounters.short.Add(1)
+		reason = "truncated"
+	case isExtended && !isValidInputCRC(bytes):
+		c.reportCounters.corrupt.Add(1)
+		reason = "CRC mismatch"
+	}
+
+	if reason != "" {
+		switch config.CorruptPolicy {
+		case CorruptReportDrop:
+			return false, nil
+		case CorruptReportFail:
+			return false, errors.Wrapf(ErrCorruptReport, "%s, report of %d bytes", reason, len(bytes))
+		}
+	}
+
+	c.reportCounters.accepted.Add(1)
+
+	return true, nil
+}
+
+func isValidInputCRC(bytes []byte) bool {
+	crc := crc32.Update(bluetoothInputCRCSeed, crc32.IEEETable, bytes[:bluetoothInputCRCOffset])
+
+	return crc == binary.LittleEndian.Uint32(bytes[bluetoothInputCRCOffset:])
+}
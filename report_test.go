@@ -0,0 +1,75 @@
+package gods4
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// bluetoothInput returns a DualShock 4 extended Bluetooth report with a valid CRC.
+func bluetoothInput() []byte {
+	report := make([]byte, bluetoothInputReportSize)
+	report[0] = bluetoothInputReportID
+	report[1] = 0xC0
+
+	// The CRC covers the 0xA1 HID input header, which is not part of the report
+	crc := crc32.ChecksumIEEE(append([]byte{0xA1}, report[:bluetoothInputCRCOffset]...))
+	binary.LittleEndian.PutUint32(report[bluetoothInputCRCOffset:], crc)
+
+	return report
+}
+
+func TestBluetoothInputCRC(t *testing.T) {
+	report := bluetoothInput()
+	if !isValidInputCRC(report) {
+		t.Fatal("valid report was rejected")
+	}
+
+	report[10] ^= 0x01
+	if isValidInputCRC(report) {
+		t.Fatal("corrupt report was accepted")
+	}
+}
+
+func TestCheckReport(t *testing.T) {
+	corrupt := bluetoothInput()
+	corrupt[10] ^= 0x01
+
+	tests := []struct {
+		name   string
+		report []byte
+		policy CorruptReportPolicy
+		ok     bool
+		err    error
+		stats  ReportStats
+	}{
+		{"valid", bluetoothInput(), CorruptReportDrop, true, nil, ReportStats{Received: 1, Accepted: 1}},
+		{"dropped", corrupt, CorruptReportDrop, false, nil, ReportStats{Received: 1, Corrupt: 1}},
+		{"accepted", corrupt, CorruptReportAccept, true, nil, ReportStats{Received: 1, Accepted: 1, Corrupt: 1}},
+		{"failed", corrupt, CorruptReportFail, false, ErrCorruptReport, ReportStats{Received: 1, Corrupt: 1}},
+		{"short", bluetoothInput()[:40], CorruptReportDrop, false, nil, ReportStats{Received: 1, Short: 1}},
+		{"reduced", usbInput(128, 128, 128, 128)[:reducedInputReportSize], CorruptReportDrop, false, nil, ReportStats{Received: 1, Reduced: 1}},
+		{"unknown ID", []byte{0x05, 0x00}, CorruptReportDrop, false, nil, ReportStats{Received: 1, UnknownID: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				model:          ModelDualShock4,
+				connectionType: ConnectionTypeBluetooth,
+				reportMode:     ReportModeExtended,
+			}
+
+			ok, err := c.checkReport(tt.report, ReportConfig{CorruptPolicy: tt.policy})
+			if ok != tt.ok || errors.Cause(err) != tt.err {
+				t.Errorf("got %v, %v, want %v, %v", ok, err, tt.ok, tt.err)
+			}
+
+			if stats := c.ReportStats(); stats != tt.stats {
+				t.Errorf("got stats %+v, want %+v", stats, tt.stats)
+			}
+		})
+	}
+}
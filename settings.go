@@ -0,0 +1,159 @@
+package gods4
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4/curve"
+	"github.com/kpeu3i/gods4/filter"
+)
+
+// The default stick filter ignores changes smaller than 4 raw units
+const analogSticksSmoothing = 4.0 / 128
+
+var (
+	ErrInvalidTriggerConfig = errors.New("ds4: invalid trigger config")
+	ErrInvalidStickConfig   = errors.New("ds4: invalid stick config")
+	ErrInvalidFilter        = errors.New("ds4: invalid filter")
+)
+
+// TriggerConfig controls how the analog value of L2/R2 is turned into press and release events.
+// A trigger is pressed once its value reaches PressThreshold and released once it drops to
+// ReleaseThreshold, so the gap between them absorbs jitter.
+type TriggerConfig struct {
+	PressThreshold    byte
+	ReleaseThreshold  byte
+	FullPullThreshold byte
+	Curve             curve.Curve
+}
+
+func DefaultTriggerConfig() TriggerConfig {
+	return TriggerConfig{
+		PressThreshold:    30,
+		ReleaseThreshold:  20,
+		FullPullThreshold: 250,
+		Curve:             curve.Linear(),
+	}
+}
+
+func (c TriggerConfig) Validate() error {
+	if c.ReleaseThreshold >= c.PressThreshold {
+		return errors.Wrapf(ErrInvalidTriggerConfig, "release threshold (%d) must be lower than press threshold (%d)", c.ReleaseThreshold, c.PressThreshold)
+	}
+
+	if c.FullPullThreshold < c.PressThreshold {
+		return errors.Wrapf(ErrInvalidTriggerConfig, "full pull threshold (%d) must not be lower than press threshold (%d)", c.FullPullThreshold, c.PressThreshold)
+	}
+
+	if c.Curve == nil {
+		return errors.Wrap(ErrInvalidTriggerConfig, "curve is required")
+	}
+
+	return nil
+}
+
+// StickConfig controls how the raw stick position is mapped into [-1, 1].
+// Dead zones and anti-deadzone are fractions of the full stick travel.
+// Filter is applied to each axis before dead zones.
+// Direction fields control how the stick is treated as a D-pad: a direction engages once
+// the stick magnitude reaches DirectionThreshold and is kept until the magnitude drops by
+// DirectionHysteresis or the angle leaves the direction sector by DirectionAngleHysteresis degrees.
+// With DirectionThreshold left at 0, all three take the values of DefaultStickConfig.
+type StickConfig struct {
+	DeadZoneType             DeadZoneType
+	InnerDeadZone            float64
+	OuterDeadZone            float64
+	AntiDeadZone             float64
+	Curve                    curve.Curve
+	Filter                   filter.Filter
+	DirectionThreshold       float64
+	DirectionHysteresis      float64
+	DirectionAngleHysteresis float64
+}
+
+func DefaultStickConfig() StickConfig {
+	return StickConfig{
+		DeadZoneType: DeadZoneScaledRadial,
+		Curve:        curve.Linear(),
+		Filter:       filter.Threshold(analogSticksSmoothing),
+
+		DirectionThreshold:       0.5,
+		DirectionHysteresis:      0.1,
+		DirectionAngleHysteresis: 10,
+	}
+}
+
+func (c StickConfig) Validate() error {
+	c = c.withDirectionDefaults()
+
+	if c.DeadZoneType > DeadZoneScaledRadial {
+		return errors.Wrapf(ErrInvalidStickConfig, "unknown dead zone type: %d", c.DeadZoneType)
+	}
+
+	if c.InnerDeadZone < 0 || c.OuterDeadZone < 0 || c.InnerDeadZone+c.OuterDeadZone >= 1 {
+		return errors.Wrapf(ErrInvalidStickConfig, "inner (%v) and outer (%v) dead zones must be positive and leave some travel", c.InnerDeadZone, c.OuterDeadZone)
+	}
+
+	if c.AntiDeadZone < 0 || c.AntiDeadZone >= 1 {
+		return errors.Wrapf(ErrInvalidStickConfig, "anti-deadzone (%v) is out of range [0, 1)", c.AntiDeadZone)
+	}
+
+	if c.Curve == nil {
+		return errors.Wrap(ErrInvalidStickConfig, "curve is required")
+	}
+
+	if c.Filter == nil {
+		return errors.Wrap(ErrInvalidStickConfig, "filter is required")
+	}
+
+	if c.DirectionThreshold <= 0 || c.DirectionThreshold > 1 {
+		return errors.Wrapf(ErrInvalidStickConfig, "direction threshold (%v) is out of range (0, 1]", c.DirectionThreshold)
+	}
+
+	if c.DirectionHysteresis < 0 || c.DirectionHysteresis >= c.DirectionThreshold {
+		return errors.Wrapf(ErrInvalidStickConfig, "direction hysteresis (%v) must be positive and lower than direction threshold", c.DirectionHysteresis)
+	}
+
+	if c.DirectionAngleHysteresis < 0 || c.DirectionAngleHysteresis >= 22.5 {
+		return errors.Wrapf(ErrInvalidStickConfig, "direction angle hysteresis (%v) is out of range [0, 22.5)", c.DirectionAngleHysteresis)
+	}
+
+	return nil
+}
+
+func (c StickConfig) withDirectionDefaults() StickConfig {
+	if c.DirectionThreshold != 0 {
+		return c
+	}
+
+	defaults := DefaultStickConfig()
+	c.DirectionThreshold = defaults.DirectionThreshold
+	c.DirectionHysteresis = defaults.DirectionHysteresis
+	c.DirectionAngleHysteresis = defaults.DirectionAngleHysteresis
+
+	return c
+}
+
+// settings is an immutable snapshot of the input processing configuration,
+// replaced as a whole whenever the controller is reconfigured.
+type settings struct {
+	l2                  TriggerConfig
+	r2                  TriggerConfig
+	leftStick           StickConfig
+	rightStick          StickConfig
+	calibration         Calibration
+	reports             ReportConfig
+	accelerometerFilter filter.Filter
+	gyroscopeFilter     filter.Filter
+}
+
+func defaultSettings() *settings {
+	return &settings{
+		l2:                  DefaultTriggerConfig(),
+		r2:                  DefaultTriggerConfig(),
+		leftStick:           DefaultStickConfig(),
+		rightStick:          DefaultStickConfig(),
+		reports:             DefaultReportConfig(),
+		accelerometerFilter: filter.None(),
+		gyroscopeFilter:     filter.None(),
+	}
+}
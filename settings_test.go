@@ -0,0 +1,38 @@
+package gods4
+
+import (
+	"testing"
+
+	"github.com/kpeu3i/gods4/curve"
+	"github.com/kpeu3i/gods4/filter"
+)
+
+func TestStickConfigDirectionDefaults(t *testing.T) {
+	config := StickConfig{
+		InnerDeadZone: 0.1,
+		Curve:         curve.Linear(),
+		Filter:        filter.None(),
+	}
+
+	err := config.Validate()
+	if err != nil {
+		t.Fatalf("config without direction fields is invalid: %v", err)
+	}
+
+	defaults := DefaultStickConfig()
+	if got := config.withDirectionDefaults(); got.DirectionThreshold != defaults.DirectionThreshold ||
+		got.DirectionHysteresis != defaults.DirectionHysteresis ||
+		got.DirectionAngleHysteresis != defaults.DirectionAngleHysteresis {
+		t.Errorf("direction fields = %v, %v, %v, want the defaults", got.DirectionThreshold, got.DirectionHysteresis, got.DirectionAngleHysteresis)
+	}
+
+	config.DirectionThreshold = 0.8
+	if got := config.withDirectionDefaults(); got.DirectionHysteresis != 0 {
+		t.Errorf("explicit direction threshold got default hysteresis %v", got.DirectionHysteresis)
+	}
+
+	config.DirectionHysteresis = 0.9
+	if config.Validate() == nil {
+		t.Error("hysteresis above the threshold is valid")
+	}
+}
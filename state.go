@@ -3,25 +3,26 @@ package gods4
 import (
 	"encoding/binary"
 	"math"
-)
+	"time"
 
-const analogSticksSmoothing = 4
+	"github.com/kpeu3i/gods4/filter"
+)
 
 type state struct {
+	timestamp     time.Duration
+	rawTimestamp  uint32
+	counter       byte
 	cross         bool
 	circle        bool
 	square        bool
 	triangle      bool
 	l1            bool
-	l2            byte
+	l2            Trigger
 	l3            bool
 	r1            bool
-	r2            byte
+	r2            Trigger
 	r3            bool
-	dPadUp        bool
-	dPadDown      bool
-	dPadLeft      bool
-	dPadRight     bool
+	dPad          DPad
 	share         bool
 	options       bool
 	ps            bool
@@ -31,11 +32,108 @@ type state struct {
 	accelerometer Accelerometer
 	gyroscope     Gyroscope
 	battery       Battery
+	headset       Headset
+	extension     bool
+	// Buttons as held by the user, set when turbo pulses some of them
+	held *state
+}
+
+// gestures returns the state button gestures are timed on.
+func (s *state) gestures() *state {
+	if s.held != nil {
+		return s.held
+	}
+
+	return s
+}
+
+// State is a snapshot of the decoded input, as seen by the emitter.
+// Counter is the report counter, 6-bit on DualShock 4 and 8-bit on DualSense,
+// gaps in it are dropped reports.
+type State struct {
+	Timestamp     time.Duration
+	Counter       byte
+	Cross         bool
+	Circle        bool
+	Square        bool
+	Triangle      bool
+	L1            bool
+	L2            Trigger
+	L3            bool
+	R1            bool
+	R2            Trigger
+	R3            bool
+	DPad          DPad
+	Share         bool
+	Options       bool
+	PS            bool
+	LeftStick     Stick
+	RightStick    Stick
+	Touchpad      Touchpad
+	Accelerometer Accelerometer
+	Gyroscope     Gyroscope
+	Battery       Battery
+	Headset       Headset
+	Extension     bool
+}
+
+func (s *state) snapshot() State {
+	touchpad := s.touchpad
+	touchpad.Swipe = append([]Touch(nil), s.touchpad.Swipe...)
+
+	return State{
+		Timestamp:     s.timestamp,
+		Counter:       s.counter,
+		Cross:         s.cross,
+		Circle:        s.circle,
+		Square:        s.square,
+		Triangle:      s.triangle,
+		L1:            s.l1,
+		L2:            s.l2,
+		L3:            s.l3,
+		R1:            s.r1,
+		R2:            s.r2,
+		R3:            s.r3,
+		DPad:          s.dPad,
+		Share:         s.share,
+		Options:       s.options,
+		PS:            s.ps,
+		LeftStick:     s.leftStick,
+		RightStick:    s.rightStick,
+		Touchpad:      touchpad,
+		Accelerometer: s.accelerometer,
+		Gyroscope:     s.gyroscope,
+		Battery:       s.battery,
+		Headset:       s.headset,
+		Extension:     s.extension,
+	}
+}
+
+// DPad is the hat switch position, DirectionNone when centered.
+type DPad = Direction
+
+type DPadChange struct {
+	Old DPad
+	New DPad
+}
+
+type Trigger struct {
+	// Value is the raw analog value, Pressure is the value mapped through the response curve.
+	Value      byte
+	Pressure   float64
+	IsPressed  bool
+	IsFullPull bool
 }
 
 type Stick struct {
-	X byte
-	Y byte
+	// X and Y are raw positions centered at 128, AxisX and AxisY are the
+	// same positions in [-1, 1] after filter, dead zones and response curve.
+	X          byte
+	Y          byte
+	AxisX      float64
+	AxisY      float64
+	Direction4 Direction
+	Direction8 Direction
 }
 
 type Touchpad struct {
@@ -43,22 +141,36 @@ type Touchpad struct {
 	Swipe []Touch
 }
 
+// X is in [0, 1919] and Y in [0, 942], or [0, 1079] on DualSense.
 type Touch struct {
 	IsActive bool
-	X        byte
-	Y        byte
+	X        uint16
+	Y        uint16
 }
 
+// X, Y and Z are filtered, the Raw fields hold the values as reported.
 type Accelerometer struct {
-	X int16
-	Y int16
-	Z int16
+	X    int16
+	Y    int16
+	Z    int16
+	RawX int16
+	RawY int16
+	RawZ int16
 }
 
+// Roll, Yaw and Pitch are filtered, the Raw fields hold the values as reported.
 type Gyroscope struct {
-	Roll  int16
-	Yaw   int16
-	Pitch int16
+	Roll     int16
+	Yaw      int16
+	Pitch    int16
+	RawRoll  int16
+	RawYaw   int16
+	RawPitch int16
+}
+
+type Headset struct {
+	IsHeadphonesConnected bool
+	IsMicConnected        bool
 }
 
 type Battery struct {
@@ -67,159 +179,212 @@ type Battery struct {
 	IsCableConnected bool
 }
 
-func newState(bytes []byte, offset uint, prevState *state) *state {
+func newState(bytes []byte, layout *inputLayout, prevState *state, settings *settings, filters *filters) *state {
+	rawTimestamp, timestamp := timestampState(bytes, layout, prevState)
+
+	var (
+		dt                            time.Duration
+		prevLeftStick, prevRightStick Stick
+	)
+
+	if prevState != nil {
+		dt = timestamp - prevState.timestamp
+		prevLeftStick, prevRightStick = prevState.leftStick, prevState.rightStick
+	}
+
 	s := &state{
-		cross:         buttonCrossState(bytes, offset),
-		circle:        buttonCircleState(bytes, offset),
-		square:        buttonSquareState(bytes, offset),
-		triangle:      buttonTriangleState(bytes, offset),
-		l1:            buttonL1State(bytes, offset),
-		l2:            buttonL2State(bytes, offset),
-		l3:            buttonL3State(bytes, offset),
-		r1:            buttonR1State(bytes, offset),
-		r2:            buttonR2State(bytes, offset),
-		r3:            buttonR3State(bytes, offset),
-		dPadUp:        buttonDPadUpState(bytes, offset),
-		dPadDown:      buttonDPadDownState(bytes, offset),
-		dPadLeft:      buttonDPadLeftState(bytes, offset),
-		dPadRight:     buttonDPadRightState(bytes, offset),
-		share:         buttonShareState(bytes, offset),
-		options:       buttonOptionsState(bytes, offset),
-		ps:            buttonPSState(bytes, offset),
-		leftStick:     buttonLeftStickState(bytes, offset, prevState),
-		rightStick:    buttonRightStickState(bytes, offset, prevState),
-		touchpad:      touchpadState(bytes, offset),
-		accelerometer: accelerometerState(bytes, offset),
-		gyroscope:     gyroscopeState(bytes, offset),
-		battery:       batteryState(bytes, offset),
+		timestamp:     timestamp,
+		rawTimestamp:  rawTimestamp,
+		counter:       counterState(bytes, layout),
+		cross:         buttonCrossState(bytes, layout),
+		circle:        buttonCircleState(bytes, layout),
+		square:        buttonSquareState(bytes, layout),
+		triangle:      buttonTriangleState(bytes, layout),
+		l1:            buttonL1State(bytes, layout),
+		l2:            buttonL2State(bytes, layout, prevState, settings.l2),
+		l3:            buttonL3State(bytes, layout),
+		r1:            buttonR1State(bytes, layout),
+		r2:            buttonR2State(bytes, layout, prevState, settings.r2),
+		r3:            buttonR3State(bytes, layout),
+		dPad:          buttonDPadState(bytes, layout),
+		share:         buttonShareState(bytes, layout),
+		options:       buttonOptionsState(bytes, layout),
+		ps:            buttonPSState(bytes, layout),
+		leftStick:     buttonLeftStickState(bytes, layout, dt, prevLeftStick, settings.leftStick, settings.calibration.LeftStick, filters.leftStick),
+		rightStick:    buttonRightStickState(bytes, layout, dt, prevRightStick, settings.rightStick, settings.calibration.RightStick, filters.rightStick),
+		touchpad:      touchpadState(bytes, layout),
+		accelerometer: accelerometerState(bytes, layout, dt, filters.accelerometer),
+		gyroscope:     gyroscopeState(bytes, layout, dt, filters.gyroscope),
+		battery:       batteryState(bytes, layout),
+		headset:       headsetState(bytes, layout),
+		extension:     extensionState(bytes, layout),
 	}
 
 	return s
 }
 
-func buttonCrossState(bytes []byte, offset uint) bool {
-	return bytes[5+offset]&32 != 0
-}
+func timestampState(bytes []byte, l *inputLayout, prevState *state) (uint32, time.Duration) {
+	if l.timestampSize == 0 {
+		// time.Since uses the monotonic clock
+		return 0, time.Since(l.epoch)
+	}
 
-func buttonCircleState(bytes []byte, offset uint) bool {
-	return bytes[5+offset]&64 != 0
-}
+	var rawTimestamp uint32
+	if l.timestampSize == 4 {
+		rawTimestamp = binary.LittleEndian.Uint32(bytes[l.timestamp:])
+	} else {
+		rawTimestamp = uint32(binary.LittleEndian.Uint16(bytes[l.timestamp:]))
+	}
+
+	if prevState == nil {
+		return rawTimestamp, 0
+	}
+
+	// The counter wraps around, unsigned subtraction keeps the delta correct
+	delta := (rawTimestamp - prevState.rawTimestamp) & (1<<(8*l.timestampSize) - 1)
 
-func buttonSquareState(bytes []byte, offset uint) bool {
-	return bytes[5+offset]&16 != 0
+	return rawTimestamp, prevState.timestamp + time.Duration(delta)*l.timestampTicks/3
 }
 
-func buttonTriangleState(bytes []byte, offset uint) bool {
-	return bytes[5+offset]&128 != 0
+func counterState(bytes []byte, l *inputLayout) byte {
+	return bytes[l.counter] >> l.counterShift
 }
 
-func buttonL1State(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&1 != 0
+func buttonCrossState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[0]]&32 != 0
 }
 
-func buttonL2State(bytes []byte, offset uint) byte {
-	if bytes[6+offset]&4 != 0 {
-		return bytes[8+offset]
-	}
+func buttonCircleState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[0]]&64 != 0
+}
 
-	return 0
+func buttonSquareState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[0]]&16 != 0
 }
 
-func buttonL3State(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&64 != 0
+func buttonTriangleState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[0]]&128 != 0
 }
 
-func buttonR1State(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&2 != 0
+func buttonL1State(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&1 != 0
 }
 
-func buttonR2State(bytes []byte, offset uint) byte {
-	if bytes[6+offset]&8 != 0 {
-		return bytes[9+offset]
+func buttonL2State(bytes []byte, l *inputLayout, prevState *state, config TriggerConfig) Trigger {
+	var prevTrigger Trigger
+
+	if prevState != nil {
+		prevTrigger = prevState.l2
 	}
 
-	return 0
+	return triggerState(bytes[l.l2], prevTrigger, config)
 }
 
-func buttonR3State(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&128 != 0
+func buttonL3State(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&64 != 0
 }
 
-func buttonDPadUpState(bytes []byte, offset uint) bool {
-	v := bytes[5+offset] & 15
-
-	return v == 0 || v == 1 || v == 7
+func buttonR1State(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&2 != 0
 }
 
-func buttonDPadDownState(bytes []byte, offset uint) bool {
-	v := bytes[5+offset] & 15
+func buttonR2State(bytes []byte, l *inputLayout, prevState *state, config TriggerConfig) Trigger {
+	var prevTrigger Trigger
 
-	return v == 3 || v == 4 || v == 5
+	if prevState != nil {
+		prevTrigger = prevState.r2
+	}
+
+	return triggerState(bytes[l.r2], prevTrigger, config)
 }
 
-func buttonDPadLeftState(bytes []byte, offset uint) bool {
-	v := bytes[5+offset] & 15
+func triggerState(value byte, prevTrigger Trigger, config TriggerConfig) Trigger {
+	isPressed := prevTrigger.IsPressed
+	if value >= config.PressThreshold {
+		isPressed = true
+	} else if value <= config.ReleaseThreshold {
+		isPressed = false
+	}
+
+	// Full pull uses the same hysteresis gap as press/release
+	isFullPull := prevTrigger.IsFullPull
+	if value >= config.FullPullThreshold {
+		isFullPull = true
+	} else if int(value) <= int(config.FullPullThreshold)-int(config.PressThreshold-config.ReleaseThreshold) {
+		isFullPull = false
+	}
+
+	t := Trigger{
+		Value:      value,
+		Pressure:   config.Curve(float64(value) / 255),
+		IsPressed:  isPressed,
+		IsFullPull: isFullPull,
+	}
 
-	return v == 5 || v == 6 || v == 7
+	return t
 }
 
-func buttonDPadRightState(bytes []byte, offset uint) bool {
-	v := bytes[5+offset] & 15
+func buttonR3State(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&128 != 0
+}
 
-	return v == 1 || v == 2 || v == 3
+func buttonDPadState(bytes []byte, l *inputLayout) DPad {
+	// Hat switch: 0 is up, values grow clockwise, 8 is centered
+	v := bytes[l.buttons[0]] & 15
+	if v > 7 {
+		return DirectionNone
+	}
+
+	return DirectionUp + Direction(v)
 }
 
-func buttonShareState(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&16 != 0
+func buttonShareState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&16 != 0
 }
 
-func buttonOptionsState(bytes []byte, offset uint) bool {
-	return bytes[6+offset]&32 != 0
+func buttonOptionsState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[1]]&32 != 0
 }
 
-//func buttonTouchpadState(bytes []byte, offset uint) bool {
-//	return bytes[7+offset]&2 != 0
+//func buttonTouchpadState(bytes []byte, l *inputLayout) bool {
+//	return bytes[l.buttons[2]]&2 != 0
 //}
 
-func buttonPSState(bytes []byte, offset uint) bool {
-	return bytes[7+offset]&1 != 0
+func buttonPSState(bytes []byte, l *inputLayout) bool {
+	return bytes[l.buttons[2]]&1 != 0
 }
 
-func buttonLeftStickState(bytes []byte, offset uint, prevState *state) Stick {
-	var prevX, prevY byte
-
-	if prevState == nil {
-		prevX, prevY = bytes[1+offset], bytes[2+offset]
-	} else {
-		prevX, prevY = prevState.leftStick.X, prevState.leftStick.Y
-	}
+func buttonLeftStickState(bytes []byte, l *inputLayout, dt time.Duration, prevStick Stick, config StickConfig, calibration StickCalibration, filters [2]filter.Filter) Stick {
+	x, y := bytes[l.leftStick], bytes[l.leftStick+1]
+	axisX, axisY := processStick(
+		filters[0].Filter(calibration.X.normalize(x), dt),
+		filters[1].Filter(calibration.Y.normalize(y), dt),
+		config,
+	)
 
-	if math.Abs(float64(bytes[1+offset])-float64(prevX)) >= float64(analogSticksSmoothing) ||
-		math.Abs(float64(bytes[2+offset])-float64(prevY)) >= float64(analogSticksSmoothing) {
-		return Stick{X: bytes[1+offset], Y: bytes[2+offset]}
-	}
+	stick := Stick{X: x, Y: y, AxisX: axisX, AxisY: axisY}
+	stick.Direction4 = stickDirection(stick, prevStick.Direction4, 4, config)
+	stick.Direction8 = stickDirection(stick, prevStick.Direction8, 8, config)
 
-	return Stick{X: prevX, Y: prevY}
+	return stick
 }
 
-func buttonRightStickState(bytes []byte, offset uint, prevState *state) Stick {
-	var prevX, prevY byte
-
-	if prevState == nil {
-		prevX, prevY = bytes[3+offset], bytes[4+offset]
-	} else {
-		prevX, prevY = prevState.rightStick.X, prevState.rightStick.Y
-	}
+func buttonRightStickState(bytes []byte, l *inputLayout, dt time.Duration, prevStick Stick, config StickConfig, calibration StickCalibration, filters [2]filter.Filter) Stick {
+	x, y := bytes[l.rightStick], bytes[l.rightStick+1]
+	axisX, axisY := processStick(
+		filters[0].Filter(calibration.X.normalize(x), dt),
+		filters[1].Filter(calibration.Y.normalize(y), dt),
+		config,
+	)
 
-	if math.Abs(float64(bytes[3+offset])-float64(prevX)) >= float64(analogSticksSmoothing) ||
-		math.Abs(float64(bytes[4+offset])-float64(prevY)) >= float64(analogSticksSmoothing) {
-		return Stick{X: bytes[3+offset], Y: bytes[4+offset]}
-	}
+	stick := Stick{X: x, Y: y, AxisX: axisX, AxisY: axisY}
+	stick.Direction4 = stickDirection(stick, prevStick.Direction4, 4, config)
+	stick.Direction8 = stickDirection(stick, prevStick.Direction8, 8, config)
 
-	return Stick{X: prevX, Y: prevY}
+	return stick
 }
 
-func touchpadState(bytes []byte, offset uint) Touchpad {
+func touchpadState(bytes []byte, l *inputLayout) Touchpad {
 	var (
 		touches     []Touch
 		touchOffset uint
@@ -227,9 +392,9 @@ func touchpadState(bytes []byte, offset uint) Touchpad {
 
 	for i := 1; i <= 2; i++ {
 		touch := Touch{
-			IsActive: (bytes[35+touchOffset+offset] >> 7) == 0,
-			X:        ((bytes[37+touchOffset+offset] & 0x0F) << 8) | bytes[36+touchOffset+offset],
-			Y:        bytes[38+touchOffset+offset]<<4 | ((bytes[37+touchOffset+offset] & 0xF0) >> 4),
+			IsActive: (bytes[l.touch+touchOffset] >> 7) == 0,
+			X:        uint16(bytes[l.touch+2+touchOffset]&0x0F)<<8 | uint16(bytes[l.touch+1+touchOffset]),
+			Y:        uint16(bytes[l.touch+3+touchOffset])<<4 | uint16(bytes[l.touch+2+touchOffset]&0xF0)>>4,
 		}
 
 		touches = append(touches, touch)
@@ -237,41 +402,65 @@ func touchpadState(bytes []byte, offset uint) Touchpad {
 	}
 
 	t := Touchpad{
-		Press: bytes[7+offset]&2 != 0,
+		Press: bytes[l.buttons[2]]&2 != 0,
 		Swipe: touches,
 	}
 
 	return t
 }
 
-func accelerometerState(bytes []byte, offset uint) Accelerometer {
+func accelerometerState(bytes []byte, l *inputLayout, dt time.Duration, filters [3]filter.Filter) Accelerometer {
+	x := int16(binary.LittleEndian.Uint16(bytes[l.accelerometer:]))
+	y := -int16(binary.LittleEndian.Uint16(bytes[l.accelerometer+2:]))
+	z := -int16(binary.LittleEndian.Uint16(bytes[l.accelerometer+4:]))
+
 	a := Accelerometer{
-		X: int16(binary.LittleEndian.Uint16(bytes[13+offset:])),
-		Y: -int16(binary.LittleEndian.Uint16(bytes[15+offset:])),
-		Z: -int16(binary.LittleEndian.Uint16(bytes[17+offset:])),
+		X:    filterInt16(filters[0], x, dt),
+		Y:    filterInt16(filters[1], y, dt),
+		Z:    filterInt16(filters[2], z, dt),
+		RawX: x,
+		RawY: y,
+		RawZ: z,
 	}
 
 	return a
 }
 
-func gyroscopeState(bytes []byte, offset uint) Gyroscope {
+func gyroscopeState(bytes []byte, l *inputLayout, dt time.Duration, filters [3]filter.Filter) Gyroscope {
+	roll := -int16(binary.LittleEndian.Uint16(bytes[l.gyroscope:]))
+	yaw := int16(binary.LittleEndian.Uint16(bytes[l.gyroscope+2:]))
+	pitch := int16(binary.LittleEndian.Uint16(bytes[l.gyroscope+4:]))
+
 	g := Gyroscope{
-		Roll:  -int16(binary.LittleEndian.Uint16(bytes[19+offset:])),
-		Yaw:   int16(binary.LittleEndian.Uint16(bytes[21+offset:])),
-		Pitch: int16(binary.LittleEndian.Uint16(bytes[23+offset:])),
+		Roll:     filterInt16(filters[0], roll, dt),
+		Yaw:      filterInt16(filters[1], yaw, dt),
+		Pitch:    filterInt16(filters[2], pitch, dt),
+		RawRoll:  roll,
+		RawYaw:   yaw,
+		RawPitch: pitch,
 	}
 
 	return g
 }
 
-func batteryState(bytes []byte, offset uint) Battery {
+func filterInt16(f filter.Filter, value int16, dt time.Duration) int16 {
+	filtered := math.Round(f.Filter(float64(value), dt))
+
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, filtered)))
+}
+
+func batteryState(bytes []byte, l *inputLayout) Battery {
+	if l.model == ModelDualSense {
+		return dualSenseBatteryState(bytes, l)
+	}
+
 	var (
 		isCharging  bool
 		maxCapacity byte
 	)
 
-	capacity := bytes[30+offset] & 0x0F
-	isCableConnected := ((bytes[30+offset] >> 4) & 0x01) == 1
+	capacity := bytes[l.status] & 0x0F
+	isCableConnected := ((bytes[l.status] >> 4) & 0x01) == 1
 
 	if !isCableConnected || capacity > 10 {
 		isCharging = false
@@ -299,3 +488,43 @@ func batteryState(bytes []byte, offset uint) Battery {
 
 	return battery
 }
+
+// dualSenseBatteryState decodes the capacity in steps of 10% and the charging status.
+func dualSenseBatteryState(bytes []byte, l *inputLayout) Battery {
+	capacity := math.Min(float64(bytes[l.status]&0x0F)*10+5, 100)
+	status := bytes[l.status] >> 4
+
+	switch status {
+	case 0x1:
+		return Battery{Capacity: byte(capacity), IsCharging: true, IsCableConnected: true}
+	case 0x2:
+		return Battery{Capacity: 100, IsCableConnected: true}
+	default:
+		return Battery{Capacity: byte(capacity)}
+	}
+}
+
+func headsetState(bytes []byte, l *inputLayout) Headset {
+	// The DualSense reports the headset in the byte after the battery
+	if l.model == ModelDualSense {
+		return Headset{
+			IsHeadphonesConnected: bytes[l.status+1]&0x01 != 0,
+			IsMicConnected:        bytes[l.status+1]&0x02 != 0,
+		}
+	}
+
+	headset := Headset{
+		IsHeadphonesConnected: ((bytes[l.status] >> 5) & 0x01) == 1,
+		IsMicConnected:        ((bytes[l.status] >> 6) & 0x01) == 1,
+	}
+
+	return headset
+}
+
+func extensionState(bytes []byte, l *inputLayout) bool {
+	if l.model == ModelDualSense {
+		return false
+	}
+
+	return ((bytes[l.status] >> 7) & 0x01) == 1
+}
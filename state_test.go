@@ -0,0 +1,151 @@
+package gods4
+
+import (
+	"io"
+	"testing"
+)
+
+// decode decodes a report with the default settings.
+func decode(bytes []byte, layout *inputLayout, prevState *state) *state {
+	settings := defaultSettings()
+
+	return newState(bytes, layout, prevState, settings, newFilters(settings))
+}
+
+func TestHeadsetState(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  Model
+		status byte
+		want   Headset
+	}{
+		{"DS4 nothing", ModelDualShock4, 0x0B, Headset{}},
+		{"DS4 headphones", ModelDualShock4, 0x2B, Headset{IsHeadphonesConnected: true}},
+		{"DS4 headset", ModelDualShock4, 0x6B, Headset{IsHeadphonesConnected: true, IsMicConnected: true}},
+		{"DS4 mic", ModelDualShock4, 0x4B, Headset{IsMicConnected: true}},
+		{"DualSense nothing", ModelDualSense, 0x00, Headset{}},
+		{"DualSense headphones", ModelDualSense, 0x01, Headset{IsHeadphonesConnected: true}},
+		{"DualSense headset", ModelDualSense, 0x03, Headset{IsHeadphonesConnected: true, IsMicConnected: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := newInputLayout(tt.model, 0)
+			bytes := make([]byte, usbInputReportSize)
+			layout.neutral(bytes)
+
+			// The DS4 keeps battery and headset in one byte, the DualSense puts the headset after the battery
+			if tt.model == ModelDualSense {
+				bytes[layout.status] = 0x08
+				bytes[layout.status+1] = tt.status
+			} else {
+				bytes[layout.status] = tt.status
+			}
+
+			s := decode(bytes, layout, nil)
+			if s.headset != tt.want {
+				t.Errorf("got %+v, want %+v", s.headset, tt.want)
+			}
+
+			if s.extension {
+				t.Error("extension is plugged")
+			}
+		})
+	}
+}
+
+func TestTriggerHysteresis(t *testing.T) {
+	config := DefaultTriggerConfig()
+
+	// Press at 30 and release at 20, full pull at 250 and its release at 240
+	tests := []struct {
+		value      byte
+		isPressed  bool
+		isFullPull bool
+	}{
+		{29, false, false},
+		{30, true, false},
+		{21, true, false},
+		{20, false, false},
+		{29, false, false},
+		{250, true, true},
+		{241, true, true},
+		{240, true, false},
+		{249, true, false},
+		{0, false, false},
+	}
+
+	var prevTrigger Trigger
+
+	for i, tt := range tests {
+		trigger := triggerState(tt.value, prevTrigger, config)
+		if trigger.IsPressed != tt.isPressed || trigger.IsFullPull != tt.isFullPull {
+			t.Errorf("step %d, value %d: got pressed %v and full pull %v, want %v and %v",
+				i, tt.value, trigger.IsPressed, trigger.IsFullPull, tt.isPressed, tt.isFullPull)
+		}
+
+		prevTrigger = trigger
+	}
+}
+
+func TestTriggerJitter(t *testing.T) {
+	// L2 jitters around the press threshold, then around the release threshold
+	var inputs [][]byte
+	for _, value := range []byte{29, 30, 29, 31, 28, 30, 21, 19, 21, 20, 22} {
+		report := usbInput(128, 128, 128, 128)
+		report[8] = value
+		inputs = append(inputs, report)
+	}
+
+	controller := NewController(newMockDevice(inputs...))
+
+	err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	for _, event := range []Event{EventL2Press, EventL2Release} {
+		event := event
+		controller.On(event, func(interface{}) error {
+			events = append(events, event)
+
+			return nil
+		})
+	}
+
+	err = controller.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.Listen()
+	if err != io.EOF {
+		t.Fatalf("got error %v, want io.EOF", err)
+	}
+
+	if len(events) != 2 || events[0] != EventL2Press || events[1] != EventL2Release {
+		t.Errorf("got %v, want a single press and release", events)
+	}
+}
+
+func TestDPadState(t *testing.T) {
+	want := []DPad{
+		DirectionUp, DirectionUpRight, DirectionRight, DirectionDownRight,
+		DirectionDown, DirectionDownLeft, DirectionLeft, DirectionUpLeft,
+		DirectionNone, DirectionNone, DirectionNone, DirectionNone,
+		DirectionNone, DirectionNone, DirectionNone, DirectionNone,
+	}
+
+	layout := newInputLayout(ModelDualShock4, 0)
+
+	for hat, direction := range want {
+		bytes := usbInput(128, 128, 128, 128)
+		// Face buttons share the byte with the hat
+		bytes[layout.buttons[0]] = byte(hat) | 0xF0
+
+		if got := decode(bytes, layout, nil).dPad; got != direction {
+			t.Errorf("hat %d: got %s, want %s", hat, got, direction)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package gods4
+
+import (
+	"math"
+)
+
+type DeadZoneType uint
+
+const (
+	// Each axis is zeroed and rescaled independently
+	DeadZoneAxial DeadZoneType = iota
+	// The stick is zeroed inside a circle, values outside are kept as is
+	DeadZoneRadial
+	// The stick is zeroed inside a circle, the remaining range is rescaled to start from zero
+	DeadZoneScaledRadial
+)
+
+func (t DeadZoneType) String() string {
+	switch t {
+	case DeadZoneAxial:
+		return "axial"
+	case DeadZoneRadial:
+		return "radial"
+	case DeadZoneScaledRadial:
+		return "scaled_radial"
+	default:
+		return ""
+	}
+}
+
+// ParseDeadZoneType returns the dead zone type with the given name, as returned by String.
+func ParseDeadZoneType(name string) (DeadZoneType, bool) {
+	for _, t := range []DeadZoneType{DeadZoneAxial, DeadZoneRadial, DeadZoneScaledRadial} {
+		if t.String() == name {
+			return t, true
+		}
+	}
+
+	return 0, false
+}
+
+// Angle returns the stick angle in degrees clockwise from up.
+func (s Stick) Angle() float64 {
+	if s.AxisX == 0 && s.AxisY == 0 {
+		return 0
+	}
+
+	angle := math.Atan2(s.AxisX, -s.AxisY) * 180 / math.Pi
+	if angle < 0 {
+		angle += 360
+	}
+
+	return angle
+}
+
+func (s Stick) Magnitude() float64 {
+	return math.Min(math.Hypot(s.AxisX, s.AxisY), 1)
+}
+
+func normalizeAxis(value byte) float64 {
+	if value >= 128 {
+		return float64(value-128) / 127
+	}
+
+	return (float64(value) - 128) / 128
+}
+
+func denormalizeAxis(value float64) byte {
+	value = math.Max(-1, math.Min(1, value))
+	if value >= 0 {
+		return byte(math.Round(128 + value*127))
+	}
+
+	return byte(math.Round(128 + value*128))
+}
+
+// processStick applies dead zones, response curve and anti-deadzone
+// from config to a normalized stick position.
+func processStick(axisX, axisY float64, config StickConfig) (float64, float64) {
+	if config.DeadZoneType == DeadZoneAxial {
+		return processAxis(axisX, config), processAxis(axisY, config)
+	}
+
+	magnitude := math.Hypot(axisX, axisY)
+	if magnitude == 0 || magnitude < config.InnerDeadZone {
+		return 0, 0
+	}
+
+	scaled := math.Min(magnitude, 1)
+	if config.DeadZoneType == DeadZoneScaledRadial {
+		scaled = rescale(magnitude, config)
+	} else if magnitude >= 1-config.OuterDeadZone {
+		scaled = 1
+	}
+
+	scaled = antiDeadZone(config.Curve(scaled), config)
+
+	return axisX / magnitude * scaled, axisY / magnitude * scaled
+}
+
+func processAxis(value float64, config StickConfig) float64 {
+	magnitude := math.Abs(value)
+	if magnitude == 0 || magnitude < config.InnerDeadZone {
+		return 0
+	}
+
+	return math.Copysign(antiDeadZone(config.Curve(rescale(magnitude, config)), config), value)
+}
+
+func rescale(magnitude float64, config StickConfig) float64 {
+	return math.Min((magnitude-config.InnerDeadZone)/(1-config.InnerDeadZone-config.OuterDeadZone), 1)
+}
+
+func antiDeadZone(magnitude float64, config StickConfig) float64 {
+	if magnitude == 0 {
+		return 0
+	}
+
+	return config.AntiDeadZone + (1-config.AntiDeadZone)*magnitude
+}
@@ -0,0 +1,113 @@
+package gods4
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kpeu3i/gods4/curve"
+)
+
+func TestNormalizeAxis(t *testing.T) {
+	tests := []struct {
+		value byte
+		want  float64
+	}{
+		{0, -1},
+		{64, -0.5},
+		{128, 0},
+		{255, 1},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeAxis(tt.value); got != tt.want {
+			t.Errorf("normalizeAxis(%d) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for value := 0; value <= 255; value++ {
+		if got := denormalizeAxis(normalizeAxis(byte(value))); got != byte(value) {
+			t.Errorf("%d is denormalized to %d", value, got)
+		}
+	}
+}
+
+func TestProcessStick(t *testing.T) {
+	config := func(deadZoneType DeadZoneType, antiDeadZone float64) StickConfig {
+		return StickConfig{
+			DeadZoneType:  deadZoneType,
+			InnerDeadZone: 0.2,
+			OuterDeadZone: 0.1,
+			AntiDeadZone:  antiDeadZone,
+			Curve:         curve.Linear(),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		config       StickConfig
+		x, y         float64
+		wantX, wantY float64
+	}{
+		// Both axes are inside the axial dead zone, the diagonal is outside the circle
+		{"axial diagonal", config(DeadZoneAxial, 0), 0.15, 0.15, 0, 0},
+		{"radial diagonal", config(DeadZoneRadial, 0), 0.15, 0.15, 0.15, 0.15},
+		{"scaled radial diagonal", config(DeadZoneScaledRadial, 0), 0.15, 0.15, 0.012255, 0.012255},
+		// The axial dead zone snaps a slightly tilted stick to the axis
+		{"axial tilted", config(DeadZoneAxial, 0), 0.5, 0.1, 0.42857, 0},
+		{"radial tilted", config(DeadZoneRadial, 0), 0.5, 0.1, 0.5, 0.1},
+		{"inside", config(DeadZoneRadial, 0), 0.1, -0.1, 0, 0},
+		{"axial outer", config(DeadZoneAxial, 0), -0.95, 0, -1, 0},
+		{"radial outer", config(DeadZoneRadial, 0), 0, 0.95, 0, 1},
+		{"scaled radial outer", config(DeadZoneScaledRadial, 0), 0.95, 0, 1, 0},
+		{"anti-deadzone", config(DeadZoneAxial, 0.1), 0.55, 0, 0.55, 0},
+		{"anti-deadzone at rest", config(DeadZoneScaledRadial, 0.1), 0, 0, 0, 0},
+		{"center", config(DeadZoneScaledRadial, 0), 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := processStick(tt.x, tt.y, tt.config)
+			if math.Abs(x-tt.wantX) > 1e-5 || math.Abs(y-tt.wantY) > 1e-5 {
+				t.Errorf("got (%.5f, %.5f), want (%.5f, %.5f)", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestProcessStickIsMonotonic(t *testing.T) {
+	curves := map[string]curve.Curve{
+		"linear":      curve.Linear(),
+		"exponential": curve.Exponential(2),
+	}
+
+	for name, c := range curves {
+		for _, deadZoneType := range []DeadZoneType{DeadZoneAxial, DeadZoneRadial, DeadZoneScaledRadial} {
+			config := StickConfig{
+				DeadZoneType:  deadZoneType,
+				InnerDeadZone: 0.1,
+				OuterDeadZone: 0.05,
+				AntiDeadZone:  0.2,
+				Curve:         c,
+			}
+
+			prev := -1.0
+
+			for value := 0; value <= 255; value++ {
+				x, _ := processStick(normalizeAxis(byte(value)), 0, config)
+				if x < prev {
+					t.Errorf("%s %s: %d is mapped to %v, below %v", name, deadZoneType, value, x, prev)
+				}
+
+				prev = x
+			}
+
+			if x, _ := processStick(normalizeAxis(0), 0, config); x != -1 {
+				t.Errorf("%s %s: 0 is mapped to %v, want -1", name, deadZoneType, x)
+			}
+
+			if x, _ := processStick(normalizeAxis(255), 0, config); x != 1 {
+				t.Errorf("%s %s: 255 is mapped to %v, want 1", name, deadZoneType, x)
+			}
+		}
+	}
+}
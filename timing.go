@@ -0,0 +1,118 @@
+package gods4
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidTimingConfig = errors.New("ds4: invalid timing config")
+
+// TimingConfig controls button gestures measured with report timestamps:
+//   - hold fires once a button has been held for HoldDuration
+//   - long_press fires on release after at least LongPressDuration
+//   - click fires on release after at most ClickDuration
+//   - double_tap fires on a click pressed within DoubleTapInterval after the previous click
+//   - repeat fires every RepeatInterval once a button has been held for RepeatDelay
+type TimingConfig struct {
+	HoldDuration      time.Duration
+	LongPressDuration time.Duration
+	ClickDuration     time.Duration
+	DoubleTapInterval time.Duration
+	RepeatDelay       time.Duration
+	RepeatInterval    time.Duration
+}
+
+func DefaultTimingConfig() TimingConfig {
+	return TimingConfig{
+		HoldDuration:      500 * time.Millisecond,
+		LongPressDuration: 800 * time.Millisecond,
+		ClickDuration:     250 * time.Millisecond,
+		DoubleTapInterval: 300 * time.Millisecond,
+		RepeatDelay:       500 * time.Millisecond,
+		RepeatInterval:    100 * time.Millisecond,
+	}
+}
+
+func (c TimingConfig) Validate() error {
+	if c.HoldDuration <= 0 ||
+		c.LongPressDuration <= 0 ||
+		c.ClickDuration <= 0 ||
+		c.DoubleTapInterval <= 0 ||
+		c.RepeatDelay <= 0 ||
+		c.RepeatInterval <= 0 {
+		return errors.Wrap(ErrInvalidTimingConfig, "all durations must be positive")
+	}
+
+	return nil
+}
+
+type buttonTimer struct {
+	pressedAt      time.Duration
+	isHoldFired    bool
+	nextRepeatAt   time.Duration
+	lastClickAt    time.Duration
+	isClickPending bool
+}
+
+func (e *emitter) checkTiming(currState, prevState *state) error {
+	timing := e.currentTiming()
+	now := currState.timestamp
+	currState, prevState = currState.gestures(), prevState.gestures()
+
+	for _, button := range buttons {
+		timer := &e.timers[button]
+		isPressed, wasPressed := currState.button(button), prevState.button(button)
+
+		var events []Event
+
+		switch {
+		case isPressed && !wasPressed:
+			timer.pressedAt = now
+			timer.isHoldFired = false
+			timer.nextRepeatAt = now + timing.RepeatDelay
+		case isPressed && wasPressed:
+			if !timer.isHoldFired && now-timer.pressedAt >= timing.HoldDuration {
+				timer.isHoldFired = true
+				events = append(events, button.event("hold"))
+			}
+
+			if now >= timer.nextRepeatAt {
+				timer.nextRepeatAt += timing.RepeatInterval
+				if timer.nextRepeatAt <= now {
+					timer.nextRepeatAt = now + timing.RepeatInterval
+				}
+				events = append(events, button.event("repeat"))
+			}
+		case !isPressed && wasPressed:
+			held := now - timer.pressedAt
+			if held >= timing.LongPressDuration {
+				events = append(events, button.event("long_press"))
+			}
+
+			if held > timing.ClickDuration {
+				timer.isClickPending = false
+				break
+			}
+
+			events = append(events, button.event("click"))
+
+			if timer.isClickPending && timer.pressedAt-timer.lastClickAt <= timing.DoubleTapInterval {
+				timer.isClickPending = false
+				events = append(events, button.event("double_tap"))
+			} else {
+				timer.isClickPending = true
+				timer.lastClickAt = now
+			}
+		}
+
+		for _, event := range events {
+			err := e.fire(now, event, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package gods4
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// reportInterval is the time between the reports of a timeline,
+// a DualShock 4 timestamp tick is 16/3 µs.
+const reportInterval = 10 * time.Millisecond
+
+// segment holds cross pressed or released for a duration.
+type segment struct {
+	isPressed bool
+	duration  time.Duration
+}
+
+// timeline returns USB reports sent every reportInterval from reportInterval on, following the segments.
+func timeline(segments ...segment) [][]byte {
+	var (
+		reports [][]byte
+		now     time.Duration
+	)
+
+	for _, segment := range segments {
+		for end := now + segment.duration; now < end; now += reportInterval {
+			report := usbInput(128, 128, 128, 128)
+			if segment.isPressed {
+				report[5] |= 0x20
+			}
+
+			ticks := (now + reportInterval) * 3 / (16 * time.Microsecond)
+			binary.LittleEndian.PutUint16(report[10:], uint16(ticks))
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+func pressed(duration time.Duration) segment {
+	return segment{isPressed: true, duration: duration}
+}
+
+func released(duration time.Duration) segment {
+	return segment{duration: duration}
+}
+
+func TestButtonTiming(t *testing.T) {
+	const ms = time.Millisecond
+
+	fastRepeat := DefaultTimingConfig()
+	fastRepeat.RepeatDelay = 200 * ms
+	fastRepeat.RepeatInterval = 50 * ms
+
+	tests := []struct {
+		name     string
+		config   TimingConfig
+		segments []segment
+		want     []Event
+	}{
+		{
+			"click",
+			DefaultTimingConfig(),
+			[]segment{pressed(100 * ms), released(50 * ms)},
+			[]Event{EventCrossClick},
+		},
+		{
+			"click at the limit",
+			DefaultTimingConfig(),
+			[]segment{pressed(250 * ms), released(50 * ms)},
+			[]Event{EventCrossClick},
+		},
+		{
+			"too slow for a click",
+			DefaultTimingConfig(),
+			[]segment{pressed(260 * ms), released(50 * ms)},
+			nil,
+		},
+		{
+			// Hold and the first repeat at 500ms, no long press before 800ms
+			"hold",
+			DefaultTimingConfig(),
+			[]segment{pressed(600 * ms), released(50 * ms)},
+			[]Event{EventCrossHold, EventCrossRepeat},
+		},
+		{
+			"long press",
+			DefaultTimingConfig(),
+			[]segment{pressed(900 * ms), released(50 * ms)},
+			[]Event{
+				EventCrossHold, EventCrossRepeat,
+				EventCrossRepeat, EventCrossRepeat, EventCrossRepeat,
+				EventCrossLongPress,
+			},
+		},
+		{
+			"repeat",
+			fastRepeat,
+			[]segment{pressed(400 * ms), released(50 * ms)},
+			[]Event{EventCrossRepeat, EventCrossRepeat, EventCrossRepeat, EventCrossRepeat},
+		},
+		{
+			"double tap",
+			DefaultTimingConfig(),
+			[]segment{pressed(100 * ms), released(100 * ms), pressed(100 * ms), released(50 * ms)},
+			[]Event{EventCrossClick, EventCrossClick, EventCrossDoubleTap},
+		},
+		{
+			// The second press comes exactly DoubleTapInterval after the first release
+			"double tap at the window boundary",
+			DefaultTimingConfig(),
+			[]segment{pressed(100 * ms), released(300 * ms), pressed(100 * ms), released(50 * ms)},
+			[]Event{EventCrossClick, EventCrossClick, EventCrossDoubleTap},
+		},
+		{
+			"double tap past the window",
+			DefaultTimingConfig(),
+			[]segment{pressed(100 * ms), released(310 * ms), pressed(100 * ms), released(50 * ms)},
+			[]Event{EventCrossClick, EventCrossClick},
+		},
+		{
+			// A double tap consumes both clicks, the third one starts over
+			"triple tap",
+			DefaultTimingConfig(),
+			[]segment{pressed(50 * ms), released(50 * ms), pressed(50 * ms), released(50 * ms), pressed(50 * ms), released(50 * ms)},
+			[]Event{EventCrossClick, EventCrossClick, EventCrossDoubleTap, EventCrossClick},
+		},
+		{
+			"slow press between clicks",
+			DefaultTimingConfig(),
+			[]segment{pressed(50 * ms), released(50 * ms), pressed(300 * ms), released(50 * ms), pressed(50 * ms), released(50 * ms)},
+			[]Event{EventCrossClick, EventCrossClick},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewController(newMockDevice(timeline(tt.segments...)...))
+
+			err := controller.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = controller.ConfigureTiming(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var events []Event
+			for _, event := range []Event{EventCrossHold, EventCrossLongPress, EventCrossClick, EventCrossDoubleTap, EventCrossRepeat} {
+				event := event
+				controller.On(event, func(interface{}) error {
+					events = append(events, event)
+
+					return nil
+				})
+			}
+
+			err = controller.Connect()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = controller.Listen()
+			if err != io.EOF {
+				t.Fatalf("got error %v, want io.EOF", err)
+			}
+
+			if !equalEvents(events, tt.want) {
+				t.Errorf("got %v, want %v", events, tt.want)
+			}
+		})
+	}
+}
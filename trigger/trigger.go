@@ -0,0 +1,174 @@
+// Package trigger builds adaptive trigger effects of the DualSense L2 and R2 triggers.
+//
+// Positions of weapon, vibration and multi-position effects are zones in [0, 9]
+// from the released to the fully pulled trigger. Positions of continuous and
+// section resistance are raw values in [0, 255].
+package trigger
+
+import (
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidEffect = errors.New("trigger: invalid effect")
+
+type Mode byte
+
+const (
+	ModeOff                   Mode = 0x05
+	ModeContinuousResistance  Mode = 0x01
+	ModeSectionResistance     Mode = 0x02
+	ModeWeapon                Mode = 0x25
+	ModeVibration             Mode = 0x26
+	ModeMultiPositionFeedback Mode = 0x21
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeOff:
+		return "off"
+	case ModeContinuousResistance:
+		return "continuous_resistance"
+	case ModeSectionResistance:
+		return "section_resistance"
+	case ModeWeapon:
+		return "weapon"
+	case ModeVibration:
+		return "vibration"
+	case ModeMultiPositionFeedback:
+		return "multi_position_feedback"
+	default:
+		return ""
+	}
+}
+
+// Zones is the number of trigger zones of weapon, vibration and multi-position effects
+const Zones = 10
+
+const (
+	minStrength = 1
+	maxStrength = 8
+)
+
+type Effect struct {
+	mode   Mode
+	params [10]byte
+}
+
+func (e *Effect) Mode() Mode {
+	return e.mode
+}
+
+// Bytes returns the effect as encoded in the output report, the mode followed by its parameters.
+func (e *Effect) Bytes() []byte {
+	return append([]byte{byte(e.mode)}, e.params[:]...)
+}
+
+func Off() *Effect {
+	return &Effect{mode: ModeOff}
+}
+
+// ContinuousResistance resists with force from start to the fully pulled trigger.
+func ContinuousResistance(start, force byte) *Effect {
+	return &Effect{mode: ModeContinuousResistance, params: [10]byte{start, force}}
+}
+
+// SectionResistance resists between start and end.
+func SectionResistance(start, end byte) (*Effect, error) {
+	if start >= end {
+		return nil, errors.Wrapf(ErrInvalidEffect, "section start (%d) must be lower than end (%d)", start, end)
+	}
+
+	return &Effect{mode: ModeSectionResistance, params: [10]byte{start, end}}, nil
+}
+
+// Weapon resists from start and snaps at end like a gun trigger. Start is in [2, 7],
+// end in (start, 8] and strength in [1, 8].
+func Weapon(start, end, strength byte) (*Effect, error) {
+	if start < 2 || start > 7 {
+		return nil, errors.Wrapf(ErrInvalidEffect, "weapon start (%d) is out of range [2, 7]", start)
+	}
+
+	if end <= start || end > 8 {
+		return nil, errors.Wrapf(ErrInvalidEffect, "weapon end (%d) is out of range (%d, 8]", end, start)
+	}
+
+	err := validateStrength("weapon strength", strength)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := uint16(1)<<start | uint16(1)<<end
+
+	return &Effect{mode: ModeWeapon, params: [10]byte{byte(zones), byte(zones >> 8), strength - 1}}, nil
+}
+
+// Vibration vibrates from position to the fully pulled trigger. Position is in [0, 9],
+// amplitude in [1, 8] and frequency in Hz is in [1, 255].
+func Vibration(position, amplitude, frequency byte) (*Effect, error) {
+	if position >= Zones {
+		return nil, errors.Wrapf(ErrInvalidEffect, "vibration position (%d) is out of range [0, 9]", position)
+	}
+
+	err := validateStrength("vibration amplitude", amplitude)
+	if err != nil {
+		return nil, err
+	}
+
+	if frequency == 0 {
+		return nil, errors.Wrap(ErrInvalidEffect, "vibration frequency must be positive")
+	}
+
+	var strengths [Zones]byte
+	for i := position; i < Zones; i++ {
+		strengths[i] = amplitude
+	}
+
+	e := &Effect{mode: ModeVibration, params: encodeZones(strengths)}
+	e.params[8] = frequency
+
+	return e, nil
+}
+
+// MultiPositionFeedback resists with a strength per zone in [1, 8], zones with strength 0 are free.
+func MultiPositionFeedback(strengths [Zones]byte) (*Effect, error) {
+	for i, strength := range strengths {
+		if strength == 0 {
+			continue
+		}
+
+		err := validateStrength("zone strength", strength)
+		if err != nil {
+			return nil, errors.Wrapf(err, "zone %d", i)
+		}
+	}
+
+	return &Effect{mode: ModeMultiPositionFeedback, params: encodeZones(strengths)}, nil
+}
+
+// encodeZones packs a bit per active zone into the first two parameters
+// and 3 bits of strength per zone into the next four.
+func encodeZones(strengths [Zones]byte) [10]byte {
+	var (
+		active uint16
+		force  uint32
+	)
+
+	for i, strength := range strengths {
+		if strength == 0 {
+			continue
+		}
+
+		active |= 1 << uint(i)
+		force |= uint32(strength-1) & 0x07 << (3 * uint(i))
+	}
+
+	return [10]byte{byte(active), byte(active >> 8), byte(force), byte(force >> 8), byte(force >> 16), byte(force >> 24)}
+}
+
+func validateStrength(name string, strength byte) error {
+	if strength < minStrength || strength > maxStrength {
+		return errors.Wrapf(ErrInvalidEffect, "%s (%d) is out of range [%d, %d]", name, strength, minStrength, maxStrength)
+	}
+
+	return nil
+}
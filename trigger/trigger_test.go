@@ -0,0 +1,71 @@
+package trigger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestEffectBytes(t *testing.T) {
+	must := func(effect *Effect, err error) *Effect {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return effect
+	}
+
+	tests := []struct {
+		name   string
+		effect *Effect
+		want   []byte
+	}{
+		{"off", Off(), []byte{0x05, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"continuous resistance", ContinuousResistance(100, 200), []byte{0x01, 100, 200, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"section resistance", must(SectionResistance(30, 160)), []byte{0x02, 30, 160, 0, 0, 0, 0, 0, 0, 0, 0}},
+		// Zones 2 and 5 are set, strength is stored minus one
+		{"weapon", must(Weapon(2, 5, 8)), []byte{0x25, 0x24, 0x00, 7, 0, 0, 0, 0, 0, 0, 0}},
+		// Zones 8 and 9 with 3 bits of strength each at bits 24 and 27, frequency in the 9th parameter
+		{"vibration", must(Vibration(8, 8, 30)), []byte{0x26, 0x00, 0x03, 0x00, 0x00, 0x00, 0x3F, 0, 0, 30, 0}},
+		{
+			"multi-position feedback",
+			must(MultiPositionFeedback([Zones]byte{1, 8, 0, 0, 0, 0, 0, 0, 0, 4})),
+			[]byte{0x21, 0x03, 0x02, 0x38, 0x00, 0x00, 0x18, 0, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.effect.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidEffects(t *testing.T) {
+	tests := map[string]func() (*Effect, error){
+		"empty section":      func() (*Effect, error) { return SectionResistance(100, 100) },
+		"weapon start":       func() (*Effect, error) { return Weapon(1, 5, 4) },
+		"weapon end":         func() (*Effect, error) { return Weapon(5, 9, 4) },
+		"weapon strength":    func() (*Effect, error) { return Weapon(2, 5, 9) },
+		"vibration position": func() (*Effect, error) { return Vibration(Zones, 4, 30) },
+		"vibration strength": func() (*Effect, error) { return Vibration(0, 0, 30) },
+		"vibration frequency": func() (*Effect, error) {
+			return Vibration(0, 4, 0)
+		},
+		"zone strength": func() (*Effect, error) {
+			return MultiPositionFeedback([Zones]byte{9})
+		},
+	}
+
+	for name, effect := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := effect()
+			if errors.Cause(err) != ErrInvalidEffect {
+				t.Errorf("got error %v, want %v", err, ErrInvalidEffect)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package gods4
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kpeu3i/gods4/led"
+	"github.com/kpeu3i/gods4/rumble"
+)
+
+// Reports arrive every few milliseconds, faster turbo would skip pulses
+const maxTurboRate = 30
+
+var ErrInvalidTurboConfig = errors.New("ds4: invalid turbo config")
+
+// errInputStopped is returned to the input goroutine once Disconnect has stopped it
+var errInputStopped = errors.New("ds4: input is stopped")
+
+type TurboMode uint8
+
+const (
+	// TurboHold fires while the button is held
+	TurboHold TurboMode = iota
+	// TurboToggle starts firing on a tap and stops on the next one
+	TurboToggle
+)
+
+// TurboCue is shown for Duration when toggle mode is switched on or off,
+// then the previous LED color and rumble are restored.
+type TurboCue struct {
+	Led      *led.Led
+	Rumble   *rumble.Rumble
+	Duration time.Duration
+}
+
+// TurboConfig makes a button emit press/release pairs at Rate per second.
+type TurboConfig struct {
+	Rate float64
+	Mode TurboMode
+	Cue  *TurboCue
+}
+
+func (c TurboConfig) Validate() error {
+	if c.Rate <= 0 || c.Rate > maxTurboRate || math.IsNaN(c.Rate) {
+		return errors.Wrapf(ErrInvalidTurboConfig, "rate (%v) is out of range (0, %d]", c.Rate, maxTurboRate)
+	}
+
+	if c.Mode > TurboToggle {
+		return errors.Wrapf(ErrInvalidTurboConfig, "unknown mode: %d", c.Mode)
+	}
+
+	if c.Cue != nil && c.Cue.Duration <= 0 {
+		return errors.Wrapf(ErrInvalidTurboConfig, "cue duration (%v) must be positive", c.Cue.Duration)
+	}
+
+	return nil
+}
+
+func (c TurboConfig) isPressed(elapsed time.Duration) bool {
+	period := time.Duration(float64(time.Second) / c.Rate)
+
+	return elapsed%period < period/2
+}
+
+type turboButton struct {
+	config    TurboConfig
+	isEnabled bool
+	isHeld    bool
+	isActive  bool
+	startedAt time.Duration
+}
+
+type turboToggle struct {
+	button   Button
+	isActive bool
+	cue      *TurboCue
+}
+
+// turbo turns held buttons into a stream of presses before the state reaches the emitter.
+// Gestures are timed on the held buttons, so pulses never look like clicks or double taps.
+type turbo struct {
+	mutex   sync.Mutex
+	buttons [len(buttons)]turboButton
+}
+
+func newTurbo() *turbo {
+	return &turbo{}
+}
+
+func (t *turbo) enable(button Button, config TurboConfig) error {
+	if button.String() == "" {
+		return errors.Wrapf(ErrInvalidTurboConfig, "unknown button: %d", button)
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.buttons[button] = turboButton{config: config, isEnabled: true}
+
+	return nil
+}
+
+func (t *turbo) disable(button Button) {
+	if button.String() == "" {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.buttons[button] = turboButton{}
+}
+
+func (t *turbo) isActive(button Button) bool {
+	if button.String() == "" {
+		return false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.buttons[button].isActive
+}
+
+// apply returns the state with turbo buttons pulsed and the toggles switched by it.
+func (t *turbo) apply(src *state) (*state, []turboToggle) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var (
+		out     *state
+		toggles []turboToggle
+	)
+
+	now := src.timestamp
+
+	for _, button := range buttons {
+		b := &t.buttons[button]
+		if !b.isEnabled {
+			continue
+		}
+
+		isHeld := src.button(button)
+		isPressedNow := isHeld && !b.isHeld
+		b.isHeld = isHeld
+
+		switch b.config.Mode {
+		case TurboHold:
+			if isPressedNow {
+				b.startedAt = now
+			}
+
+			b.isActive = isHeld
+		case TurboToggle:
+			if isPressedNow {
+				b.isActive = !b.isActive
+				b.startedAt = now
+				toggles = append(toggles, turboToggle{button: button, isActive: b.isActive, cue: b.config.Cue})
+			}
+		}
+
+		if out == nil {
+			copied := *src
+			out = &copied
+		}
+
+		out.setButton(button, b.isActive && b.config.isPressed(now-b.startedAt))
+		out.held = src
+	}
+
+	if out == nil {
+		return src, toggles
+	}
+
+	return out, toggles
+}
+
+func (c *Controller) toggleTurbo(now time.Duration, toggle turboToggle, worker *outputWorker) error {
+	event := EventTurboDisable
+	if toggle.isActive {
+		event = EventTurboEnable
+	}
+
+	err := c.emitter.dispatch(now, event, toggle.button)
+	if err != nil {
+		return err
+	}
+
+	if toggle.cue != nil {
+		cue := *toggle.cue
+		if !c.queueOutput(worker, func(quit <-chan struct{}) error {
+			return c.playCue(cue, quit)
+		}) {
+			return errInputStopped
+		}
+	}
+
+	return nil
+}
+
+// playCue shows the cue for its duration, or until the controller is disconnected.
+func (c *Controller) playCue(cue TurboCue, quit <-chan struct{}) error {
+	prevLed, prevRumble, output, err := c.showCue(cue)
+	if err != nil {
+		return err
+	}
+
+	err = c.emitter.emitOutput(output)
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(cue.Duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-quit:
+		return nil
+	}
+
+	output, isRestored, err := c.hideCue(cue, prevLed, prevRumble)
+	if err != nil || !isRestored {
+		return err
+	}
+
+	return c.emitter.emitOutput(output)
+}
+
+// showCue sets the cue LED and rumble and returns the values they replaced.
+func (c *Controller) showCue(cue TurboCue) (*led.Led, *rumble.Rumble, Output, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return nil, nil, Output{}, err
+	}
+
+	prevLed, prevRumble := copyLed(c.led), copyRumble(c.rumble)
+
+	if cue.Led != nil {
+		err = c.set(c.ledPatch(cue.Led))
+		if err != nil {
+			return nil, nil, Output{}, err
+		}
+
+		c.led = copyLed(cue.Led)
+	}
+
+	if cue.Rumble != nil {
+		err = c.set(c.rumblePatch(cue.Rumble))
+		if err != nil {
+			return nil, nil, Output{}, err
+		}
+
+		c.rumble = copyRumble(cue.Rumble)
+	}
+
+	return prevLed, prevRumble, c.output(), nil
+}
+
+// hideCue restores the previous LED and rumble unless they were changed meanwhile.
+func (c *Controller) hideCue(cue TurboCue, prevLed *led.Led, prevRumble *rumble.Rumble) (Output, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.errorIfNotConnected()
+	if err != nil {
+		return Output{}, false, err
+	}
+
+	isRestored := false
+
+	if cue.Led != nil && equalLed(c.led, cue.Led) {
+		if prevLed == nil {
+			prevLed = led.None()
+		}
+
+		err = c.set(c.ledPatch(prevLed))
+		if err != nil {
+			return Output{}, false, err
+		}
+
+		c.led, isRestored = prevLed, true
+	}
+
+	if cue.Rumble != nil && equalRumble(c.rumble, cue.Rumble) {
+		if prevRumble == nil {
+			prevRumble = rumble.New(0, 0)
+		}
+
+		err = c.set(c.rumblePatch(prevRumble))
+		if err != nil {
+			return Output{}, false, err
+		}
+
+		c.rumble, isRestored = prevRumble, true
+	}
+
+	return c.output(), isRestored, nil
+}
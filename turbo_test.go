@@ -0,0 +1,111 @@
+package gods4
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kpeu3i/gods4/led"
+)
+
+func TestTurboPulsesAreNotGestures(t *testing.T) {
+	turbo := newTurbo()
+
+	err := turbo.enable(ButtonCross, TurboConfig{Rate: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newEmitter()
+	counts := make(map[Event]int)
+	for _, event := range []Event{EventCrossPress, EventCrossClick, EventCrossDoubleTap, EventCrossHold} {
+		event := event
+		e.setCallback(event, func(interface{}) error {
+			counts[event]++
+
+			return nil
+		})
+	}
+
+	prevState, _ := turbo.apply(&state{})
+
+	// Cross is held for a second, then released
+	for now := 10 * time.Millisecond; now <= 1100*time.Millisecond; now += 10 * time.Millisecond {
+		currState, _ := turbo.apply(&state{timestamp: now, cross: now <= time.Second})
+
+		err = e.emit(currState, prevState)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prevState = currState
+	}
+
+	if counts[EventCrossPress] != 10 {
+		t.Errorf("got %d pulses, want 10", counts[EventCrossPress])
+	}
+
+	if counts[EventCrossClick] != 0 || counts[EventCrossDoubleTap] != 0 {
+		t.Errorf("pulses fired %d clicks and %d double taps", counts[EventCrossClick], counts[EventCrossDoubleTap])
+	}
+
+	if counts[EventCrossHold] != 1 {
+		t.Errorf("got %d holds, want 1", counts[EventCrossHold])
+	}
+}
+
+func TestDisconnectStopsCue(t *testing.T) {
+	c := NewController(newMockDevice())
+
+	err := c.ConfigureConnection(ConnectionConfig{Type: ConnectionTypeUSB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cue := TurboCue{Led: led.RGB(255, 0, 0), Duration: time.Hour}
+	c.outputWorker.jobs <- func(quit <-chan struct{}) error {
+		return c.playCue(cue, quit)
+	}
+
+	for !equalLed(c.CurrentLed(), cue.Led) {
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- c.Disconnect()
+	}()
+
+	select {
+	case err = <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Disconnect waits for the cue to end")
+	}
+}
+
+func TestOutputWorkerReportsErrors(t *testing.T) {
+	w := newOutputWorker()
+	defer w.wait()
+	defer w.stop()
+
+	failure := errors.New("write failed")
+	w.jobs <- func(<-chan struct{}) error { return ErrControllerIsNotConnected }
+	w.jobs <- func(<-chan struct{}) error { return failure }
+
+	select {
+	case err := <-w.errors:
+		if err != failure {
+			t.Errorf("got error %v, want %v", err, failure)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error was not reported")
+	}
+}
@@ -0,0 +1,29 @@
+package volume
+
+// Left and right are the headphone channels.
+type Volume struct {
+	left    byte
+	right   byte
+	mic     byte
+	speaker byte
+}
+
+func (v *Volume) Left() byte {
+	return v.left
+}
+
+func (v *Volume) Right() byte {
+	return v.right
+}
+
+func (v *Volume) Mic() byte {
+	return v.mic
+}
+
+func (v *Volume) Speaker() byte {
+	return v.speaker
+}
+
+func New(left, right, mic, speaker byte) *Volume {
+	return &Volume{left: left, right: right, mic: mic, speaker: speaker}
+}